@@ -17,6 +17,10 @@ import (
 // @in                          header
 // @name                        Authorization
 // @desc                        Add Bearer token to the request header
+// @securityDefinitions.apikey  ApiKeyAuth
+// @in                          header
+// @name                        X-Api-Key
+// @desc                        Api key of the internal services
 func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -60,6 +61,20 @@ func TestNew(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "should set the roles of the routes",
+			env: map[string]string{
+				"AUTHORIZATION_SEARCH_ROLES": "rider,driver",
+			},
+			want: func(t *testing.T, c *Config) {
+				if !reflect.DeepEqual(c.Authorization.SaveRoles, []string{"driver"}) {
+					t.Errorf("want Authorization.SaveRoles = %v, got %v", []string{"driver"}, c.Authorization.SaveRoles)
+				}
+				if !reflect.DeepEqual(c.Authorization.SearchRoles, []string{"rider", "driver"}) {
+					t.Errorf("want Authorization.SearchRoles = %v, got %v", []string{"rider", "driver"}, c.Authorization.SearchRoles)
+				}
+			},
+		},
 		{
 			name: "should panic if env value is invalid",
 			env: map[string]string{
@@ -1,4 +1,4 @@
-// Package docs GENERATED BY THE COMMAND ABOVE; DO NOT EDIT
+// Package docs GENERATED BY SWAG; DO NOT EDIT
 // This file was generated by swaggo/swag
 package docs
 
@@ -16,6 +16,69 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/location/api-keys": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Issues an api key of an internal service for the tenant of the caller, admin only.\nThe key is returned only once, it is revoked by deleting the api-key:\u003cid\u003e redis key.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Location Service"
+                ],
+                "summary": "Create Api Key",
+                "parameters": [
+                    {
+                        "description": "Payload",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/CreateApiKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/CreateApiKeyResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/HTTPError"
+                        }
+                    }
+                }
+            }
+        },
         "/location/save": {
             "post": {
                 "security": [
@@ -61,11 +124,41 @@ const docTemplate = `{
                             "$ref": "#/definitions/HTTPError"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/HTTPError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/HTTPError"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/HTTPError"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/HTTPError"
+                        }
                     }
                 }
             }
@@ -75,9 +168,12 @@ const docTemplate = `{
                 "security": [
                     {
                         "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
                     }
                 ],
-                "description": "Searches for driver locations",
+                "description": "Searches for driver locations, the shape of the results depends on the caller:\nadmins, dispatchers and the services with the location:full scope get LocationResponse,\ndrivers get LocationResponse of their own vehicles only, riders and the services without\nthe location:full scope get PublicLocationResponse without the driver id and contact details.",
                 "consumes": [
                     "application/json"
                 ],
@@ -101,11 +197,11 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "PublicLocationResponse, or LocationResponse by the role and the scopes, see x-role-responses",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/LocationResponse"
+                                "$ref": "#/definitions/PublicLocationResponse"
                             }
                         }
                     },
@@ -115,6 +211,83 @@ const docTemplate = `{
                             "$ref": "#/definitions/HTTPError"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/HTTPError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/HTTPError"
+                        }
+                    }
+                },
+                "x-role-responses": {
+                    "admin": "#/definitions/LocationResponse",
+                    "dispatcher": "#/definitions/LocationResponse",
+                    "driver": "#/definitions/LocationResponse",
+                    "rider": "#/definitions/PublicLocationResponse",
+                    "service": {
+                        "default": "#/definitions/PublicLocationResponse",
+                        "scope:location:full": "#/definitions/LocationResponse"
+                    }
+                }
+            }
+        },
+        "/location/vehicles/{id}/cache": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Removes the vehicle from the cache, admin only",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Location Service"
+                ],
+                "summary": "Purge Vehicle Cache",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Vehicle ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": ""
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/HTTPError"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -126,6 +299,36 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "CreateApiKeyRequest": {
+            "type": "object",
+            "required": [
+                "service"
+            ],
+            "properties": {
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "service": {
+                    "type": "string"
+                }
+            }
+        },
+        "CreateApiKeyResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "description": "hash of the key, it is revoked by deleting the api-key:\u003cid\u003e redis key",
+                    "type": "string"
+                },
+                "key": {
+                    "description": "returned only once, only its hash is stored",
+                    "type": "string"
+                }
+            }
+        },
         "Driver": {
             "type": "object",
             "properties": {
@@ -138,9 +341,16 @@ const docTemplate = `{
                 "nickname": {
                     "type": "string"
                 },
+                "phone_mask": {
+                    "description": "e.g. +90*******67, the full number is never stored",
+                    "type": "string"
+                },
                 "picture": {
                     "type": "string"
                 },
+                "rating": {
+                    "type": "number"
+                },
                 "user_id": {
                     "type": "string"
                 }
@@ -149,6 +359,10 @@ const docTemplate = `{
         "HTTPError": {
             "type": "object",
             "properties": {
+                "code": {
+                    "description": "machine readable reason, e.g. token_expired",
+                    "type": "string"
+                },
                 "message": {}
             }
         },
@@ -158,9 +372,6 @@ const docTemplate = `{
                 "dist": {
                     "type": "number"
                 },
-                "driver": {
-                    "$ref": "#/definitions/Driver"
-                },
                 "lat": {
                     "type": "number"
                 },
@@ -172,11 +383,79 @@ const docTemplate = `{
                 }
             }
         },
+        "PublicDriver": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "nickname": {
+                    "type": "string"
+                },
+                "phone_mask": {
+                    "description": "e.g. +90*******67",
+                    "type": "string"
+                },
+                "picture": {
+                    "type": "string"
+                },
+                "rating": {
+                    "type": "number"
+                }
+            }
+        },
+        "PublicLocationResponse": {
+            "type": "object",
+            "properties": {
+                "dist": {
+                    "type": "number"
+                },
+                "lat": {
+                    "type": "number"
+                },
+                "lng": {
+                    "type": "number"
+                },
+                "vehicle": {
+                    "$ref": "#/definitions/PublicVehicle"
+                }
+            }
+        },
+        "PublicVehicle": {
+            "type": "object",
+            "properties": {
+                "class": {
+                    "type": "string"
+                },
+                "color": {
+                    "type": "string"
+                },
+                "driver": {
+                    "$ref": "#/definitions/PublicDriver"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "plate": {
+                    "type": "string"
+                },
+                "seats": {
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "vehicle_id": {
+                    "type": "string"
+                }
+            }
+        },
         "SaveLocationRequest": {
             "type": "object",
             "required": [
                 "lat",
-                "lng"
+                "lng",
+                "vehicle_id"
             ],
             "properties": {
                 "lat": {
@@ -188,6 +467,9 @@ const docTemplate = `{
                     "type": "number",
                     "maximum": 180,
                     "minimum": -180
+                },
+                "vehicle_id": {
+                    "type": "string"
                 }
             }
         },
@@ -212,6 +494,9 @@ const docTemplate = `{
                 "class": {
                     "type": "string"
                 },
+                "color": {
+                    "type": "string"
+                },
                 "driver": {
                     "$ref": "#/definitions/Driver"
                 },
@@ -234,6 +519,11 @@ const docTemplate = `{
         }
     },
     "securityDefinitions": {
+        "ApiKeyAuth": {
+            "type": "apiKey",
+            "name": "X-Api-Key",
+            "in": "header"
+        },
         "BearerAuth": {
             "type": "apiKey",
             "name": "Authorization",
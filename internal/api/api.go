@@ -3,51 +3,180 @@ package api
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/orkungursel/hey-taxi-location-api/internal/api/http"
 	"github.com/orkungursel/hey-taxi-location-api/internal/infrastructure"
 	"github.com/orkungursel/hey-taxi-location-api/internal/server"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/tracing"
 	"github.com/orkungursel/hey-taxi-location-api/proto"
 )
 
-func Api(s *server.Server, redisClient *redis.Client, vehicleServiceGrpc proto.VehicleServiceClient) error {
+// Api wires the location api into the server, the returned func writes the
+// buffered work out and is called on shutdown before the clients are closed
+func Api(s *server.Server, redisClient redis.UniversalClient, pgPool *pgxpool.Pool,
+	vehicleServiceGrpc proto.VehicleServiceClient) (func(), error) {
+
 	if s == nil {
-		return errors.New("server is nil")
+		return nil, errors.New("server is nil")
 	}
 
 	c := s.Config()
 	if c == nil {
-		return errors.New("config is nil")
-	}
-
-	if redisClient == nil {
-		return errors.New("redis client is nil")
+		return nil, errors.New("config is nil")
 	}
 
 	logger := s.Logger()
+	metrics := s.Metrics()
+
+	if UsesRedis(c) {
+		if redisClient == nil {
+			return nil, errors.New("redis client is nil")
+		}
+
+		// test Redis connection
+		if err := redisClient.Ping(context.Background()).Err(); err != nil {
+			return nil, err
+		}
+		logger.Info("connected to Redis")
 
-	// test Redis connection
-	if err := redisClient.Ping(context.Background()).Err(); err != nil {
-		return err
+		if metrics != nil {
+			redisClient.AddHook(infrastructure.NewRedisMetricsHook(metrics))
+		}
+		if tracing.Enabled(c) {
+			redisClient.AddHook(infrastructure.NewRedisTracingHook())
+		}
+
+		s.RegisterHealthCheck("redis", func(ctx context.Context) error {
+			return redisClient.Ping(ctx).Err()
+		})
+	}
+
+	if pgPool != nil {
+		s.RegisterHealthCheck("postgres", pgPool.Ping)
 	}
-	logger.Info("connected to Redis")
 
 	if vehicleServiceGrpc == nil {
-		return errors.New("vehicle service client is nil")
+		return nil, errors.New("vehicle service client is nil")
 	}
 
-	tokenService := infrastructure.NewTokenService(c, logger)
-	vehicleRepo := infrastructure.NewVehicleRepository(redisClient, logger)
-	vehicleService := infrastructure.NewVehicleService(logger, vehicleServiceGrpc, vehicleRepo)
+	revokedTokenRepo, err := NewRevokedTokenRepository(c, redisClient, logger)
+	if err != nil {
+		return nil, err
+	}
+	if revokedTokenRepo == nil {
+		logger.Warn("token revocation is disabled")
+	}
 
-	locationRepo := infrastructure.NewLocationRepository(redisClient, logger)
-	locationService := infrastructure.NewLocationService(locationRepo, logger, vehicleService)
+	tokenService := infrastructure.NewTokenService(c, logger, revokedTokenRepo)
+	s.RegisterHealthCheck("token_keys", tokenService.CheckKeys)
+	vehicleRepo, err := NewVehicleRepository(c, redisClient, logger)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("vehicle storage: %s", c.Storage.Vehicle)
+
+	if tracing.Enabled(c) {
+		vehicleRepo = infrastructure.NewTracedVehicleRepository(vehicleRepo)
+	}
+
+	vehicleCache := infrastructure.NewVehicleLRU(c.VehicleCache.Size,
+		time.Duration(c.VehicleCache.Ttl)*time.Second, time.Duration(c.VehicleCache.NotFoundTtl)*time.Second)
+	vehicleService := infrastructure.NewVehicleService(logger, vehicleServiceGrpc, vehicleRepo, vehicleCache,
+		NewVehicleServiceClientOptions(c).MaxDuration(), metrics)
+
+	if c.VehicleEvents.Enabled {
+		ctx, cancel := context.WithCancel(s.Context())
+		go func() {
+			<-s.Stopped()
+			cancel()
+		}()
+
+		source := NewVehicleEventSource(c, redisClient, logger)
+		invalidator := infrastructure.NewVehicleCacheInvalidator(source, vehicleService, logger, c.VehicleEvents.Refresh)
+		if err := invalidator.Start(ctx); err != nil {
+			cancel()
+			return nil, err
+		}
+		logger.Infof("listening vehicle events on %s", c.VehicleEvents.Channel)
+	}
+
+	locationRepo, err := NewLocationRepository(c, redisClient, pgPool, logger)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("location storage: %s", c.Storage.Location)
 
-	ctrl := http.NewController(c, logger, locationService, tokenService)
+	if counter, ok := locationRepo.(locationCounter); ok {
+		if err := metrics.GaugeFunc("active_drivers", "Drivers in the drivers set of the default tenant.",
+			countLocations(counter, logger)); err != nil {
+			return nil, err
+		}
+	}
+
+	shutdown := func() {}
+	if wb := c.WriteBehind; wb.Enabled {
+		buffered := infrastructure.NewBufferedLocationRepository(locationRepo, logger,
+			time.Duration(wb.Window)*time.Millisecond, wb.Size)
+		buffered.Start()
+		locationRepo = buffered
+
+		if err := registerWriteBehindMetrics(metrics, buffered); err != nil {
+			return nil, err
+		}
+		logger.Infof("write-behind location saves every %dms", wb.Window)
+
+		shutdown = func() {
+			ctx, cancel := context.WithTimeout(context.Background(),
+				time.Duration(c.Server.Http.ShutdownTimeout)*time.Second)
+			defer cancel()
+
+			if err := buffered.Close(ctx); err != nil {
+				logger.Errorf("failed to write the buffered locations: %v", err)
+			}
+		}
+	}
+
+	if tracing.Enabled(c) {
+		locationRepo = infrastructure.NewTracedLocationRepository(locationRepo)
+		logger.Infof("tracing exporter: %s", c.Tracing.Exporter)
+	}
+
+	fuzzer, err := infrastructure.NewLocationFuzzer(c.Privacy.Mode, float64(c.Privacy.Radius),
+		time.Duration(c.Privacy.Window)*time.Second, c.Privacy.Secret)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("location privacy mode: %s", c.Privacy.Mode)
+
+	assignmentRepo := NewAssignmentRepository(redisClient, logger)
+	coalescer := NewLocationCoalescer(c)
+	locationService := infrastructure.NewLocationService(locationRepo, logger, vehicleService, fuzzer,
+		assignmentRepo, coalescer, NewLastSeenRepository(c, redisClient, logger), metrics)
+
+	apiKeyService := NewApiKeyService(c, redisClient, logger)
+	if apiKeyService == nil {
+		logger.Info("api key authentication is disabled")
+	}
+
+	rateLimiter := NewRateLimiter(c, redisClient, logger)
+	if rateLimiter == nil {
+		logger.Info("rate limiting is disabled")
+	}
+
+	ctrl := http.NewController(c, logger, locationService, vehicleService, tokenService, apiKeyService, rateLimiter)
 	if err := s.RegisterHttpApi("/location", ctrl); err != nil {
-		return err
+		return nil, err
+	}
+
+	if metrics != nil {
+		if err := s.RegisterHttpApiAsRoot(c.Metrics.Path, http.NewMetricsController(metrics)); err != nil {
+			return nil, err
+		}
+		logger.Infof("serving metrics on %s", c.Metrics.Path)
 	}
 
-	return nil
+	return shutdown, nil
 }
@@ -14,27 +14,51 @@ type Controller struct {
 	config          *config.Config
 	logger          logger.ILogger
 	locationService app.LocationService
+	vehicleService  app.VehicleService
 	tokenService    app.TokenService
+	apiKeyService   app.ApiKeyService
+	rateLimiter     app.RateLimiter
 }
 
-func NewController(config *config.Config, logger logger.ILogger,
-	ls app.LocationService, ts app.TokenService) *Controller {
+// NewController returns the controller, the api keys are not accepted if ks is nil
+// and the requests are not limited if rl is nil
+func NewController(config *config.Config, logger logger.ILogger, ls app.LocationService,
+	vs app.VehicleService, ts app.TokenService, ks app.ApiKeyService, rl app.RateLimiter) *Controller {
 
 	return &Controller{
 		config:          config,
 		logger:          logger,
 		tokenService:    ts,
+		apiKeyService:   ks,
+		rateLimiter:     rl,
 		locationService: ls,
+		vehicleService:  vs,
 	}
 }
 
 // RegisterRoutes registers the routes to the echo server
 func (a *Controller) RegisterRoutes(e *echo.Group) {
 	e.Use(middleware.ErrorHandler())
-	e.Use(middleware.Auth(a.tokenService))
-
-	e.POST("/save/", a.saveLocation())
-	e.POST("/search/", a.searchLocation())
+	e.Use(middleware.Auth(a.tokenService, a.apiKeyService))
+
+	saveLimit := app.RateLimit{PerMinute: a.config.RateLimit.SavePerMinute, Burst: a.config.RateLimit.SaveBurst}
+	searchLimit := app.RateLimit{PerMinute: a.config.RateLimit.SearchPerMinute, Burst: a.config.RateLimit.SearchBurst}
+
+	// the roles allowed to call the routes, admins may call every route
+	roles := a.config.Authorization
+	e.POST("/save/", a.saveLocation(),
+		middleware.Authorize(roles.SaveRoles...),
+		middleware.RateLimit(a.rateLimiter, "save", saveLimit))
+	e.POST("/search/", a.searchLocation(),
+		middleware.Authorize(roles.SearchRoles...),
+		middleware.RequireScope(app.ScopeLocationSearch),
+		middleware.RateLimit(a.rateLimiter, "search", searchLimit))
+	e.DELETE("/vehicles/:id/cache/", a.purgeVehicleCache(), middleware.Authorize(roles.PurgeCacheRoles...))
+
+	// the api keys are issued only where they are accepted
+	if a.apiKeyService != nil {
+		e.POST("/api-keys/", a.createApiKey(), middleware.Authorize(roles.ApiKeyRoles...))
+	}
 }
 
 // @Summary      Save Location
@@ -45,7 +69,12 @@ func (a *Controller) RegisterRoutes(e *echo.Group) {
 // @Param        payload  body      app.SaveLocationRequest  true  "Payload"
 // @Success      200      {array}   app.LocationResponse
 // @Failure      400      {object}  app.HTTPError
+// @Failure      401      {object}  app.HTTPError
+// @Failure      403      {object}  app.HTTPError
+// @Failure      404      {object}  app.HTTPError
+// @Failure      429      {object}  app.HTTPError
 // @Failure      500      {object}  app.HTTPError
+// @Failure      503      {object}  app.HTTPError
 // @Router       /location/save [post]
 // @Security     BearerAuth
 func (a *Controller) saveLocation() echo.HandlerFunc {
@@ -73,16 +102,24 @@ func (a *Controller) saveLocation() echo.HandlerFunc {
 }
 
 // @Summary      Search
-// @Description  Searches for driver locations
+// @Description  Searches for driver locations, the shape of the results depends on the caller:
+// @Description  admins, dispatchers and the services with the location:full scope get LocationResponse,
+// @Description  drivers get LocationResponse of their own vehicles only, riders and the services without
+// @Description  the location:full scope get PublicLocationResponse without the driver id and contact details.
 // @Tags         Location Service
 // @Accept       json
 // @Produce      json
 // @Param        payload  body      app.SearchLocationRequest  true  "Payload"
-// @Success      200      {array}   app.LocationResponse
+// @Success      200      {array}   app.PublicLocationResponse  "PublicLocationResponse, or LocationResponse by the role and the scopes, see x-role-responses"
+// @x-role-responses {"admin": "#/definitions/LocationResponse", "dispatcher": "#/definitions/LocationResponse", "driver": "#/definitions/LocationResponse", "rider": "#/definitions/PublicLocationResponse", "service": {"scope:location:full": "#/definitions/LocationResponse", "default": "#/definitions/PublicLocationResponse"}}
 // @Failure      400      {object}  app.HTTPError
+// @Failure      401      {object}  app.HTTPError
+// @Failure      403      {object}  app.HTTPError
+// @Failure      429      {object}  app.HTTPError
 // @Failure      500      {object}  app.HTTPError
 // @Router       /location/search [post]
 // @Security     BearerAuth
+// @Security     ApiKeyAuth
 func (a *Controller) searchLocation() echo.HandlerFunc {
 	return func(c echo.Context) error {
 		payload := &app.SearchLocationRequest{}
@@ -94,11 +131,71 @@ func (a *Controller) searchLocation() echo.HandlerFunc {
 			return err
 		}
 
+		claims, err := GetClaims(c)
+		if err != nil {
+			return err
+		}
+
 		res, err := a.locationService.SearchLocations(c.Request().Context(), *payload)
 		if err != nil {
 			return err
 		}
 
-		return c.JSON(http.StatusOK, res)
+		return c.JSON(http.StatusOK, app.ProjectLocationResponses(claims, res))
+	}
+}
+
+// @Summary      Purge Vehicle Cache
+// @Description  Removes the vehicle from the cache, admin only
+// @Tags         Location Service
+// @Produce      json
+// @Param        id   path      string  true  "Vehicle ID"
+// @Success      204
+// @Failure      401  {object}  app.HTTPError
+// @Failure      403  {object}  app.HTTPError
+// @Failure      500  {object}  app.HTTPError
+// @Router       /location/vehicles/{id}/cache [delete]
+// @Security     BearerAuth
+func (a *Controller) purgeVehicleCache() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if err := a.vehicleService.PurgeVehicle(c.Request().Context(), c.Param("id")); err != nil {
+			return err
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// @Summary      Create Api Key
+// @Description  Issues an api key of an internal service for the tenant of the caller, admin only.
+// @Description  The key is returned only once, it is revoked by deleting the api-key:<id> redis key.
+// @Tags         Location Service
+// @Accept       json
+// @Produce      json
+// @Param        payload  body      app.CreateApiKeyRequest  true  "Payload"
+// @Success      201      {object}  app.CreateApiKeyResponse
+// @Failure      400      {object}  app.HTTPError
+// @Failure      401      {object}  app.HTTPError
+// @Failure      403      {object}  app.HTTPError
+// @Failure      500      {object}  app.HTTPError
+// @Router       /location/api-keys [post]
+// @Security     BearerAuth
+func (a *Controller) createApiKey() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		payload := &app.CreateApiKeyRequest{}
+		if err := (&echo.DefaultBinder{}).BindBody(c, &payload); err != nil {
+			return err
+		}
+
+		if err := app.Validate(payload); err != nil {
+			return err
+		}
+
+		res, err := a.apiKeyService.CreateApiKey(c.Request().Context(), *payload)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusCreated, res)
 	}
 }
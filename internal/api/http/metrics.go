@@ -0,0 +1,21 @@
+package http
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/metrics"
+)
+
+// MetricsController serves the prometheus metrics, it is registered at the root
+// without authentication, like the other scrape targets of the cluster
+type MetricsController struct {
+	metrics *metrics.Metrics
+}
+
+func NewMetricsController(m *metrics.Metrics) *MetricsController {
+	return &MetricsController{metrics: m}
+}
+
+// RegisterRoutes registers the routes to the echo server
+func (a *MetricsController) RegisterRoutes(e *echo.Group) {
+	e.GET("/", echo.WrapHandler(a.metrics.Handler()))
+}
@@ -2,24 +2,100 @@ package middleware
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
 	"github.com/orkungursel/hey-taxi-location-api/internal/app"
 )
 
-func Auth(ts app.TokenService) echo.MiddlewareFunc {
+const (
+	authRealm    = "hey-taxi"
+	ApiKeyHeader = "X-Api-Key" // ApiKeyHeader carries the api key of the internal services
+)
+
+// Auth authenticates the users by their access tokens, and the internal services
+// by their api keys if ks is not nil
+func Auth(ts app.TokenService, ks app.ApiKeyService) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			claims, err := ts.ValidateAccessTokenFromRequest(c.Request().Context(), c.Request())
+			if key := c.Request().Header.Get(ApiKeyHeader); key != "" && ks != nil {
+				return authenticateService(c, next, ks, key)
+			}
 
+			token, err := ts.ExtractAccessToken(c.Request())
 			if err != nil {
-				return c.JSON(http.StatusUnauthorized, errors.New("unauthorized"))
+				return unauthorized(c, err)
 			}
 
+			claims, err := ts.ParseToken(c.Request().Context(), token)
+			if err != nil {
+				return unauthorized(c, err)
+			}
+
+			tenant := claims.GetTenant()
+			if !app.IsValidTenant(tenant) {
+				return unauthorized(c, app.ErrUnauthorized)
+			}
+
+			ctx := app.WithTenant(c.Request().Context(), tenant)
+			ctx = app.WithClaims(ctx, claims)
+			ctx = app.WithAccessToken(ctx, token)
+
 			c.Set("claims", claims)
+			c.SetRequest(c.Request().WithContext(ctx))
 
 			return next(c)
 		}
 	}
 }
+
+// authenticateService authenticates the service by its api key, the key is not
+// forwarded to the vehicle service as an access token
+func authenticateService(c echo.Context, next echo.HandlerFunc, ks app.ApiKeyService, key string) error {
+	claims, err := ks.ValidateApiKey(c.Request().Context(), key)
+	if err != nil {
+		return unauthorized(c, err)
+	}
+
+	tenant := claims.GetTenant()
+	if !app.IsValidTenant(tenant) {
+		return unauthorized(c, app.ErrApiKeyInvalid)
+	}
+
+	ctx := app.WithTenant(c.Request().Context(), tenant)
+	ctx = app.WithClaims(ctx, claims)
+
+	c.Set("claims", claims)
+	c.SetRequest(c.Request().WithContext(ctx))
+
+	return next(c)
+}
+
+// unauthorized sets the WWW-Authenticate header of RFC 6750 and returns the
+// token error, which tells why the token is rejected, or app.ErrUnauthorized
+func unauthorized(c echo.Context, err error) error {
+	var e *app.Error
+	if !errors.As(err, &e) {
+		e = app.ErrUnauthorized
+	}
+
+	if e.Code() != http.StatusUnauthorized {
+		return e
+	}
+
+	challenge := fmt.Sprintf("Bearer realm=%q", authRealm)
+	switch e {
+	case app.ErrApiKeyInvalid:
+		challenge = fmt.Sprintf("ApiKey realm=%q", authRealm)
+	case app.ErrTokenMissing:
+		// no error attribute, the request has no credentials
+	case app.ErrTokenScheme, app.ErrTokenMalformed:
+		challenge += fmt.Sprintf(", error=\"invalid_request\", error_description=%q", e.Error())
+	default:
+		challenge += fmt.Sprintf(", error=\"invalid_token\", error_description=%q", e.Error())
+	}
+	c.Response().Header().Set(echo.HeaderWWWAuthenticate, challenge)
+
+	return e
+}
@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/orkungursel/hey-taxi-location-api/internal/app"
+	"github.com/orkungursel/hey-taxi-location-api/internal/app/mock"
+)
+
+func TestAuth(t *testing.T) {
+	tests := []struct {
+		name          string
+		extractErr    error
+		claims        app.Claims
+		parseErr      error
+		wantCode      int
+		wantBody      string
+		wantChallenge string
+	}{
+		{name: "should pass valid token", claims: testClaims{role: app.RoleRider}, wantCode: http.StatusOK},
+		{name: "should challenge without error when the token is missing", extractErr: app.ErrTokenMissing,
+			wantCode:      http.StatusUnauthorized,
+			wantBody:      `{"message":"token is missing","code":"token_missing"}`,
+			wantChallenge: `Bearer realm="hey-taxi"`},
+		{name: "should report invalid request for another scheme", extractErr: app.ErrTokenScheme,
+			wantCode:      http.StatusUnauthorized,
+			wantBody:      `{"message":"authorization scheme is not supported","code":"token_scheme"}`,
+			wantChallenge: `Bearer realm="hey-taxi", error="invalid_request", error_description="authorization scheme is not supported"`},
+		{name: "should tell why the token is rejected", parseErr: app.ErrTokenExpired,
+			wantCode:      http.StatusUnauthorized,
+			wantBody:      `{"message":"token is expired","code":"token_expired"}`,
+			wantChallenge: `Bearer realm="hey-taxi", error="invalid_token", error_description="token is expired"`},
+		{name: "should hide other errors", parseErr: errors.New("boom"),
+			wantCode:      http.StatusUnauthorized,
+			wantBody:      `{"message":"unauthorized","code":"unauthorized"}`,
+			wantChallenge: `Bearer realm="hey-taxi", error="invalid_token", error_description="unauthorized"`},
+		{name: "should fail on revocation check errors", parseErr: app.NewInternalServerError(errors.New("redis down")),
+			wantCode: http.StatusInternalServerError, wantBody: `{"message":"Internal Server Error"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			ts := mock.NewMockTokenService(ctrl)
+			if tt.extractErr != nil {
+				ts.EXPECT().ExtractAccessToken(gomock.Any()).Return("", tt.extractErr)
+			} else {
+				ts.EXPECT().ExtractAccessToken(gomock.Any()).Return("token", nil)
+				ts.EXPECT().ParseToken(gomock.Any(), "token").Return(tt.claims, tt.parseErr)
+			}
+
+			e := echo.New()
+			e.GET("/", func(c echo.Context) error {
+				if _, ok := c.Get("claims").(app.Claims); !ok {
+					t.Errorf("Auth() claims are not set")
+				}
+				if got := app.AccessTokenFromContext(c.Request().Context()); got != "token" {
+					t.Errorf("Auth() access token = %v, want token", got)
+				}
+				return c.NoContent(http.StatusOK)
+			}, ErrorHandler(), Auth(ts, nil))
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("Auth() code = %v, want %v", rec.Code, tt.wantCode)
+			}
+			if tt.wantBody != "" && strings.TrimSpace(rec.Body.String()) != tt.wantBody {
+				t.Errorf("Auth() body = %v, want %v", rec.Body.String(), tt.wantBody)
+			}
+			if got := rec.Header().Get(echo.HeaderWWWAuthenticate); got != tt.wantChallenge {
+				t.Errorf("Auth() WWW-Authenticate = %v, want %v", got, tt.wantChallenge)
+			}
+		})
+	}
+}
+
+func TestAuth_ApiKey(t *testing.T) {
+	service := testClaims{role: app.RoleService, scopes: []string{app.ScopeLocationSearch}}
+
+	tests := []struct {
+		name          string
+		apiKeys       bool
+		claims        app.Claims
+		err           error
+		wantCode      int
+		wantBody      string
+		wantChallenge string
+	}{
+		{name: "should authenticate the service", apiKeys: true, claims: service, wantCode: http.StatusOK},
+		{name: "should reject invalid key", apiKeys: true, err: app.ErrApiKeyInvalid,
+			wantCode:      http.StatusUnauthorized,
+			wantBody:      `{"message":"api key is invalid","code":"api_key_invalid"}`,
+			wantChallenge: `ApiKey realm="hey-taxi"`},
+		{name: "should fail on storage errors", apiKeys: true, err: app.NewInternalServerError(errors.New("redis down")),
+			wantCode: http.StatusInternalServerError},
+		{name: "should fall back to the access token when api keys are disabled",
+			wantCode:      http.StatusUnauthorized,
+			wantBody:      `{"message":"token is missing","code":"token_missing"}`,
+			wantChallenge: `Bearer realm="hey-taxi"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			ts := mock.NewMockTokenService(ctrl)
+			var ks app.ApiKeyService
+			if tt.apiKeys {
+				m := mock.NewMockApiKeyService(ctrl)
+				m.EXPECT().ValidateApiKey(gomock.Any(), "key").Return(tt.claims, tt.err)
+				ks = m
+			} else {
+				ts.EXPECT().ExtractAccessToken(gomock.Any()).Return("", app.ErrTokenMissing)
+			}
+
+			e := echo.New()
+			e.GET("/", func(c echo.Context) error {
+				if !reflect.DeepEqual(c.Get("claims"), tt.claims) {
+					t.Errorf("Auth() claims = %v, want %v", c.Get("claims"), tt.claims)
+				}
+				if got := app.AccessTokenFromContext(c.Request().Context()); got != "" {
+					t.Errorf("Auth() should not forward the api key, got %v", got)
+				}
+				return c.NoContent(http.StatusOK)
+			}, ErrorHandler(), Auth(ts, ks))
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(ApiKeyHeader, "key")
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("Auth() code = %v, want %v", rec.Code, tt.wantCode)
+			}
+			if tt.wantBody != "" && strings.TrimSpace(rec.Body.String()) != tt.wantBody {
+				t.Errorf("Auth() body = %v, want %v", rec.Body.String(), tt.wantBody)
+			}
+			if got := rec.Header().Get(echo.HeaderWWWAuthenticate); got != tt.wantChallenge {
+				t.Errorf("Auth() WWW-Authenticate = %v, want %v", got, tt.wantChallenge)
+			}
+		})
+	}
+}
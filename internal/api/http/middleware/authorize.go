@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/orkungursel/hey-taxi-location-api/internal/app"
+)
+
+// Authorize lets the callers with one of the roles, or the admins, call the route
+// and rejects everyone else with 403. It must run after Auth.
+func Authorize(roles ...string) echo.MiddlewareFunc {
+	allowed := make(map[string]bool, len(roles)+1)
+	allowed[app.RoleAdmin] = true
+	for _, r := range roles {
+		if r != "" {
+			allowed[r] = true
+		}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims, ok := c.Get("claims").(app.Claims)
+			if !ok || claims == nil || !allowed[claims.GetRole()] {
+				return app.ErrForbidden
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// RequireScope rejects the services without the scope with 403, the other
+// callers are left to Authorize. It must run after Auth.
+func RequireScope(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims, ok := c.Get("claims").(app.Claims)
+			if !ok || claims == nil {
+				return app.ErrForbidden
+			}
+
+			if claims.GetRole() == app.RoleService && !app.HasScope(claims, scope) {
+				return app.ErrForbidden
+			}
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/orkungursel/hey-taxi-location-api/internal/app"
+)
+
+type testClaims struct {
+	role   string
+	scopes []string
+}
+
+func (c testClaims) GetSubject() string  { return "subject" }
+func (c testClaims) GetRole() string     { return c.role }
+func (c testClaims) GetIssuer() string   { return "" }
+func (c testClaims) GetTenant() string   { return "" }
+func (c testClaims) GetScopes() []string { return c.scopes }
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		name     string
+		roles    []string
+		scope    string
+		claims   app.Claims
+		wantCode int
+	}{
+		{name: "should allow listed role", roles: []string{app.RoleDriver}, claims: testClaims{role: app.RoleDriver}, wantCode: http.StatusOK},
+		{name: "should allow any listed role", roles: []string{app.RoleRider, app.RoleDispatcher}, claims: testClaims{role: app.RoleDispatcher}, wantCode: http.StatusOK},
+		{name: "should allow admin everywhere", roles: []string{app.RoleDriver}, claims: testClaims{role: app.RoleAdmin}, wantCode: http.StatusOK},
+		{name: "should allow admin on admin only routes", claims: testClaims{role: app.RoleAdmin}, wantCode: http.StatusOK},
+		{name: "should reject other roles", roles: []string{app.RoleDriver}, claims: testClaims{role: app.RoleRider}, wantCode: http.StatusForbidden},
+		{name: "should reject empty role", roles: []string{app.RoleDriver}, claims: testClaims{}, wantCode: http.StatusForbidden},
+		{name: "should not allow empty role", roles: []string{""}, claims: testClaims{}, wantCode: http.StatusForbidden},
+		{name: "should reject without claims", roles: []string{app.RoleDriver}, wantCode: http.StatusForbidden},
+		{name: "should allow service with the scope", roles: []string{app.RoleService}, scope: app.ScopeLocationSearch,
+			claims: testClaims{role: app.RoleService, scopes: []string{app.ScopeLocationSearch}}, wantCode: http.StatusOK},
+		{name: "should reject service without the scope", roles: []string{app.RoleService}, scope: app.ScopeLocationSearch,
+			claims: testClaims{role: app.RoleService, scopes: []string{app.ScopeLocationFull}}, wantCode: http.StatusForbidden},
+		{name: "should reject service whose role is not allowed", roles: []string{app.RoleRider}, scope: app.ScopeLocationSearch,
+			claims: testClaims{role: app.RoleService, scopes: []string{app.ScopeLocationSearch}}, wantCode: http.StatusForbidden},
+		{name: "should not require the scope of users", roles: []string{app.RoleRider}, scope: app.ScopeLocationSearch,
+			claims: testClaims{role: app.RoleRider}, wantCode: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			middlewares := []echo.MiddlewareFunc{func(next echo.HandlerFunc) echo.HandlerFunc {
+				return func(c echo.Context) error {
+					if tt.claims != nil {
+						c.Set("claims", tt.claims)
+					}
+					return next(c)
+				}
+			}, ErrorHandler(), Authorize(tt.roles...)}
+			if tt.scope != "" {
+				middlewares = append(middlewares, RequireScope(tt.scope))
+			}
+
+			e := echo.New()
+			e.POST("/", func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			}, middlewares...)
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("Authorize() code = %v, want %v", rec.Code, tt.wantCode)
+			}
+			if tt.wantCode == http.StatusForbidden && strings.TrimSpace(rec.Body.String()) != `{"message":"forbidden","code":"forbidden"}` {
+				t.Errorf("Authorize() body = %v, want the HTTPError shape", rec.Body.String())
+			}
+		})
+	}
+}
@@ -21,7 +21,11 @@ func ErrorHandler() echo.MiddlewareFunc {
 						return echo.NewHTTPError(http.StatusInternalServerError)
 					}
 
-					return echo.NewHTTPError(e.Code(), e.Error())
+					if e.ErrorCode() != "" {
+						return echo.NewHTTPError(code, app.HTTPError{Message: e.Error(), ErrorCode: e.ErrorCode()})
+					}
+
+					return echo.NewHTTPError(code, e.Error())
 				}
 
 				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/orkungursel/hey-taxi-location-api/internal/app"
+)
+
+// RateLimit limits the requests of every caller on the route, the callers over
+// the limit get 429 with a Retry-After header. The requests are let through if
+// the limiter fails. It must run after Auth.
+func RateLimit(limiter app.RateLimiter, route string, limit app.RateLimit) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if limiter == nil {
+			return next
+		}
+
+		return func(c echo.Context) error {
+			key := route + ":ip:" + c.RealIP()
+			if claims, ok := c.Get("claims").(app.Claims); ok && claims != nil {
+				key = route + ":" + claims.GetRole() + ":" + claims.GetSubject()
+			}
+
+			allowed, wait, err := limiter.Allow(c.Request().Context(), key, limit)
+			if err != nil {
+				c.Logger().Warnf("failed to check the rate limit of %s: %v", key, err)
+				return next(c)
+			}
+
+			if !allowed {
+				retryAfter := int(math.Max(1, math.Ceil(wait.Seconds())))
+				c.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(retryAfter))
+				return app.ErrTooManyRequests
+			}
+
+			return next(c)
+		}
+	}
+}
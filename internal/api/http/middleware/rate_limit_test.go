@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/orkungursel/hey-taxi-location-api/internal/app"
+	"github.com/orkungursel/hey-taxi-location-api/internal/app/mock"
+)
+
+func TestRateLimit(t *testing.T) {
+	limit := app.RateLimit{PerMinute: 60, Burst: 10}
+
+	tests := []struct {
+		name           string
+		claims         app.Claims
+		wantKey        string
+		allowed        bool
+		wait           time.Duration
+		err            error
+		wantCode       int
+		wantBody       string
+		wantRetryAfter string
+	}{
+		{name: "should let the request through", claims: testClaims{role: app.RoleDriver},
+			wantKey: "save:driver:subject", allowed: true, wantCode: http.StatusOK},
+		{name: "should reject over the limit", claims: testClaims{role: app.RoleDriver},
+			wantKey: "save:driver:subject", wait: 1200 * time.Millisecond,
+			wantCode: http.StatusTooManyRequests, wantBody: `{"message":"too many requests","code":"rate_limited"}`,
+			wantRetryAfter: "2"},
+		{name: "should wait at least a second", claims: testClaims{role: app.RoleDriver},
+			wantKey: "save:driver:subject", wait: 10 * time.Millisecond,
+			wantCode: http.StatusTooManyRequests, wantRetryAfter: "1"},
+		{name: "should limit by ip without claims",
+			wantKey: "save:ip:192.0.2.1", allowed: true, wantCode: http.StatusOK},
+		{name: "should let the request through when the limiter fails", claims: testClaims{role: app.RoleDriver},
+			wantKey: "save:driver:subject", err: errors.New("redis down"), wantCode: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			limiter := mock.NewMockRateLimiter(ctrl)
+			limiter.EXPECT().Allow(gomock.Any(), tt.wantKey, limit).Return(tt.allowed, tt.wait, tt.err)
+
+			e := echo.New()
+			e.POST("/", func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			}, func(next echo.HandlerFunc) echo.HandlerFunc {
+				return func(c echo.Context) error {
+					if tt.claims != nil {
+						c.Set("claims", tt.claims)
+					}
+					return next(c)
+				}
+			}, ErrorHandler(), RateLimit(limiter, "save", limit))
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("RateLimit() code = %v, want %v", rec.Code, tt.wantCode)
+			}
+			if tt.wantBody != "" && strings.TrimSpace(rec.Body.String()) != tt.wantBody {
+				t.Errorf("RateLimit() body = %v, want %v", rec.Body.String(), tt.wantBody)
+			}
+			if got := rec.Header().Get(echo.HeaderRetryAfter); got != tt.wantRetryAfter {
+				t.Errorf("RateLimit() Retry-After = %v, want %v", got, tt.wantRetryAfter)
+			}
+		})
+	}
+}
+
+func TestRateLimit_Disabled(t *testing.T) {
+	e := echo.New()
+	e.POST("/", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, RateLimit(nil, "save", app.RateLimit{PerMinute: 1, Burst: 1}))
+
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("RateLimit() code = %v, want %v", rec.Code, http.StatusOK)
+		}
+	}
+}
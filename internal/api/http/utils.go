@@ -8,10 +8,19 @@ import (
 )
 
 func GetUserId(c echo.Context) (string, error) {
-	claims := c.Get("claims")
-	if claims == nil {
-		return "", echo.NewHTTPError(http.StatusUnauthorized, "claims is nil")
+	claims, err := GetClaims(c)
+	if err != nil {
+		return "", err
 	}
 
-	return claims.(app.Claims).GetSubject(), nil
+	return claims.GetSubject(), nil
+}
+
+func GetClaims(c echo.Context) (app.Claims, error) {
+	claims, ok := c.Get("claims").(app.Claims)
+	if !ok || claims == nil {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "claims is nil")
+	}
+
+	return claims, nil
 }
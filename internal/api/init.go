@@ -1,53 +1,223 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/go-redis/redis/v8"
+	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/orkungursel/hey-taxi-location-api/config"
+	"github.com/orkungursel/hey-taxi-location-api/internal/infrastructure"
 	"github.com/orkungursel/hey-taxi-location-api/internal/server"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/metrics"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/tracing"
 	"github.com/orkungursel/hey-taxi-location-api/proto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
+const (
+	RedisModeSingle   = "single"
+	RedisModeSentinel = "sentinel"
+	RedisModeCluster  = "cluster"
+)
+
 func init() {
 	server.Plug(func(s *server.Server, next server.Next) {
 		config := s.Config()
 
 		// Redis
-		rc := NewRedisClientWithConfig(config)
-		defer rc.Close()
+		var rc redis.UniversalClient
+		if UsesRedis(config) {
+			var err error
+			rc, err = NewRedisClientWithConfig(config)
+			if err != nil {
+				next(err)
+				return
+			}
+			defer rc.Close()
+		}
+
+		// Postgres
+		var pg *pgxpool.Pool
+		if UsesPostgres(config) {
+			var err error
+			pg, err = NewPostgresPoolWithConfig(s.Context(), config, s.Logger())
+			if err != nil {
+				next(err)
+				return
+			}
+			defer pg.Close()
+		}
 
 		// Set up a connection to vehicle grpc service.
 		vehicleServiceAddr := config.VehicleService.Host + ":" + config.VehicleService.Port
-		vehicleServiceConn, err := grpc.Dial(vehicleServiceAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		vehicleServiceOpts, err := NewVehicleServiceDialOptions(config, s.Logger(), s.Metrics())
+		if err != nil {
+			next(err)
+			return
+		}
+
+		vehicleServiceConn, err := grpc.Dial(vehicleServiceAddr, vehicleServiceOpts...)
 		if err != nil {
 			next(err)
 			return
 		}
 		defer vehicleServiceConn.Close()
+		s.RegisterHealthCheck("vehicle_service", GrpcConnHealthCheck(vehicleServiceConn))
 
 		// User Service GRPC Client
 		vs := proto.NewVehicleServiceClient(vehicleServiceConn)
 
-		if err := Api(s, rc, vs); err != nil {
+		shutdown, err := Api(s, rc, pg, vs)
+		if err != nil {
 			next(err)
 			return
 		}
 
 		next(nil)
 
-		<-s.Wait() // should wait until all http handlers are closed because of the context
+		<-s.Stopped() // the http handlers are done, the clients can be closed
+		shutdown()
 	})
 }
 
-func NewRedisClientWithConfig(config *config.Config) *redis.Client {
-	redisOptions := &redis.Options{
-		Addr:       config.Redis.Addr,
-		Password:   config.Redis.Password,
-		PoolSize:   config.Redis.PoolSize,
-		DB:         config.Redis.DB,
-		MaxRetries: config.Redis.MaxRetries,
+// NewVehicleServiceDialOptions returns the transport and per-RPC credentials
+// and the interceptors of the vehicle service connection, the calls are
+// observed if m is not nil
+func NewVehicleServiceDialOptions(config *config.Config, logger logger.ILogger,
+	m *metrics.Metrics) ([]grpc.DialOption, error) {
+	c := config.VehicleService
+
+	// the access tokens of the users are never sent in plaintext
+	if c.Auth.ForwardToken && !c.Tls.Enabled {
+		return nil, errors.New("forwarding the access token requires vehicle service tls")
+	}
+
+	creds := insecure.NewCredentials()
+	if c.Tls.Enabled {
+		var err error
+		creds, err = infrastructure.NewVehicleServiceTransportCredentials(infrastructure.VehicleServiceTLSOptions{
+			CaFile:     c.Tls.CaFile,
+			ServerName: c.Tls.ServerName,
+			CertFile:   c.Tls.CertFile,
+			KeyFile:    c.Tls.KeyFile,
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else if c.Auth.Token != "" {
+		logger.Warn("vehicle service token is sent over an insecure connection")
+	}
+
+	// the tracing and metrics see a call once, the retries are inside them
+	interceptors := []grpc.UnaryClientInterceptor{}
+	if tracing.Enabled(config) {
+		interceptors = append(interceptors, infrastructure.NewVehicleServiceTracingInterceptor())
+	}
+	if m != nil {
+		interceptors = append(interceptors, infrastructure.NewVehicleServiceMetricsInterceptor(m))
+	}
+	interceptors = append(interceptors,
+		infrastructure.NewVehicleServiceInterceptor(NewVehicleServiceClientOptions(config), logger))
+
+	return []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithPerRPCCredentials(
+			infrastructure.NewVehicleServiceRPCCredentials(c.Auth.Token, c.Auth.ForwardToken, c.Tls.Enabled),
+		),
+		grpc.WithChainUnaryInterceptor(interceptors...),
+	}, nil
+}
+
+// GrpcConnHealthCheck fails while the connection is not usable. An idle
+// connection is asked to connect and counts as available, it has not failed yet.
+func GrpcConnHealthCheck(conn *grpc.ClientConn) server.HealthCheck {
+	return func(ctx context.Context) error {
+		switch state := conn.GetState(); state {
+		case connectivity.Ready:
+			return nil
+		case connectivity.Idle:
+			conn.Connect()
+			return nil
+		default:
+			return fmt.Errorf("connection is %s", strings.ToLower(state.String()))
+		}
+	}
+}
+
+// NewVehicleServiceClientOptions maps the config to the vehicle service client options
+func NewVehicleServiceClientOptions(config *config.Config) infrastructure.VehicleServiceClientOptions {
+	c := config.VehicleService
+
+	return infrastructure.VehicleServiceClientOptions{
+		Timeout:          time.Duration(c.Timeout) * time.Second,
+		MaxRetries:       c.MaxRetries,
+		Backoff:          time.Duration(c.RetryBackoff) * time.Millisecond,
+		BreakerThreshold: c.BreakerThreshold,
+		BreakerTimeout:   time.Duration(c.BreakerTimeout) * time.Second,
+	}
+}
+
+// NewRedisClientWithConfig returns a single node, sentinel or cluster client
+// depending on the Redis.Mode
+func NewRedisClientWithConfig(config *config.Config) (redis.UniversalClient, error) {
+	c := config.Redis
+
+	addrs := c.Addrs
+	if len(addrs) == 0 {
+		addrs = []string{c.Addr}
+	}
+
+	switch c.Mode {
+	case "", RedisModeSingle:
+		return redis.NewClient(&redis.Options{
+			Addr:       c.Addr,
+			Password:   c.Password,
+			PoolSize:   c.PoolSize,
+			DB:         c.DB,
+			MaxRetries: c.MaxRetries,
+		}), nil
+	case RedisModeSentinel:
+		if c.MasterName == "" {
+			return nil, errors.New("redis sentinel master name is empty")
+		}
+
+		opts := &redis.FailoverOptions{
+			MasterName:       c.MasterName,
+			SentinelAddrs:    addrs,
+			SentinelPassword: c.SentinelPassword,
+			Password:         c.Password,
+			PoolSize:         c.PoolSize,
+			DB:               c.DB,
+			MaxRetries:       c.MaxRetries,
+			RouteByLatency:   c.RouteByLatency,
+			RouteRandomly:    c.RouteRandomly,
+		}
+
+		// replica routing is only supported by the cluster flavoured failover client
+		if c.RouteByLatency || c.RouteRandomly {
+			return redis.NewFailoverClusterClient(opts), nil
+		}
+
+		return redis.NewFailoverClient(opts), nil
+	case RedisModeCluster:
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:          addrs,
+			Password:       c.Password,
+			PoolSize:       c.PoolSize,
+			MaxRetries:     c.MaxRetries,
+			MaxRedirects:   c.MaxRedirects,
+			ReadOnly:       c.ReadOnly,
+			RouteByLatency: c.RouteByLatency,
+			RouteRandomly:  c.RouteRandomly,
+		}), nil
 	}
 
-	return redis.NewClient(redisOptions)
+	return nil, fmt.Errorf("unknown redis mode: %s", c.Mode)
 }
@@ -0,0 +1,278 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/orkungursel/hey-taxi-location-api/config"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestNewRedisClientWithConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    func(rc redis.UniversalClient) bool
+		wantErr bool
+	}{
+		{
+			name: "should return single node client by default",
+			want: func(rc redis.UniversalClient) bool {
+				_, ok := rc.(*redis.Client)
+				return ok
+			},
+		},
+		{
+			name: "should return failover client in sentinel mode",
+			env: map[string]string{
+				"REDIS_MODE":        "sentinel",
+				"REDIS_ADDRS":       "localhost:26379,localhost:26380",
+				"REDIS_MASTER_NAME": "mymaster",
+			},
+			want: func(rc redis.UniversalClient) bool {
+				_, ok := rc.(*redis.Client)
+				return ok
+			},
+		},
+		{
+			name: "should return failover cluster client in sentinel mode with replica routing",
+			env: map[string]string{
+				"REDIS_MODE":             "sentinel",
+				"REDIS_ADDRS":            "localhost:26379",
+				"REDIS_MASTER_NAME":      "mymaster",
+				"REDIS_ROUTE_BY_LATENCY": "true",
+			},
+			want: func(rc redis.UniversalClient) bool {
+				_, ok := rc.(*redis.ClusterClient)
+				return ok
+			},
+		},
+		{
+			name: "should fail in sentinel mode without master name",
+			env: map[string]string{
+				"REDIS_MODE": "sentinel",
+			},
+			wantErr: true,
+		},
+		{
+			name: "should return cluster client in cluster mode",
+			env: map[string]string{
+				"REDIS_MODE":  "cluster",
+				"REDIS_ADDRS": "localhost:7000,localhost:7001,localhost:7002",
+			},
+			want: func(rc redis.UniversalClient) bool {
+				_, ok := rc.(*redis.ClusterClient)
+				return ok
+			},
+		},
+		{
+			name: "should fail with unknown mode",
+			env: map[string]string{
+				"REDIS_MODE": "unknown",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			rc, err := NewRedisClientWithConfig(config.New())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewRedisClientWithConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			defer rc.Close()
+
+			if !tt.want(rc) {
+				t.Errorf("NewRedisClientWithConfig() = %T", rc)
+			}
+		})
+	}
+}
+
+func TestNewVehicleServiceDialOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{
+			name: "should use insecure credentials by default",
+		},
+		{
+			name: "should use tls with the system pool",
+			env: map[string]string{
+				"VEHICLE_SERVICE_TLS_ENABLED": "true",
+			},
+		},
+		{
+			name: "should fail when ca file does not exist",
+			env: map[string]string{
+				"VEHICLE_SERVICE_TLS_ENABLED": "true",
+				"VEHICLE_SERVICE_TLS_CA_FILE": "/not/exist",
+			},
+			wantErr: true,
+		},
+		{
+			name: "should forward the access token over tls",
+			env: map[string]string{
+				"VEHICLE_SERVICE_TLS_ENABLED":        "true",
+				"VEHICLE_SERVICE_AUTH_FORWARD_TOKEN": "true",
+			},
+		},
+		{
+			name: "should refuse to forward the access token without tls",
+			env: map[string]string{
+				"VEHICLE_SERVICE_AUTH_FORWARD_TOKEN": "true",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			opts, err := NewVehicleServiceDialOptions(config.New(), mock.NewLoggerMock(), nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewVehicleServiceDialOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(opts) == 0 {
+				t.Error("NewVehicleServiceDialOptions() returned no options")
+			}
+		})
+	}
+}
+
+func TestGrpcConnHealthCheck(t *testing.T) {
+	conn, err := grpc.Dial("localhost:0", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	check := GrpcConnHealthCheck(conn)
+
+	// an idle connection has not failed yet
+	if err := check(context.Background()); err != nil {
+		t.Errorf("GrpcConnHealthCheck() error = %v", err)
+	}
+
+	conn.Close()
+	if err := check(context.Background()); err == nil {
+		t.Error("GrpcConnHealthCheck() should fail on a closed connection")
+	}
+}
+
+func TestNewLocationCoalescer(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want bool
+	}{
+		{
+			name: "should coalesce by default",
+			want: true,
+		},
+		{
+			name: "should not coalesce with zero distance",
+			env:  map[string]string{"COALESCE_DISTANCE": "0"},
+		},
+		{
+			name: "should not coalesce with write-behind",
+			env:  map[string]string{"WRITE_BEHIND_ENABLED": "true"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			if got := NewLocationCoalescer(config.New()); (got != nil) != tt.want {
+				t.Errorf("NewLocationCoalescer() = %v, want coalescer %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewLocationRepository(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{
+			name: "should return sharded repository",
+			env:  map[string]string{"STORAGE_SHARDING_ENABLED": "true"},
+		},
+		{
+			name: "should refuse sharding in cluster mode",
+			env: map[string]string{
+				"STORAGE_SHARDING_ENABLED": "true",
+				"REDIS_MODE":               "cluster",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			_, err := NewLocationRepository(config.New(), redis.NewClient(&redis.Options{}), nil, mock.NewLoggerMock())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewLocationRepository() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewRevokedTokenRepository(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		rc      redis.UniversalClient
+		want    bool
+		wantErr bool
+	}{
+		{
+			name: "should return redis revocation list",
+			rc:   redis.NewClient(&redis.Options{}),
+			want: true,
+		},
+		{
+			name:    "should fail without redis",
+			wantErr: true,
+		},
+		{
+			name: "should return nil when disabled",
+			env:  map[string]string{"JWT_REVOCATION": "false"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			got, err := NewRevokedTokenRepository(config.New(), tt.rc, mock.NewLoggerMock())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewRevokedTokenRepository() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (got != nil) != tt.want {
+				t.Errorf("NewRevokedTokenRepository() = %v, want repository %v", got, tt.want)
+			}
+		})
+	}
+}
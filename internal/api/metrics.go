@@ -0,0 +1,51 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-location-api/internal/infrastructure"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/metrics"
+)
+
+const (
+	countLocationsTimeout = time.Second // countLocationsTimeout bounds the count of a scrape
+)
+
+// locationCounter is implemented by the location repositories which can count their drivers
+type locationCounter interface {
+	Count(ctx context.Context) (int64, error)
+}
+
+// countLocations returns the value func of the active drivers gauge
+func countLocations(counter locationCounter, logger logger.ILogger) func() float64 {
+	return func() float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), countLocationsTimeout)
+		defer cancel()
+
+		n, err := counter.Count(ctx)
+		if err != nil {
+			logger.Warnf("failed to count the drivers: %v", err)
+			return 0
+		}
+
+		return float64(n)
+	}
+}
+
+// registerWriteBehindMetrics exposes the queue of the buffered location saves
+func registerWriteBehindMetrics(m *metrics.Metrics, r *infrastructure.BufferedLocationRepository) error {
+	if err := m.GaugeFunc("write_behind_queue_depth", "Vehicles waiting for the next write-behind batch.",
+		func() float64 { return float64(r.Depth()) }); err != nil {
+		return err
+	}
+
+	if err := m.CounterFunc("write_behind_written_total", "Locations written in write-behind batches.",
+		func() float64 { return float64(r.Written()) }); err != nil {
+		return err
+	}
+
+	return m.CounterFunc("write_behind_dropped_total", "Locations lost with the failed write-behind batches.",
+		func() float64 { return float64(r.Dropped()) })
+}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/orkungursel/hey-taxi-location-api/config"
+	"github.com/orkungursel/hey-taxi-location-api/internal/app"
+	"github.com/orkungursel/hey-taxi-location-api/internal/infrastructure"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger"
+)
+
+const (
+	StorageRedis    = "redis"
+	StorageMemory   = "memory"
+	StoragePostgres = "postgres"
+)
+
+// UsesRedis returns true if any of the repositories is backed by redis
+func UsesRedis(c *config.Config) bool {
+	return c.Storage.Location == StorageRedis || c.Storage.Vehicle == StorageRedis
+}
+
+// UsesPostgres returns true if any of the repositories is backed by postgres
+func UsesPostgres(c *config.Config) bool {
+	return c.Storage.Location == StoragePostgres
+}
+
+// NewPostgresPoolWithConfig connects to postgres and applies the migrations if enabled
+func NewPostgresPoolWithConfig(ctx context.Context, c *config.Config,
+	logger logger.ILogger) (*pgxpool.Pool, error) {
+
+	pc, err := pgxpool.ParseConfig(c.Postgres.Dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Postgres.MaxConns > 0 {
+		pc.MaxConns = int32(c.Postgres.MaxConns)
+	}
+
+	db, err := pgxpool.ConnectConfig(ctx, pc)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Postgres.Migrate {
+		if err := infrastructure.MigratePostgres(ctx, db, logger); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	return db, nil
+}
+
+// NewLocationRepository returns the location repository of the configured storage
+func NewLocationRepository(c *config.Config, rc redis.UniversalClient, pg *pgxpool.Pool,
+	logger logger.ILogger) (app.LocationRepository, error) {
+
+	switch c.Storage.Location {
+	case StorageRedis:
+		if sh := c.Storage.Sharding; sh.Enabled {
+			// the shard keys of a vehicle are moved by a script, they cannot be in different slots
+			if c.Redis.Mode == RedisModeCluster {
+				return nil, errors.New("location sharding is not supported in redis cluster mode")
+			}
+			return infrastructure.NewShardedLocationRepository(rc, logger, sh.Precision, sh.Regions)
+		}
+		return infrastructure.NewLocationRepository(rc, logger), nil
+	case StorageMemory:
+		return infrastructure.NewMemoryLocationRepository(logger), nil
+	case StoragePostgres:
+		if pg == nil {
+			return nil, errors.New("postgres pool is nil")
+		}
+		return infrastructure.NewPostgresLocationRepository(pg, logger), nil
+	}
+
+	return nil, fmt.Errorf("unknown location storage: %s", c.Storage.Location)
+}
+
+// NewLocationCoalescer returns the location coalescer, or nil if coalescing is disabled or
+// write-behind is enabled. A queued location is not written yet, the coalescer
+// would skip the next pings of the vehicle even if its batch failed.
+func NewLocationCoalescer(c *config.Config) *infrastructure.LocationCoalescer {
+	if c.WriteBehind.Enabled {
+		return nil
+	}
+
+	return infrastructure.NewLocationCoalescer(float64(c.Coalesce.Distance),
+		time.Duration(c.Coalesce.Interval)*time.Second)
+}
+
+// NewVehicleRepository returns the vehicle repository of the configured storage
+func NewVehicleRepository(c *config.Config, rc redis.UniversalClient,
+	logger logger.ILogger) (app.VehicleRepository, error) {
+
+	switch c.Storage.Vehicle {
+	case StorageRedis:
+		return infrastructure.NewVehicleRepository(rc, logger), nil
+	case StorageMemory:
+		return infrastructure.NewMemoryVehicleRepository(logger), nil
+	}
+
+	return nil, fmt.Errorf("unknown vehicle storage: %s", c.Storage.Vehicle)
+}
+
+// NewVehicleEventSource returns the redis pub/sub source if redis is in use,
+// otherwise the in-process one
+func NewVehicleEventSource(c *config.Config, rc redis.UniversalClient,
+	logger logger.ILogger) app.VehicleEventSource {
+
+	if rc != nil {
+		return infrastructure.NewRedisVehicleEventSource(rc, logger, c.VehicleEvents.Channel)
+	}
+
+	return infrastructure.NewMemoryVehicleEventSource()
+}
+
+// NewAssignmentRepository returns the redis assignment repository if redis is in use,
+// otherwise the in-process one
+func NewAssignmentRepository(rc redis.UniversalClient, logger logger.ILogger) app.AssignmentRepository {
+	if rc != nil {
+		return infrastructure.NewAssignmentRepository(rc, logger)
+	}
+
+	return infrastructure.NewMemoryAssignmentRepository()
+}
+
+// NewLastSeenRepository returns the redis last-seen repository if redis is in
+// use, otherwise the in-process one
+func NewLastSeenRepository(c *config.Config, rc redis.UniversalClient, logger logger.ILogger) app.LastSeenRepository {
+	if rc != nil {
+		return infrastructure.NewLastSeenRepository(rc, logger, time.Duration(c.Coalesce.Interval)*time.Second,
+			time.Duration(c.Coalesce.LastSeenRetention)*time.Second)
+	}
+
+	return infrastructure.NewMemoryLastSeenRepository()
+}
+
+// NewRevokedTokenRepository returns the redis revocation list if it is enabled,
+// otherwise nil which disables the revocation check. It fails if it is enabled
+// but redis is not in use, the revoked tokens would be accepted silently.
+func NewRevokedTokenRepository(c *config.Config, rc redis.UniversalClient,
+	logger logger.ILogger) (app.RevokedTokenRepository, error) {
+
+	if !c.Jwt.Revocation {
+		return nil, nil
+	}
+
+	if rc == nil {
+		return nil, errors.New("token revocation requires redis, disable it with JWT_REVOCATION=false")
+	}
+
+	return infrastructure.NewRevokedTokenRepository(rc, logger), nil
+}
+
+// NewApiKeyService returns the redis api key service if api keys are enabled
+// and redis is in use, otherwise nil which disables the api key authentication
+func NewApiKeyService(c *config.Config, rc redis.UniversalClient, logger logger.ILogger) app.ApiKeyService {
+	if !c.ApiKeys.Enabled || rc == nil {
+		return nil
+	}
+
+	return infrastructure.NewApiKeyService(rc, logger)
+}
+
+// NewRateLimiter returns the redis rate limiter if redis is in use, otherwise the
+// in-process one, or nil if rate limiting is disabled
+func NewRateLimiter(c *config.Config, rc redis.UniversalClient, logger logger.ILogger) app.RateLimiter {
+	if !c.RateLimit.Enabled {
+		return nil
+	}
+
+	if rc != nil {
+		return infrastructure.NewRateLimiter(rc, logger)
+	}
+
+	return infrastructure.NewMemoryRateLimiter()
+}
@@ -0,0 +1,17 @@
+package app
+
+import "context"
+
+type accessTokenContextKey struct{}
+
+// WithAccessToken returns a copy of the context which carries the raw access token
+// of the request, so it can be forwarded to the downstream services
+func WithAccessToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, accessTokenContextKey{}, token)
+}
+
+// AccessTokenFromContext returns the raw access token of the context, or an empty string
+func AccessTokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(accessTokenContextKey{}).(string)
+	return token
+}
@@ -0,0 +1,12 @@
+//go:generate mockgen -source api_key_service.go -destination mock/api_key_service_mock.go -package mock
+package app
+
+import "context"
+
+// ApiKeyService authenticates the internal services, which call the api
+// without a user token, by their api keys
+type ApiKeyService interface {
+	ValidateApiKey(ctx context.Context, key string) (Claims, error)
+	// CreateApiKey issues a key of the service for the tenant of the context
+	CreateApiKey(ctx context.Context, in CreateApiKeyRequest) (*CreateApiKeyResponse, error)
+}
@@ -0,0 +1,12 @@
+//go:generate mockgen -source assignment_repository.go -destination mock/assignment_repository_mock.go -package mock
+package app
+
+import "context"
+
+// AssignmentRepository tells which rider a vehicle is assigned to, the
+// assignments are written by the dispatch service
+type AssignmentRepository interface {
+	GetAssignedRider(ctx context.Context, vehicleId string) (string, error)
+	// GetAssignedRiders returns the riders of the assigned vehicles by vehicle id
+	GetAssignedRiders(ctx context.Context, vehicleIds []string) (map[string]string, error)
+}
@@ -0,0 +1,16 @@
+package app
+
+import "context"
+
+type claimsContextKey struct{}
+
+// WithClaims returns a copy of the context which carries the claims of the caller
+func WithClaims(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, claimsContextKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims of the caller, or nil
+func ClaimsFromContext(ctx context.Context) Claims {
+	claims, _ := ctx.Value(claimsContextKey{}).(Claims)
+	return claims
+}
@@ -11,11 +11,43 @@ var (
 	ErrUserNotFound   = errors.New("user not found")
 	ErrInvalidToken   = errors.New("invalid token")
 	ErrInvalidUserId  = errors.New("invalid user id")
+
+	// ErrForbidden is returned when the role of the caller may not call the route
+	ErrForbidden = NewErrorWithCode(http.StatusForbidden, "forbidden", errors.New("forbidden"))
+
+	// ErrVehicleNotFound is returned when the vehicle service does not know the vehicle
+	ErrVehicleNotFound = NewError(http.StatusNotFound, errors.New("vehicle not found"))
+
+	// ErrUnauthorized is returned when the caller could not be authenticated
+	ErrUnauthorized = NewErrorWithCode(http.StatusUnauthorized, "unauthorized", errors.New("unauthorized"))
+
+	// the reasons why an access token is rejected
+	ErrTokenMissing     = newTokenError("token_missing", "token is missing")
+	ErrTokenScheme      = newTokenError("token_scheme", "authorization scheme is not supported")
+	ErrTokenMalformed   = newTokenError("token_malformed", "token is malformed")
+	ErrTokenAlgorithm   = newTokenError("token_algorithm", "token algorithm is not allowed")
+	ErrTokenUnknownKey  = newTokenError("token_unknown_key", "token key is unknown")
+	ErrTokenSignature   = newTokenError("token_signature", "token signature is invalid")
+	ErrTokenExpired     = newTokenError("token_expired", "token is expired")
+	ErrTokenNotYetValid = newTokenError("token_not_yet_valid", "token is not valid yet")
+	ErrTokenIssuer      = newTokenError("token_issuer", "token issuer is invalid")
+	ErrTokenAudience    = newTokenError("token_audience", "token audience is invalid")
+	ErrTokenRevoked     = newTokenError("token_revoked", "token is revoked")
+
+	// ErrTooManyRequests is returned when the caller exceeds the rate limit of the route
+	ErrTooManyRequests = NewErrorWithCode(http.StatusTooManyRequests, "rate_limited", errors.New("too many requests"))
+
+	// ErrSaveQueueFull is returned when the write-behind queue stays full until the request is done
+	ErrSaveQueueFull = NewErrorWithCode(http.StatusServiceUnavailable, "save_queue_full", errors.New("save queue is full"))
+
+	// ErrApiKeyInvalid is returned when the api key of a service is unknown or revoked
+	ErrApiKeyInvalid = NewErrorWithCode(http.StatusUnauthorized, "api_key_invalid", errors.New("api key is invalid"))
 )
 
 type Error struct {
-	code int
-	err  error
+	code      int
+	errorCode string
+	err       error
 }
 
 func NewError(code int, err error) *Error {
@@ -25,6 +57,20 @@ func NewError(code int, err error) *Error {
 	}
 }
 
+// NewErrorWithCode returns an error which carries a machine readable code,
+// e.g. token_expired, next to the message
+func NewErrorWithCode(code int, errorCode string, err error) *Error {
+	return &Error{
+		code:      code,
+		errorCode: errorCode,
+		err:       err,
+	}
+}
+
+func newTokenError(errorCode, message string) *Error {
+	return NewErrorWithCode(http.StatusUnauthorized, errorCode, errors.New(message))
+}
+
 func NewErrorf(code int, format string, args ...interface{}) *Error {
 	return &Error{
 		code: code,
@@ -48,6 +94,11 @@ func (e Error) Code() int {
 	return e.code
 }
 
+// ErrorCode returns the machine readable code of the error, or an empty string
+func (e Error) ErrorCode() string {
+	return e.errorCode
+}
+
 func (e Error) Error() string {
 	return e.err.Error()
 }
@@ -0,0 +1,15 @@
+//go:generate mockgen -source last_seen_repository.go -destination mock/last_seen_repository_mock.go -package mock
+package app
+
+import (
+	"context"
+	"time"
+)
+
+// LastSeenRepository keeps when the vehicles sent their last stored ping, the
+// pings skipped by the coalescing are only known by the coalescer
+type LastSeenRepository interface {
+	Touch(ctx context.Context, vehicleId string, at time.Time) error
+	// LastSeen returns the time of the last ping of the vehicle, zero if it is unknown
+	LastSeen(ctx context.Context, vehicleId string) (time.Time, error)
+}
@@ -0,0 +1,66 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: api_key_service.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	app "github.com/orkungursel/hey-taxi-location-api/internal/app"
+)
+
+// MockApiKeyService is a mock of ApiKeyService interface.
+type MockApiKeyService struct {
+	ctrl     *gomock.Controller
+	recorder *MockApiKeyServiceMockRecorder
+}
+
+// MockApiKeyServiceMockRecorder is the mock recorder for MockApiKeyService.
+type MockApiKeyServiceMockRecorder struct {
+	mock *MockApiKeyService
+}
+
+// NewMockApiKeyService creates a new mock instance.
+func NewMockApiKeyService(ctrl *gomock.Controller) *MockApiKeyService {
+	mock := &MockApiKeyService{ctrl: ctrl}
+	mock.recorder = &MockApiKeyServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockApiKeyService) EXPECT() *MockApiKeyServiceMockRecorder {
+	return m.recorder
+}
+
+// CreateApiKey mocks base method.
+func (m *MockApiKeyService) CreateApiKey(ctx context.Context, in app.CreateApiKeyRequest) (*app.CreateApiKeyResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateApiKey", ctx, in)
+	ret0, _ := ret[0].(*app.CreateApiKeyResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateApiKey indicates an expected call of CreateApiKey.
+func (mr *MockApiKeyServiceMockRecorder) CreateApiKey(ctx, in interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateApiKey", reflect.TypeOf((*MockApiKeyService)(nil).CreateApiKey), ctx, in)
+}
+
+// ValidateApiKey mocks base method.
+func (m *MockApiKeyService) ValidateApiKey(ctx context.Context, key string) (app.Claims, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateApiKey", ctx, key)
+	ret0, _ := ret[0].(app.Claims)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ValidateApiKey indicates an expected call of ValidateApiKey.
+func (mr *MockApiKeyServiceMockRecorder) ValidateApiKey(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateApiKey", reflect.TypeOf((*MockApiKeyService)(nil).ValidateApiKey), ctx, key)
+}
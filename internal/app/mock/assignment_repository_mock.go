@@ -0,0 +1,65 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: assignment_repository.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockAssignmentRepository is a mock of AssignmentRepository interface.
+type MockAssignmentRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAssignmentRepositoryMockRecorder
+}
+
+// MockAssignmentRepositoryMockRecorder is the mock recorder for MockAssignmentRepository.
+type MockAssignmentRepositoryMockRecorder struct {
+	mock *MockAssignmentRepository
+}
+
+// NewMockAssignmentRepository creates a new mock instance.
+func NewMockAssignmentRepository(ctrl *gomock.Controller) *MockAssignmentRepository {
+	mock := &MockAssignmentRepository{ctrl: ctrl}
+	mock.recorder = &MockAssignmentRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAssignmentRepository) EXPECT() *MockAssignmentRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetAssignedRider mocks base method.
+func (m *MockAssignmentRepository) GetAssignedRider(ctx context.Context, vehicleId string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAssignedRider", ctx, vehicleId)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAssignedRider indicates an expected call of GetAssignedRider.
+func (mr *MockAssignmentRepositoryMockRecorder) GetAssignedRider(ctx, vehicleId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAssignedRider", reflect.TypeOf((*MockAssignmentRepository)(nil).GetAssignedRider), ctx, vehicleId)
+}
+
+// GetAssignedRiders mocks base method.
+func (m *MockAssignmentRepository) GetAssignedRiders(ctx context.Context, vehicleIds []string) (map[string]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAssignedRiders", ctx, vehicleIds)
+	ret0, _ := ret[0].(map[string]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAssignedRiders indicates an expected call of GetAssignedRiders.
+func (mr *MockAssignmentRepositoryMockRecorder) GetAssignedRiders(ctx, vehicleIds interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAssignedRiders", reflect.TypeOf((*MockAssignmentRepository)(nil).GetAssignedRiders), ctx, vehicleIds)
+}
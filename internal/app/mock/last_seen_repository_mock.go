@@ -0,0 +1,65 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: last_seen_repository.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockLastSeenRepository is a mock of LastSeenRepository interface.
+type MockLastSeenRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockLastSeenRepositoryMockRecorder
+}
+
+// MockLastSeenRepositoryMockRecorder is the mock recorder for MockLastSeenRepository.
+type MockLastSeenRepositoryMockRecorder struct {
+	mock *MockLastSeenRepository
+}
+
+// NewMockLastSeenRepository creates a new mock instance.
+func NewMockLastSeenRepository(ctrl *gomock.Controller) *MockLastSeenRepository {
+	mock := &MockLastSeenRepository{ctrl: ctrl}
+	mock.recorder = &MockLastSeenRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLastSeenRepository) EXPECT() *MockLastSeenRepositoryMockRecorder {
+	return m.recorder
+}
+
+// LastSeen mocks base method.
+func (m *MockLastSeenRepository) LastSeen(ctx context.Context, vehicleId string) (time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LastSeen", ctx, vehicleId)
+	ret0, _ := ret[0].(time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LastSeen indicates an expected call of LastSeen.
+func (mr *MockLastSeenRepositoryMockRecorder) LastSeen(ctx, vehicleId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LastSeen", reflect.TypeOf((*MockLastSeenRepository)(nil).LastSeen), ctx, vehicleId)
+}
+
+// Touch mocks base method.
+func (m *MockLastSeenRepository) Touch(ctx context.Context, vehicleId string, at time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Touch", ctx, vehicleId, at)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Touch indicates an expected call of Touch.
+func (mr *MockLastSeenRepositoryMockRecorder) Touch(ctx, vehicleId, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Touch", reflect.TypeOf((*MockLastSeenRepository)(nil).Touch), ctx, vehicleId, at)
+}
@@ -0,0 +1,50 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: revoked_token_repository.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockRevokedTokenRepository is a mock of RevokedTokenRepository interface.
+type MockRevokedTokenRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRevokedTokenRepositoryMockRecorder
+}
+
+// MockRevokedTokenRepositoryMockRecorder is the mock recorder for MockRevokedTokenRepository.
+type MockRevokedTokenRepositoryMockRecorder struct {
+	mock *MockRevokedTokenRepository
+}
+
+// NewMockRevokedTokenRepository creates a new mock instance.
+func NewMockRevokedTokenRepository(ctrl *gomock.Controller) *MockRevokedTokenRepository {
+	mock := &MockRevokedTokenRepository{ctrl: ctrl}
+	mock.recorder = &MockRevokedTokenRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRevokedTokenRepository) EXPECT() *MockRevokedTokenRepositoryMockRecorder {
+	return m.recorder
+}
+
+// IsRevoked mocks base method.
+func (m *MockRevokedTokenRepository) IsRevoked(ctx context.Context, tokenId string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsRevoked", ctx, tokenId)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsRevoked indicates an expected call of IsRevoked.
+func (mr *MockRevokedTokenRepositoryMockRecorder) IsRevoked(ctx, tokenId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsRevoked", reflect.TypeOf((*MockRevokedTokenRepository)(nil).IsRevoked), ctx, tokenId)
+}
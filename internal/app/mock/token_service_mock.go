@@ -64,6 +64,20 @@ func (mr *MockClaimsMockRecorder) GetRole() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRole", reflect.TypeOf((*MockClaims)(nil).GetRole))
 }
 
+// GetScopes mocks base method.
+func (m *MockClaims) GetScopes() []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetScopes")
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// GetScopes indicates an expected call of GetScopes.
+func (mr *MockClaimsMockRecorder) GetScopes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetScopes", reflect.TypeOf((*MockClaims)(nil).GetScopes))
+}
+
 // GetSubject mocks base method.
 func (m *MockClaims) GetSubject() string {
 	m.ctrl.T.Helper()
@@ -78,6 +92,20 @@ func (mr *MockClaimsMockRecorder) GetSubject() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubject", reflect.TypeOf((*MockClaims)(nil).GetSubject))
 }
 
+// GetTenant mocks base method.
+func (m *MockClaims) GetTenant() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTenant")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GetTenant indicates an expected call of GetTenant.
+func (mr *MockClaimsMockRecorder) GetTenant() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTenant", reflect.TypeOf((*MockClaims)(nil).GetTenant))
+}
+
 // MockTokenService is a mock of TokenService interface.
 type MockTokenService struct {
 	ctrl     *gomock.Controller
@@ -101,6 +129,21 @@ func (m *MockTokenService) EXPECT() *MockTokenServiceMockRecorder {
 	return m.recorder
 }
 
+// ExtractAccessToken mocks base method.
+func (m *MockTokenService) ExtractAccessToken(r *http.Request) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExtractAccessToken", r)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExtractAccessToken indicates an expected call of ExtractAccessToken.
+func (mr *MockTokenServiceMockRecorder) ExtractAccessToken(r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExtractAccessToken", reflect.TypeOf((*MockTokenService)(nil).ExtractAccessToken), r)
+}
+
 // ParseToken mocks base method.
 func (m *MockTokenService) ParseToken(ctx context.Context, token string) (app.Claims, error) {
 	m.ctrl.T.Helper()
@@ -0,0 +1,51 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: vehicle_event.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	app "github.com/orkungursel/hey-taxi-location-api/internal/app"
+)
+
+// MockVehicleEventSource is a mock of VehicleEventSource interface.
+type MockVehicleEventSource struct {
+	ctrl     *gomock.Controller
+	recorder *MockVehicleEventSourceMockRecorder
+}
+
+// MockVehicleEventSourceMockRecorder is the mock recorder for MockVehicleEventSource.
+type MockVehicleEventSourceMockRecorder struct {
+	mock *MockVehicleEventSource
+}
+
+// NewMockVehicleEventSource creates a new mock instance.
+func NewMockVehicleEventSource(ctrl *gomock.Controller) *MockVehicleEventSource {
+	mock := &MockVehicleEventSource{ctrl: ctrl}
+	mock.recorder = &MockVehicleEventSourceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockVehicleEventSource) EXPECT() *MockVehicleEventSourceMockRecorder {
+	return m.recorder
+}
+
+// Subscribe mocks base method.
+func (m *MockVehicleEventSource) Subscribe(ctx context.Context) (<-chan app.VehicleEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Subscribe", ctx)
+	ret0, _ := ret[0].(<-chan app.VehicleEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Subscribe indicates an expected call of Subscribe.
+func (mr *MockVehicleEventSourceMockRecorder) Subscribe(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Subscribe", reflect.TypeOf((*MockVehicleEventSource)(nil).Subscribe), ctx)
+}
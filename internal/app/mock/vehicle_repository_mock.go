@@ -64,6 +64,21 @@ func (mr *MockVehicleRepositoryMockRecorder) Get(ctx, vehicleId interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockVehicleRepository)(nil).Get), ctx, vehicleId)
 }
 
+// GetMany mocks base method.
+func (m *MockVehicleRepository) GetMany(ctx context.Context, vehicleIds []string) (map[string]*model.Vehicle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMany", ctx, vehicleIds)
+	ret0, _ := ret[0].(map[string]*model.Vehicle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMany indicates an expected call of GetMany.
+func (mr *MockVehicleRepositoryMockRecorder) GetMany(ctx, vehicleIds interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMany", reflect.TypeOf((*MockVehicleRepository)(nil).GetMany), ctx, vehicleIds)
+}
+
 // Save mocks base method.
 func (m *MockVehicleRepository) Save(ctx context.Context, vehicle *model.Vehicle) error {
 	m.ctrl.T.Helper()
@@ -49,3 +49,47 @@ func (mr *MockVehicleServiceMockRecorder) GetVehicleById(ctx, vehicleId interfac
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVehicleById", reflect.TypeOf((*MockVehicleService)(nil).GetVehicleById), ctx, vehicleId)
 }
+
+// GetVehiclesByIds mocks base method.
+func (m *MockVehicleService) GetVehiclesByIds(ctx context.Context, vehicleIds []string) (map[string]*model.Vehicle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVehiclesByIds", ctx, vehicleIds)
+	ret0, _ := ret[0].(map[string]*model.Vehicle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVehiclesByIds indicates an expected call of GetVehiclesByIds.
+func (mr *MockVehicleServiceMockRecorder) GetVehiclesByIds(ctx, vehicleIds interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVehiclesByIds", reflect.TypeOf((*MockVehicleService)(nil).GetVehiclesByIds), ctx, vehicleIds)
+}
+
+// PurgeVehicle mocks base method.
+func (m *MockVehicleService) PurgeVehicle(ctx context.Context, vehicleId string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeVehicle", ctx, vehicleId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PurgeVehicle indicates an expected call of PurgeVehicle.
+func (mr *MockVehicleServiceMockRecorder) PurgeVehicle(ctx, vehicleId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeVehicle", reflect.TypeOf((*MockVehicleService)(nil).PurgeVehicle), ctx, vehicleId)
+}
+
+// RefreshVehicle mocks base method.
+func (m *MockVehicleService) RefreshVehicle(ctx context.Context, vehicleId string) (*model.Vehicle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshVehicle", ctx, vehicleId)
+	ret0, _ := ret[0].(*model.Vehicle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RefreshVehicle indicates an expected call of RefreshVehicle.
+func (mr *MockVehicleServiceMockRecorder) RefreshVehicle(ctx, vehicleId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshVehicle", reflect.TypeOf((*MockVehicleService)(nil).RefreshVehicle), ctx, vehicleId)
+}
@@ -0,0 +1,80 @@
+package app
+
+// PublicDriver is the driver as seen by the riders, without the id and the contact details
+type PublicDriver struct {
+	Name      string  `json:"name"`
+	Nickname  string  `json:"nickname"`
+	Picture   string  `json:"picture"`
+	Rating    float64 `json:"rating"`
+	PhoneMask string  `json:"phone_mask"` // e.g. +90*******67
+} // @name PublicDriver
+
+// PublicVehicle is the vehicle as seen by the riders
+type PublicVehicle struct {
+	Id     string       `json:"vehicle_id"`
+	Name   string       `json:"name"`
+	Plate  string       `json:"plate"`
+	Type   string       `json:"type"`
+	Class  string       `json:"class"`
+	Seats  int          `json:"seats"`
+	Color  string       `json:"color"`
+	Driver PublicDriver `json:"driver"`
+} // @name PublicVehicle
+
+// PublicLocationResponse is the search result returned instead of LocationResponse to
+// the riders and the other callers which may not see the full vehicle
+type PublicLocationResponse struct {
+	Vehicle PublicVehicle `json:"vehicle"`
+	Lat     float64       `json:"lat"`
+	Lng     float64       `json:"lng"`
+	Dist    float64       `json:"dist"`
+} // @name PublicLocationResponse
+
+// ProjectLocationResponses shapes the search results for the role of the claims:
+// admins, dispatchers and the services with the location:full scope see everything,
+// drivers see only their own vehicles and everyone else, riders included, gets the
+// public projection
+func ProjectLocationResponses(claims Claims, in []LocationResponse) interface{} {
+	if SeesFullLocations(claims) {
+		return in
+	}
+
+	switch claims.GetRole() {
+	case RoleDriver:
+		out := make([]LocationResponse, 0)
+		for _, l := range in {
+			if l.Vehicle.Driver.Id == claims.GetSubject() {
+				out = append(out, l)
+			}
+		}
+		return out
+	}
+
+	out := make([]PublicLocationResponse, len(in))
+	for i, l := range in {
+		v := l.Vehicle
+		out[i] = PublicLocationResponse{
+			Vehicle: PublicVehicle{
+				Id:    v.Id,
+				Name:  v.Name,
+				Plate: v.Plate,
+				Type:  v.Type,
+				Class: v.Class,
+				Seats: v.Seats,
+				Color: v.Color,
+				Driver: PublicDriver{
+					Name:      v.Driver.Name,
+					Nickname:  v.Driver.Nickname,
+					Picture:   v.Driver.Picture,
+					Rating:    v.Driver.Rating,
+					PhoneMask: v.Driver.PhoneMask,
+				},
+			},
+			Lat:  l.Lat,
+			Lng:  l.Lng,
+			Dist: l.Dist,
+		}
+	}
+
+	return out
+}
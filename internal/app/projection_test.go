@@ -0,0 +1,89 @@
+package app
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/orkungursel/hey-taxi-location-api/internal/domain/model"
+)
+
+type testClaims struct {
+	subject string
+	role    string
+	scopes  []string
+}
+
+func (c testClaims) GetSubject() string  { return c.subject }
+func (c testClaims) GetRole() string     { return c.role }
+func (c testClaims) GetIssuer() string   { return "" }
+func (c testClaims) GetTenant() string   { return "" }
+func (c testClaims) GetScopes() []string { return c.scopes }
+
+func TestProjectLocationResponses(t *testing.T) {
+	in := []LocationResponse{
+		{
+			Vehicle: model.Vehicle{
+				Id: "v1", Name: "Taxi", Plate: "34 TX 1", Type: "sedan", Class: "comfort", Seats: 4, Color: "yellow",
+				Driver: model.Driver{Id: "d1", Name: "Driver", Nickname: "dd", Email: "d1@example.com",
+					Picture: "d1.png", Rating: 4.5, PhoneMask: "+90********67"},
+			},
+			Lat: 1, Lng: 2, Dist: 3,
+		},
+		{
+			Vehicle: model.Vehicle{Id: "v2", Driver: model.Driver{Id: "d2", Email: "d2@example.com"}},
+			Lat:     4, Lng: 5, Dist: 6,
+		},
+	}
+
+	full := `[` +
+		`{"vehicle":{"vehicle_id":"v1","name":"Taxi","plate":"34 TX 1","type":"sedan","class":"comfort","seats":4,"color":"yellow",` +
+		`"driver":{"user_id":"d1","name":"Driver","nickname":"dd","email":"d1@example.com","picture":"d1.png","rating":4.5,"phone_mask":"+90********67"}},` +
+		`"lat":1,"lng":2,"dist":3},` +
+		`{"vehicle":{"vehicle_id":"v2","name":"","plate":"","type":"","class":"","seats":0,"color":"",` +
+		`"driver":{"user_id":"d2","name":"","nickname":"","email":"d2@example.com","picture":"","rating":0,"phone_mask":""}},` +
+		`"lat":4,"lng":5,"dist":6}]`
+
+	public := `[` +
+		`{"vehicle":{"vehicle_id":"v1","name":"Taxi","plate":"34 TX 1","type":"sedan","class":"comfort","seats":4,"color":"yellow",` +
+		`"driver":{"name":"Driver","nickname":"dd","picture":"d1.png","rating":4.5,"phone_mask":"+90********67"}},` +
+		`"lat":1,"lng":2,"dist":3},` +
+		`{"vehicle":{"vehicle_id":"v2","name":"","plate":"","type":"","class":"","seats":0,"color":"",` +
+		`"driver":{"name":"","nickname":"","picture":"","rating":0,"phone_mask":""}},` +
+		`"lat":4,"lng":5,"dist":6}]`
+
+	tests := []struct {
+		name   string
+		claims Claims
+		want   string
+	}{
+		{name: "admin sees everything", claims: testClaims{subject: "a1", role: RoleAdmin}, want: full},
+		{name: "dispatcher sees everything", claims: testClaims{subject: "x1", role: RoleDispatcher}, want: full},
+		{name: "service with the full scope sees everything",
+			claims: testClaims{subject: "dispatch", role: RoleService, scopes: []string{ScopeLocationSearch, ScopeLocationFull}},
+			want:   full},
+		{name: "service sees the public details",
+			claims: testClaims{subject: "pricing", role: RoleService, scopes: []string{ScopeLocationSearch}},
+			want:   public},
+		{name: "rider sees the public details", claims: testClaims{subject: "r1", role: RoleRider}, want: public},
+		{name: "unknown role sees the public details", claims: testClaims{subject: "u1"}, want: public},
+		{name: "driver sees nothing about others", claims: testClaims{subject: "d3", role: RoleDriver}, want: `[]`},
+		{
+			name:   "driver sees own vehicle",
+			claims: testClaims{subject: "d2", role: RoleDriver},
+			want: `[{"vehicle":{"vehicle_id":"v2","name":"","plate":"","type":"","class":"","seats":0,"color":"",` +
+				`"driver":{"user_id":"d2","name":"","nickname":"","email":"d2@example.com","picture":"","rating":0,"phone_mask":""}},` +
+				`"lat":4,"lng":5,"dist":6}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(ProjectLocationResponses(tt.claims, in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("ProjectLocationResponses() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
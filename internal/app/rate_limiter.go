@@ -0,0 +1,21 @@
+//go:generate mockgen -source rate_limiter.go -destination mock/rate_limiter_mock.go -package mock
+package app
+
+import (
+	"context"
+	"time"
+)
+
+// RateLimit is a token bucket which holds up to Burst requests and refills
+// PerMinute requests a minute
+type RateLimit struct {
+	PerMinute int
+	Burst     int
+}
+
+// RateLimiter counts the requests of the keys, e.g. the route and the caller
+type RateLimiter interface {
+	// Allow takes a request from the bucket of the key, if the bucket is empty it
+	// returns false and how long the caller should wait
+	Allow(ctx context.Context, key string, limit RateLimit) (bool, time.Duration, error)
+}
@@ -11,3 +11,8 @@ type SearchLocationRequest struct {
 	Lat float64 `json:"lat" validate:"required"`
 	Lng float64 `json:"lng" validate:"required"`
 } // @name SearchLocationRequest
+
+type CreateApiKeyRequest struct {
+	Service string   `json:"service" validate:"required"`
+	Scopes  []string `json:"scopes" validate:"dive,oneof=location:search location:full"`
+} // @name CreateApiKeyRequest
@@ -3,9 +3,10 @@ package app
 import "github.com/orkungursel/hey-taxi-location-api/internal/domain/model"
 
 type HTTPError struct {
-	Code     int         `json:"-"`
-	Message  interface{} `json:"message"`
-	Internal error       `json:"-"` // Stores the error returned by an external dependency
+	Code      int         `json:"-"`
+	Message   interface{} `json:"message"`
+	ErrorCode string      `json:"code,omitempty"` // machine readable reason, e.g. token_expired
+	Internal  error       `json:"-"`              // Stores the error returned by an external dependency
 } // @name HTTPError
 
 type LocationResponse struct {
@@ -14,3 +15,8 @@ type LocationResponse struct {
 	Lng     float64       `json:"lng"`
 	Dist    float64       `json:"dist"`
 } // @name LocationResponse
+
+type CreateApiKeyResponse struct {
+	Key string `json:"key"` // returned only once, only its hash is stored
+	Id  string `json:"id"`  // hash of the key, it is revoked by deleting the api-key:<id> redis key
+} // @name CreateApiKeyResponse
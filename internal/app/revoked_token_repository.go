@@ -0,0 +1,10 @@
+//go:generate mockgen -source revoked_token_repository.go -destination mock/revoked_token_repository_mock.go -package mock
+package app
+
+import "context"
+
+// RevokedTokenRepository tells whether an access token was revoked by its jti,
+// the revocations are written by the identity service
+type RevokedTokenRepository interface {
+	IsRevoked(ctx context.Context, tokenId string) (bool, error)
+}
@@ -0,0 +1,38 @@
+package app
+
+const (
+	RoleAdmin      = "admin"
+	RoleDispatcher = "dispatcher"
+	RoleDriver     = "driver"
+	RoleRider      = "rider"
+	RoleService    = "service" // internal callers authenticated by an api key, e.g. dispatch or pricing
+)
+
+const (
+	ScopeLocationSearch = "location:search" // may search the locations
+	ScopeLocationFull   = "location:full"   // sees the full vehicle details and the exact locations
+)
+
+// HasScope reports whether the claims grant the scope
+func HasScope(claims Claims, scope string) bool {
+	for _, s := range claims.GetScopes() {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SeesFullLocations reports whether the caller sees the search results as they
+// are: admins, dispatchers and the services with the location:full scope
+func SeesFullLocations(claims Claims) bool {
+	switch claims.GetRole() {
+	case RoleAdmin, RoleDispatcher:
+		return true
+	case RoleService:
+		return HasScope(claims, ScopeLocationFull)
+	}
+
+	return false
+}
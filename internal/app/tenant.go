@@ -0,0 +1,27 @@
+package app
+
+import (
+	"context"
+	"regexp"
+)
+
+type tenantContextKey struct{}
+
+var tenantPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// WithTenant returns a copy of the context which carries the tenant
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant of the context, or an empty string
+// which stands for the default tenant
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantContextKey{}).(string)
+	return tenant
+}
+
+// IsValidTenant checks whether the tenant can be used as a storage namespace
+func IsValidTenant(tenant string) bool {
+	return tenant == "" || tenantPattern.MatchString(tenant)
+}
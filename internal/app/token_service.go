@@ -10,9 +10,12 @@ type Claims interface {
 	GetSubject() string
 	GetRole() string
 	GetIssuer() string
+	GetTenant() string
+	GetScopes() []string
 }
 
 type TokenService interface {
+	ExtractAccessToken(r *http.Request) (string, error)
 	ParseToken(ctx context.Context, token string) (Claims, error)
 	ValidateAccessTokenFromRequest(ctx context.Context, r *http.Request) (Claims, error)
 }
@@ -0,0 +1,20 @@
+//go:generate mockgen -source vehicle_event.go -destination mock/vehicle_event_mock.go -package mock
+package app
+
+import "context"
+
+const (
+	VehicleEventUpdated = "vehicle.updated"
+	VehicleEventDeleted = "vehicle.deleted"
+)
+
+// VehicleEvent is published by the vehicle service when a vehicle or its driver changes
+type VehicleEvent struct {
+	Type      string `json:"type"`
+	VehicleId string `json:"vehicle_id"`
+	Tenant    string `json:"tenant,omitempty"`
+}
+
+type VehicleEventSource interface {
+	Subscribe(ctx context.Context) (<-chan VehicleEvent, error)
+}
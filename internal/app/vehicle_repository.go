@@ -9,6 +9,8 @@ import (
 
 type VehicleRepository interface {
 	Get(ctx context.Context, vehicleId string) (*model.Vehicle, error)
+	// GetMany returns the stored vehicles of the ids by their ids, the missing ones are left out
+	GetMany(ctx context.Context, vehicleIds []string) (map[string]*model.Vehicle, error)
 	Save(ctx context.Context, vehicle *model.Vehicle) error
 	Delete(ctx context.Context, vehicleId string) error
 }
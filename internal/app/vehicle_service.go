@@ -9,4 +9,7 @@ import (
 
 type VehicleService interface {
 	GetVehicleById(ctx context.Context, vehicleId string) (*model.Vehicle, error)
+	GetVehiclesByIds(ctx context.Context, vehicleIds []string) (map[string]*model.Vehicle, error)
+	RefreshVehicle(ctx context.Context, vehicleId string) (*model.Vehicle, error)
+	PurgeVehicle(ctx context.Context, vehicleId string) error
 }
@@ -1,9 +1,11 @@
 package model
 
 type Driver struct {
-	Id       string `json:"user_id"`
-	Name     string `json:"name"`
-	Nickname string `json:"nickname"`
-	Email    string `json:"email"`
-	Picture  string `json:"picture"`
+	Id        string  `json:"user_id"`
+	Name      string  `json:"name"`
+	Nickname  string  `json:"nickname"`
+	Email     string  `json:"email"`
+	Picture   string  `json:"picture"`
+	Rating    float64 `json:"rating"`
+	PhoneMask string  `json:"phone_mask"` // e.g. +90*******67, the full number is never stored
 } // @name Driver
@@ -9,6 +9,7 @@ type Vehicle struct {
 	Type   string `json:"type"`
 	Class  string `json:"class"`
 	Seats  int    `json:"seats"`
+	Color  string `json:"color"`
 	Driver Driver `json:"driver"`
 } // @name Vehicle
 
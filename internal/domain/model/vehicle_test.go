@@ -37,7 +37,7 @@ func TestVehicle_MarshalJSON(t *testing.T) {
 					Picture: "driver_picture",
 				},
 			},
-			want:    []byte(`{"vehicle_id":"vehicle_id","name":"name","plate":"plate","type":"type","class":"class","seats":1,"driver":{"user_id":"driver_id","name":"driver_name","nickname":"","email":"driver_email","picture":"driver_picture"}}`),
+			want:    []byte(`{"vehicle_id":"vehicle_id","name":"name","plate":"plate","type":"type","class":"class","seats":1,"color":"","driver":{"user_id":"driver_id","name":"driver_name","nickname":"","email":"driver_email","picture":"driver_picture","rating":0,"phone_mask":""}}`),
 			wantErr: false,
 		},
 	}
@@ -100,7 +100,7 @@ func TestVehicle_UnmarshalJSON(t *testing.T) {
 				},
 			},
 			args: args{
-				data: []byte(`{"vehicle_id":"vehicle_id","name":"name","plate":"plate","type":"type","class":"class","seats":1,"driver":{"user_id":"driver_id","name":"driver_name","nickname":"","email":"driver_email","picture":"driver_picture"}}`),
+				data: []byte(`{"vehicle_id":"vehicle_id","name":"name","plate":"plate","type":"type","class":"class","seats":1,"color":"","driver":{"user_id":"driver_id","name":"driver_name","nickname":"","email":"driver_email","picture":"driver_picture","rating":0,"phone_mask":""}}`),
 			},
 			wantErr: false,
 		},
@@ -0,0 +1,124 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/orkungursel/hey-taxi-location-api/internal/app"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger"
+)
+
+const (
+	apiKeyDbKey  = "api-key" // apiKeyDbKey is the prefix of the keys of the hashed api keys
+	apiKeyIssuer = "api-key" // apiKeyIssuer is the issuer of the service claims
+	apiKeyLength = 32
+)
+
+// ServiceClaims are the claims of a service authenticated by its api key
+type ServiceClaims struct {
+	Service string
+	Tenant  string
+	Scopes  []string
+}
+
+func (c *ServiceClaims) GetSubject() string {
+	return c.Service
+}
+
+func (c *ServiceClaims) GetRole() string {
+	return app.RoleService
+}
+
+func (c *ServiceClaims) GetIssuer() string {
+	return apiKeyIssuer
+}
+
+func (c *ServiceClaims) GetTenant() string {
+	return c.Tenant
+}
+
+func (c *ServiceClaims) GetScopes() []string {
+	return c.Scopes
+}
+
+// ApiKeyService validates the api keys against the "api-key:<sha256 of the key>"
+// redis hashes, which hold the service, tenant and space separated scopes fields.
+// Only the hashes are stored, a key is revoked by deleting its hash.
+type ApiKeyService struct {
+	db     redis.UniversalClient
+	logger logger.ILogger
+	dbKey  string
+}
+
+func NewApiKeyService(db redis.UniversalClient, logger logger.ILogger) *ApiKeyService {
+	return &ApiKeyService{
+		db:     db,
+		logger: logger,
+		dbKey:  apiKeyDbKey,
+	}
+}
+
+// ValidateApiKey returns the claims of the service which owns the key
+func (s *ApiKeyService) ValidateApiKey(ctx context.Context, key string) (app.Claims, error) {
+	if key == "" {
+		return nil, app.ErrApiKeyInvalid
+	}
+
+	fields, err := s.db.HGetAll(ctx, s.generateDbKey(key)).Result()
+	if err != nil {
+		return nil, app.NewInternalServerError(fmt.Errorf("failed to get api key: %w", err))
+	}
+
+	if fields["service"] == "" {
+		return nil, app.ErrApiKeyInvalid
+	}
+
+	return &ServiceClaims{
+		Service: fields["service"],
+		Tenant:  fields["tenant"],
+		Scopes:  strings.Fields(fields["scopes"]),
+	}, nil
+}
+
+// CreateApiKey generates a new api key of the service for the tenant of the
+// context and stores its hash, the key itself can not be read back
+func (s *ApiKeyService) CreateApiKey(ctx context.Context,
+	in app.CreateApiKeyRequest) (*app.CreateApiKeyResponse, error) {
+
+	if in.Service == "" {
+		return nil, errors.New("service is empty")
+	}
+
+	b := make([]byte, apiKeyLength)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	key := base64.RawURLEncoding.EncodeToString(b)
+
+	if err := s.db.HSet(ctx, s.generateDbKey(key),
+		"service", in.Service,
+		"tenant", app.TenantFromContext(ctx),
+		"scopes", strings.Join(in.Scopes, " "),
+	).Err(); err != nil {
+		return nil, app.NewInternalServerError(fmt.Errorf("failed to create api key: %w", err))
+	}
+
+	return &app.CreateApiKeyResponse{Key: key, Id: apiKeyId(key)}, nil
+}
+
+func (s *ApiKeyService) generateDbKey(key string) string {
+	return s.dbKey + ":" + apiKeyId(key)
+}
+
+// apiKeyId returns the sha256 of the key, which identifies the key in redis
+func apiKeyId(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
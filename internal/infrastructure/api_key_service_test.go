@@ -0,0 +1,89 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/orkungursel/hey-taxi-location-api/internal/app"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger/mock"
+)
+
+func TestApiKeyService(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	db := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer db.Close()
+
+	ctx := context.Background()
+	s := NewApiKeyService(db, mock.NewLoggerMock())
+
+	// the key is issued for the tenant of the admin
+	dispatch := ServiceClaims{Service: "dispatch", Tenant: "acme", Scopes: []string{app.ScopeLocationSearch, app.ScopeLocationFull}}
+	created, err := s.CreateApiKey(app.WithTenant(ctx, "acme"),
+		app.CreateApiKeyRequest{Service: dispatch.Service, Scopes: dispatch.Scopes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dispatchKey := created.Key
+
+	revoked, err := s.CreateApiKey(ctx, app.CreateApiKeyRequest{Service: "pricing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	revokedKey := revoked.Key
+	mr.Del(apiKeyDbKey + ":" + revoked.Id)
+
+	if _, err := s.CreateApiKey(ctx, app.CreateApiKeyRequest{}); err == nil {
+		t.Errorf("CreateApiKey() should fail without service")
+	}
+
+	// only the hash of the key is stored
+	if mr.Exists(apiKeyDbKey+":"+dispatchKey) || !mr.Exists(apiKeyDbKey+":"+created.Id) {
+		t.Errorf("CreateApiKey() should store the hash of the key")
+	}
+
+	tests := []struct {
+		name    string
+		key     string
+		want    app.Claims
+		wantErr error
+	}{
+		{name: "should return the claims of the service", key: dispatchKey, want: &dispatch},
+		{name: "should reject revoked key", key: revokedKey, wantErr: app.ErrApiKeyInvalid},
+		{name: "should reject unknown key", key: "unknown", wantErr: app.ErrApiKeyInvalid},
+		{name: "should reject empty key", key: "", wantErr: app.ErrApiKeyInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.ValidateApiKey(ctx, tt.key)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ValidateApiKey() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateApiKey() = %v, want %v", got, tt.want)
+			}
+			if got != nil && got.GetRole() != app.RoleService {
+				t.Errorf("ValidateApiKey() role = %v, want %v", got.GetRole(), app.RoleService)
+			}
+		})
+	}
+
+	t.Run("should fail when redis is not available", func(t *testing.T) {
+		mr.Close()
+
+		_, err := s.ValidateApiKey(ctx, dispatchKey)
+		var ae *app.Error
+		if !errors.As(err, &ae) || ae.Code() != http.StatusInternalServerError {
+			t.Errorf("ValidateApiKey() error = %v, want internal server error", err)
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger"
+)
+
+const (
+	assignmentDbKey = "assignment" // assignmentDbKey is the prefix of the keys of the assigned riders
+)
+
+// AssignmentRepository reads the rider assigned to a vehicle from the
+// "assignment:<vehicle id>" keys written by the dispatch service
+type AssignmentRepository struct {
+	db     redis.UniversalClient
+	logger logger.ILogger
+	dbKey  string
+}
+
+func NewAssignmentRepository(db redis.UniversalClient, logger logger.ILogger) *AssignmentRepository {
+	return &AssignmentRepository{
+		db:     db,
+		logger: logger,
+		dbKey:  assignmentDbKey,
+	}
+}
+
+// GetAssignedRider returns the id of the rider assigned to the vehicle, or an empty string
+func (r *AssignmentRepository) GetAssignedRider(ctx context.Context, vehicleId string) (string, error) {
+	if vehicleId == "" {
+		return "", errors.New("vehicleId is empty")
+	}
+
+	rider, err := r.db.Get(ctx, tenantKey(ctx, r.dbKey+":"+vehicleId)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+
+	return rider, err
+}
+
+// GetAssignedRiders returns the riders of the assigned vehicles by vehicle id, the
+// keys are read in a single pipeline
+func (r *AssignmentRepository) GetAssignedRiders(ctx context.Context, vehicleIds []string) (map[string]string, error) {
+	cmds := make(map[string]*redis.StringCmd, len(vehicleIds))
+	_, err := r.db.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, id := range vehicleIds {
+			if id == "" {
+				return errors.New("vehicleId is empty")
+			}
+			cmds[id] = pipe.Get(ctx, tenantKey(ctx, r.dbKey+":"+id))
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	res := make(map[string]string, len(cmds))
+	for id, cmd := range cmds {
+		rider, err := cmd.Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		res[id] = rider
+	}
+
+	return res, nil
+}
@@ -0,0 +1,63 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// MemoryAssignmentRepository is an in-process AssignmentRepository, the
+// assignments are set with Assign
+type MemoryAssignmentRepository struct {
+	mu    sync.RWMutex
+	items map[string]string
+}
+
+func NewMemoryAssignmentRepository() *MemoryAssignmentRepository {
+	return &MemoryAssignmentRepository{
+		items: make(map[string]string),
+	}
+}
+
+// GetAssignedRider returns the id of the rider assigned to the vehicle, or an empty string
+func (r *MemoryAssignmentRepository) GetAssignedRider(ctx context.Context, vehicleId string) (string, error) {
+	if vehicleId == "" {
+		return "", errors.New("vehicleId is empty")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.items[tenantKey(ctx, vehicleId)], nil
+}
+
+// GetAssignedRiders returns the riders of the assigned vehicles by vehicle id
+func (r *MemoryAssignmentRepository) GetAssignedRiders(ctx context.Context, vehicleIds []string) (map[string]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	res := make(map[string]string, len(vehicleIds))
+	for _, id := range vehicleIds {
+		if id == "" {
+			return nil, errors.New("vehicleId is empty")
+		}
+		if rider, ok := r.items[tenantKey(ctx, id)]; ok {
+			res[id] = rider
+		}
+	}
+
+	return res, nil
+}
+
+// Assign assigns the rider to the vehicle, an empty rider removes the assignment
+func (r *MemoryAssignmentRepository) Assign(ctx context.Context, vehicleId, riderId string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if riderId == "" {
+		delete(r.items, tenantKey(ctx, vehicleId))
+		return
+	}
+
+	r.items[tenantKey(ctx, vehicleId)] = riderId
+}
@@ -0,0 +1,83 @@
+package infrastructure
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/orkungursel/hey-taxi-location-api/internal/app"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger/mock"
+)
+
+func TestAssignmentRepositories(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	db := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer db.Close()
+
+	acme := app.WithTenant(context.Background(), "acme")
+	memory := NewMemoryAssignmentRepository()
+
+	// the dispatch service writes the assignments
+	_ = mr.Set(assignmentDbKey+":v1", "rider1")
+	_ = mr.Set("tenant:acme:"+assignmentDbKey+":v1", "rider2")
+	memory.Assign(context.Background(), "v1", "rider1")
+	memory.Assign(acme, "v1", "rider2")
+	memory.Assign(acme, "v2", "rider3")
+	memory.Assign(acme, "v2", "")
+
+	repos := map[string]app.AssignmentRepository{
+		"redis":  NewAssignmentRepository(db, mock.NewLoggerMock()),
+		"memory": memory,
+	}
+
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			tests := []struct {
+				name      string
+				ctx       context.Context
+				vehicleId string
+				want      string
+				wantErr   bool
+			}{
+				{name: "should return the assigned rider", ctx: context.Background(), vehicleId: "v1", want: "rider1"},
+				{name: "should return the assigned rider of the tenant", ctx: acme, vehicleId: "v1", want: "rider2"},
+				{name: "should return empty when not assigned", ctx: acme, vehicleId: "v2", want: ""},
+				{name: "should fail with empty vehicle id", ctx: acme, vehicleId: "", wantErr: true},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					got, err := repo.GetAssignedRider(tt.ctx, tt.vehicleId)
+					if (err != nil) != tt.wantErr {
+						t.Fatalf("GetAssignedRider() error = %v, wantErr %v", err, tt.wantErr)
+					}
+					if got != tt.want {
+						t.Errorf("GetAssignedRider() = %v, want %v", got, tt.want)
+					}
+				})
+			}
+
+			t.Run("should return the assigned riders", func(t *testing.T) {
+				got, err := repo.GetAssignedRiders(acme, []string{"v1", "v2", "v3"})
+				if err != nil {
+					t.Fatalf("GetAssignedRiders() error = %v", err)
+				}
+				if want := map[string]string{"v1": "rider2"}; !reflect.DeepEqual(got, want) {
+					t.Errorf("GetAssignedRiders() = %v, want %v", got, want)
+				}
+			})
+
+			t.Run("should fail with empty vehicle id", func(t *testing.T) {
+				if _, err := repo.GetAssignedRiders(acme, []string{"v1", ""}); err == nil {
+					t.Error("GetAssignedRiders() error = nil, want error")
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package infrastructure
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+)
+
+const (
+	circuitClosed = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// CircuitBreaker opens after threshold consecutive failures and fails fast
+// until the open timeout passes, then lets a single probe call through
+// which closes it again on success. Every state change starts a new
+// generation, the results of the calls allowed in an earlier one are ignored.
+type CircuitBreaker struct {
+	mu          sync.Mutex
+	threshold   int
+	openTimeout time.Duration
+	state       int
+	failures    int
+	openedAt    time.Time
+	probing     bool
+	generation  uint64
+	now         func() time.Time
+	onChange    func(from, to string)
+}
+
+// NewCircuitBreaker returns a breaker, it returns nil, a breaker which never opens,
+// when threshold is not positive
+func NewCircuitBreaker(threshold int, openTimeout time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+
+	return &CircuitBreaker{
+		threshold:   threshold,
+		openTimeout: openTimeout,
+		now:         time.Now,
+	}
+}
+
+// OnStateChange registers a callback which is called with the old and new state names
+func (b *CircuitBreaker) OnStateChange(fn func(from, to string)) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.onChange = fn
+}
+
+// Allow reports whether a call can be made, it returns ErrCircuitOpen otherwise.
+// Every allowed call must be followed by Done with the returned generation.
+func (b *CircuitBreaker) Allow() (uint64, error) {
+	if b == nil {
+		return 0, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if b.now().Sub(b.openedAt) < b.openTimeout {
+			return 0, ErrCircuitOpen
+		}
+		b.setState(circuitHalfOpen)
+		fallthrough
+	case circuitHalfOpen:
+		if b.probing {
+			return 0, ErrCircuitOpen
+		}
+		b.probing = true
+	}
+
+	return b.generation, nil
+}
+
+// Done records the result of a call allowed in the generation, a call which
+// was allowed before the breaker opened does not settle the probe
+func (b *CircuitBreaker) Done(generation uint64, success bool) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if generation != b.generation {
+		return
+	}
+
+	if b.state == circuitHalfOpen {
+		b.probing = false
+		if success {
+			b.failures = 0
+			b.setState(circuitClosed)
+		} else {
+			b.open()
+		}
+		return
+	}
+
+	if success {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == circuitClosed && b.failures >= b.threshold {
+		b.open()
+	}
+}
+
+// State returns the name of the current state
+func (b *CircuitBreaker) State() string {
+	if b == nil {
+		return circuitStateName(circuitClosed)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return circuitStateName(b.state)
+}
+
+func (b *CircuitBreaker) open() {
+	b.openedAt = b.now()
+	b.setState(circuitOpen)
+}
+
+func (b *CircuitBreaker) setState(state int) {
+	if b.state == state {
+		return
+	}
+
+	from := b.state
+	b.state = state
+	b.generation++
+
+	if b.onChange != nil {
+		b.onChange(circuitStateName(from), circuitStateName(state))
+	}
+}
+
+func circuitStateName(state int) string {
+	switch state {
+	case circuitOpen:
+		return "open"
+	case circuitHalfOpen:
+		return "half-open"
+	}
+
+	return "closed"
+}
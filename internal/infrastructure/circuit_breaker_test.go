@@ -0,0 +1,125 @@
+package infrastructure
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+
+	b := NewCircuitBreaker(2, time.Second)
+	b.now = func() time.Time { return now }
+
+	var changes []string
+	b.OnStateChange(func(from, to string) {
+		changes = append(changes, from+"->"+to)
+	})
+
+	call := func(success bool) error {
+		generation, err := b.Allow()
+		if err != nil {
+			return err
+		}
+		b.Done(generation, success)
+		return nil
+	}
+
+	// a success resets the consecutive failures
+	_ = call(false)
+	_ = call(true)
+	_ = call(false)
+	if got := b.State(); got != "closed" {
+		t.Fatalf("CircuitBreaker.State() = %v, want closed", got)
+	}
+
+	_ = call(false)
+	if err := call(true); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("CircuitBreaker.Allow() error = %v, want %v", err, ErrCircuitOpen)
+	}
+
+	// a failed probe opens it again
+	now = now.Add(time.Second)
+	_ = call(false)
+	if err := call(true); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("CircuitBreaker.Allow() error = %v, want %v", err, ErrCircuitOpen)
+	}
+
+	// only a single probe is let through while half-open
+	now = now.Add(time.Second)
+	probe, err := b.Allow()
+	if err != nil {
+		t.Fatalf("CircuitBreaker.Allow() error = %v", err)
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("CircuitBreaker.Allow() error = %v, want %v", err, ErrCircuitOpen)
+	}
+	b.Done(probe, true)
+
+	if got := b.State(); got != "closed" {
+		t.Fatalf("CircuitBreaker.State() = %v, want closed", got)
+	}
+
+	want := []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}
+	if len(changes) != len(want) {
+		t.Fatalf("state changes = %v, want %v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("state changes[%d] = %v, want %v", i, changes[i], want[i])
+		}
+	}
+}
+
+func TestCircuitBreaker_Disabled(t *testing.T) {
+	b := NewCircuitBreaker(0, time.Second)
+	for i := 0; i < 10; i++ {
+		generation, err := b.Allow()
+		if err != nil {
+			t.Fatalf("CircuitBreaker.Allow() error = %v", err)
+		}
+		b.Done(generation, false)
+	}
+}
+
+func TestCircuitBreaker_StaleCall(t *testing.T) {
+	now := time.Now()
+
+	b := NewCircuitBreaker(1, time.Second)
+	b.now = func() time.Time { return now }
+
+	// allowed while closed, it finishes after the breaker went half-open
+	old, err := b.Allow()
+	if err != nil {
+		t.Fatalf("CircuitBreaker.Allow() error = %v", err)
+	}
+
+	failed, _ := b.Allow()
+	b.Done(failed, false)
+
+	now = now.Add(time.Second)
+	probe, err := b.Allow()
+	if err != nil {
+		t.Fatalf("CircuitBreaker.Allow() error = %v", err)
+	}
+
+	b.Done(old, true)
+	if got := b.State(); got != "half-open" {
+		t.Fatalf("CircuitBreaker.State() = %v, want half-open", got)
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("CircuitBreaker.Allow() error = %v, want %v", err, ErrCircuitOpen)
+	}
+
+	b.Done(probe, false)
+	if got := b.State(); got != "open" {
+		t.Fatalf("CircuitBreaker.State() = %v, want open", got)
+	}
+
+	// an old success does not close the breaker reopened by the probe
+	b.Done(old, true)
+	if got := b.State(); got != "open" {
+		t.Errorf("CircuitBreaker.State() = %v, want open", got)
+	}
+}
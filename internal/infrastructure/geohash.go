@@ -0,0 +1,174 @@
+package infrastructure
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+const (
+	geohashAlphabet   = "0123456789bcdefghjkmnpqrstuvwxyz"
+	geoEarthRadius    = 6372797.560856 // geoEarthRadius is the earth radius in meters, same as redis uses
+	geoMinLatitude    = -85.05112878   // geoMinLatitude is the minimum latitude accepted by redis
+	geoMaxLatitude    = 85.05112878    // geoMaxLatitude is the maximum latitude accepted by redis
+	geoMinLongitude   = -180
+	geoMaxLongitude   = 180
+	geoDistPrecision  = 10000 // geoDistPrecision rounds distances to 4 decimals like redis does
+	geohashMaxBuckets = 4096  // geohashMaxBuckets caps the cells visited by a single search
+)
+
+var (
+	ErrInvalidCoordinates = errors.New("invalid longitude,latitude pair")
+	ErrInvalidUnit        = errors.New("unsupported unit provided. please use m, km, ft, mi")
+)
+
+// ValidateCoordinates checks whether the point can be stored in a geo index
+func ValidateCoordinates(lat, lng float64) error {
+	if lat < geoMinLatitude || lat > geoMaxLatitude ||
+		lng < geoMinLongitude || lng > geoMaxLongitude {
+		return ErrInvalidCoordinates
+	}
+
+	return nil
+}
+
+// UnitToMeters returns the multiplier to convert the given unit to meters
+func UnitToMeters(unit string) (float64, error) {
+	switch unit {
+	case "m":
+		return 1, nil
+	case "km":
+		return 1000, nil
+	case "mi":
+		return 1609.34, nil
+	case "ft":
+		return 0.3048, nil
+	}
+
+	return 0, ErrInvalidUnit
+}
+
+// Distance returns the haversine distance between two points in meters
+func Distance(lat1, lng1, lat2, lng2 float64) float64 {
+	lat1r := lat1 * math.Pi / 180
+	lat2r := lat2 * math.Pi / 180
+	u := math.Sin((lat2r - lat1r) / 2)
+	v := math.Sin((lng2 - lng1) * math.Pi / 180 / 2)
+
+	return 2 * geoEarthRadius * math.Asin(math.Sqrt(u*u+math.Cos(lat1r)*math.Cos(lat2r)*v*v))
+}
+
+// RoundDistance rounds the distance the same way redis does in its replies
+func RoundDistance(d float64) float64 {
+	return math.Round(d*geoDistPrecision) / geoDistPrecision
+}
+
+// EncodeGeohash encodes the point as a geohash with the given precision
+func EncodeGeohash(lat, lng float64, precision int) string {
+	var sb strings.Builder
+	sb.Grow(precision)
+
+	minLat, maxLat := -90.0, 90.0
+	minLng, maxLng := -180.0, 180.0
+	even := true
+	bit, ch := 0, 0
+
+	for sb.Len() < precision {
+		if even {
+			mid := (minLng + maxLng) / 2
+			if lng >= mid {
+				ch |= 1 << (4 - bit)
+				minLng = mid
+			} else {
+				maxLng = mid
+			}
+		} else {
+			mid := (minLat + maxLat) / 2
+			if lat >= mid {
+				ch |= 1 << (4 - bit)
+				minLat = mid
+			} else {
+				maxLat = mid
+			}
+		}
+
+		even = !even
+		if bit < 4 {
+			bit++
+			continue
+		}
+
+		sb.WriteByte(geohashAlphabet[ch])
+		bit, ch = 0, 0
+	}
+
+	return sb.String()
+}
+
+// geohashCellSize returns the height and width in degrees of a geohash cell
+func geohashCellSize(precision int) (float64, float64) {
+	bits := precision * 5
+	lngBits := (bits + 1) / 2
+	latBits := bits / 2
+
+	return 180 / math.Pow(2, float64(latBits)), 360 / math.Pow(2, float64(lngBits))
+}
+
+// GeohashesInRadius returns the geohash cells covering the circle around the point.
+// It returns nil when the circle needs more cells than geohashMaxBuckets,
+// which means the caller should scan every bucket instead.
+func GeohashesInRadius(lat, lng, meters float64, precision int) []string {
+	dLat := meters / geoEarthRadius * 180 / math.Pi
+	minLat := math.Max(lat-dLat, -90)
+	maxLat := math.Min(lat+dLat, 90)
+
+	// the circle covers every longitude when it contains a pole
+	if minLat <= -90 || maxLat >= 90 {
+		return nil
+	}
+
+	cosLat := math.Min(math.Cos(minLat*math.Pi/180), math.Cos(maxLat*math.Pi/180))
+	dLng := dLat / cosLat
+	if dLng >= 180 {
+		return nil
+	}
+
+	cellLat, cellLng := geohashCellSize(precision)
+	rows := int(math.Ceil((maxLat-minLat)/cellLat)) + 1
+	cols := int(math.Ceil(2*dLng/cellLng)) + 1
+	if rows*cols > geohashMaxBuckets {
+		return nil
+	}
+
+	seen := make(map[string]struct{}, rows*cols)
+	res := make([]string, 0, rows*cols)
+
+	for i := 0; i < rows; i++ {
+		y := math.Min(minLat+float64(i)*cellLat, maxLat)
+		for j := 0; j < cols; j++ {
+			x := normalizeLongitude(math.Min(lng-dLng+float64(j)*cellLng, lng+dLng))
+			h := EncodeGeohash(y, x, precision)
+			if _, ok := seen[h]; ok {
+				continue
+			}
+
+			seen[h] = struct{}{}
+			res = append(res, h)
+		}
+	}
+
+	return res
+}
+
+// normalizeLongitude wraps the longitude into [-180, 180)
+func normalizeLongitude(lng float64) float64 {
+	for lng < -180 {
+		lng += 360
+	}
+
+	for lng >= 180 {
+		lng -= 360
+	}
+
+	return lng
+}
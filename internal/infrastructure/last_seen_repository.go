@@ -0,0 +1,124 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger"
+)
+
+const (
+	lastSeenDbKey = "vehicle-last-seen" // lastSeenDbKey is the sorted set of the vehicles by their last ping
+)
+
+// LastSeenRepository keeps the last ping of every vehicle in a sorted set of
+// the tenant, scored by the unix time in milliseconds. A vehicle is written at
+// most once per interval, and the vehicles not seen for retention are trimmed
+// from the set along with a write, at most once per interval.
+type LastSeenRepository struct {
+	db        redis.UniversalClient
+	logger    logger.ILogger
+	dbKey     string
+	interval  time.Duration
+	retention time.Duration
+
+	mu      sync.Mutex
+	touched map[string]time.Time // last write of the vehicles
+	trimmed map[string]time.Time // last trim of the sets
+}
+
+// NewLastSeenRepository returns the repository, every touch is written if
+// interval is not positive, and nothing is trimmed if retention is not positive
+func NewLastSeenRepository(db redis.UniversalClient, logger logger.ILogger,
+	interval, retention time.Duration) *LastSeenRepository {
+
+	return &LastSeenRepository{
+		db:        db,
+		logger:    logger,
+		dbKey:     lastSeenDbKey,
+		interval:  interval,
+		retention: retention,
+		touched:   make(map[string]time.Time),
+		trimmed:   make(map[string]time.Time),
+	}
+}
+
+// Touch records at as the last ping of the vehicle, it is not written if the
+// vehicle was written less than interval before
+func (r *LastSeenRepository) Touch(ctx context.Context, vehicleId string, at time.Time) error {
+	if vehicleId == "" {
+		return errors.New("vehicleId is empty")
+	}
+
+	key := tenantKey(ctx, r.dbKey)
+	write, trim := r.due(key, tenantKey(ctx, vehicleId), at)
+	if !write {
+		return nil
+	}
+
+	_, err := r.db.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, key, &redis.Z{
+			Score:  float64(at.UnixMilli()),
+			Member: vehicleId,
+		})
+		if trim {
+			max := strconv.FormatInt(at.Add(-r.retention).UnixMilli(), 10)
+			pipe.ZRemRangeByScore(ctx, key, "-inf", "("+max)
+		}
+		return nil
+	})
+	if err != nil {
+		// written again by the next touch
+		r.mu.Lock()
+		delete(r.touched, tenantKey(ctx, vehicleId))
+		r.mu.Unlock()
+	}
+
+	return err
+}
+
+// due reports whether the vehicle should be written and the set trimmed, the
+// vehicles not written for interval are forgotten at most once per interval
+func (r *LastSeenRepository) due(key, vehicle string, at time.Time) (write, trim bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if last, ok := r.touched[vehicle]; ok && at.Sub(last) < r.interval {
+		return false, false
+	}
+	r.touched[vehicle] = at
+
+	if at.Sub(r.trimmed[key]) < r.interval {
+		return true, false
+	}
+	r.trimmed[key] = at
+
+	for k, last := range r.touched {
+		if at.Sub(last) >= r.interval {
+			delete(r.touched, k)
+		}
+	}
+
+	return true, r.retention > 0
+}
+
+// LastSeen returns the time of the last ping of the vehicle, zero if it is unknown
+func (r *LastSeenRepository) LastSeen(ctx context.Context, vehicleId string) (time.Time, error) {
+	if vehicleId == "" {
+		return time.Time{}, errors.New("vehicleId is empty")
+	}
+
+	ms, err := r.db.ZScore(ctx, tenantKey(ctx, r.dbKey), vehicleId).Result()
+	if errors.Is(err, redis.Nil) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.UnixMilli(int64(ms)), nil
+}
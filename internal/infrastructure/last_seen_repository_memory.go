@@ -0,0 +1,46 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// MemoryLastSeenRepository is an in-process LastSeenRepository
+type MemoryLastSeenRepository struct {
+	mu    sync.RWMutex
+	items map[string]time.Time
+}
+
+func NewMemoryLastSeenRepository() *MemoryLastSeenRepository {
+	return &MemoryLastSeenRepository{
+		items: make(map[string]time.Time),
+	}
+}
+
+// Touch records at as the last ping of the vehicle
+func (r *MemoryLastSeenRepository) Touch(ctx context.Context, vehicleId string, at time.Time) error {
+	if vehicleId == "" {
+		return errors.New("vehicleId is empty")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.items[tenantKey(ctx, vehicleId)] = at
+
+	return nil
+}
+
+// LastSeen returns the time of the last ping of the vehicle, zero if it is unknown
+func (r *MemoryLastSeenRepository) LastSeen(ctx context.Context, vehicleId string) (time.Time, error) {
+	if vehicleId == "" {
+		return time.Time{}, errors.New("vehicleId is empty")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.items[tenantKey(ctx, vehicleId)], nil
+}
@@ -0,0 +1,91 @@
+package infrastructure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/orkungursel/hey-taxi-location-api/internal/app"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger/mock"
+)
+
+func TestLastSeenRepository(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mr.Close)
+
+	repos := map[string]app.LastSeenRepository{
+		"redis":  NewLastSeenRepository(redis.NewClient(&redis.Options{Addr: mr.Addr()}), mock.NewLoggerMock(), time.Minute, time.Hour),
+		"memory": NewMemoryLastSeenRepository(),
+	}
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			acme := app.WithTenant(ctx, "acme")
+			at := time.UnixMilli(time.Now().UnixMilli())
+
+			if err := repo.Touch(ctx, "v1", at); err != nil {
+				t.Fatalf("LastSeenRepository.Touch() error = %v", err)
+			}
+			if err := repo.Touch(ctx, "", at); err == nil {
+				t.Errorf("LastSeenRepository.Touch() should fail with empty id")
+			}
+
+			if got, err := repo.LastSeen(ctx, "v1"); err != nil || !got.Equal(at) {
+				t.Errorf("LastSeenRepository.LastSeen() = %v, %v, want %v", got, err, at)
+			}
+			if got, err := repo.LastSeen(ctx, "unknown"); err != nil || !got.IsZero() {
+				t.Errorf("LastSeenRepository.LastSeen() = %v, %v, want zero", got, err)
+			}
+			if got, err := repo.LastSeen(acme, "v1"); err != nil || !got.IsZero() {
+				t.Errorf("LastSeenRepository.LastSeen() = %v, %v, want zero for another tenant", got, err)
+			}
+		})
+	}
+}
+
+func TestLastSeenRepository_Throttle(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mr.Close)
+
+	ctx := context.Background()
+	repo := NewLastSeenRepository(redis.NewClient(&redis.Options{Addr: mr.Addr()}), mock.NewLoggerMock(),
+		10*time.Second, time.Hour)
+
+	// a vehicle not seen for longer than the retention
+	now := time.UnixMilli(time.Now().UnixMilli())
+	if _, err := mr.ZAdd(lastSeenDbKey, float64(now.Add(-2*time.Hour).UnixMilli()), "gone"); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name    string
+		advance time.Duration
+		want    time.Duration // since now
+	}{
+		{name: "should write the first touch"},
+		{name: "should not write within the interval", advance: 5 * time.Second},
+		{name: "should write once the interval passed", advance: 5 * time.Second, want: 10 * time.Second},
+	}
+	at := now
+	for _, step := range steps {
+		at = at.Add(step.advance)
+		if err := repo.Touch(ctx, "v1", at); err != nil {
+			t.Fatalf("%s: LastSeenRepository.Touch() error = %v", step.name, err)
+		}
+		if got, err := repo.LastSeen(ctx, "v1"); err != nil || !got.Equal(now.Add(step.want)) {
+			t.Errorf("%s: LastSeenRepository.LastSeen() = %v, %v, want %v", step.name, got, err, now.Add(step.want))
+		}
+	}
+
+	if got, err := repo.LastSeen(ctx, "gone"); err != nil || !got.IsZero() {
+		t.Errorf("LastSeenRepository.LastSeen() = %v, %v, want the vehicle trimmed", got, err)
+	}
+}
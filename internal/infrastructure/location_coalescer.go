@@ -0,0 +1,128 @@
+package infrastructure
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-location-api/internal/domain/model"
+)
+
+const (
+	// locationCoalescerIdle is how long a vehicle which is not seen is remembered
+	locationCoalescerIdle = time.Minute
+)
+
+type coalescedLocation struct {
+	lat, lng float64
+	storedAt time.Time
+	seenAt   time.Time
+}
+
+// LocationCoalescer remembers the last stored location of every vehicle, so the
+// pings which moved less than distance metres within interval of the stored
+// location can be skipped. The stored location is never older than interval
+// while the vehicle is seen. Every instance coalesces its own pings, spreading a
+// vehicle over several instances costs writes but does not lose any.
+type LocationCoalescer struct {
+	mu        sync.Mutex
+	distance  float64
+	interval  time.Duration
+	locations map[string]*coalescedLocation
+	pruned    time.Time
+	now       func() time.Time
+}
+
+// NewLocationCoalescer returns the coalescer, or nil which stores every ping if
+// distance or interval is not positive
+func NewLocationCoalescer(distance float64, interval time.Duration) *LocationCoalescer {
+	if distance <= 0 || interval <= 0 {
+		return nil
+	}
+
+	return &LocationCoalescer{
+		distance:  distance,
+		interval:  interval,
+		locations: make(map[string]*coalescedLocation),
+		now:       time.Now,
+	}
+}
+
+// Skip reports whether the location is close enough to the stored one to be
+// skipped, the vehicle is marked as seen either way
+func (c *LocationCoalescer) Skip(ctx context.Context, l model.Location) bool {
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.prune(now)
+
+	stored, ok := c.locations[tenantKey(ctx, l.VehicleId)]
+	if !ok {
+		return false
+	}
+	stored.seenAt = now
+
+	return now.Sub(stored.storedAt) < c.interval &&
+		Distance(stored.lat, stored.lng, l.Lat, l.Lng) < c.distance
+}
+
+// Stored records the location as the stored location of the vehicle
+func (c *LocationCoalescer) Stored(ctx context.Context, l model.Location) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.locations[tenantKey(ctx, l.VehicleId)] = &coalescedLocation{
+		lat:      l.Lat,
+		lng:      l.Lng,
+		storedAt: now,
+		seenAt:   now,
+	}
+}
+
+// LastSeen returns when the vehicle sent its last ping, stored or skipped, it
+// returns false if the vehicle is not remembered
+func (c *LocationCoalescer) LastSeen(ctx context.Context, vehicleId string) (time.Time, bool) {
+	if c == nil {
+		return time.Time{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stored, ok := c.locations[tenantKey(ctx, vehicleId)]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return stored.seenAt, true
+}
+
+// prune forgets the vehicles which are not seen for a while, their next ping
+// is stored anyway
+func (c *LocationCoalescer) prune(now time.Time) {
+	idle := locationCoalescerIdle
+	if c.interval > idle {
+		idle = c.interval
+	}
+
+	if now.Sub(c.pruned) < idle {
+		return
+	}
+	c.pruned = now
+
+	for key, stored := range c.locations {
+		if now.Sub(stored.seenAt) >= idle {
+			delete(c.locations, key)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package infrastructure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/orkungursel/hey-taxi-location-api/internal/app"
+	"github.com/orkungursel/hey-taxi-location-api/internal/app/mock"
+	"github.com/orkungursel/hey-taxi-location-api/internal/domain/model"
+	logger "github.com/orkungursel/hey-taxi-location-api/pkg/logger/mock"
+)
+
+func TestNewLocationCoalescer(t *testing.T) {
+	if c := NewLocationCoalescer(0, time.Second); c != nil {
+		t.Errorf("NewLocationCoalescer() = %v, want nil without distance", c)
+	}
+	if c := NewLocationCoalescer(10, 0); c != nil {
+		t.Errorf("NewLocationCoalescer() = %v, want nil without interval", c)
+	}
+
+	var c *LocationCoalescer
+	if c.Skip(context.Background(), l1) {
+		t.Errorf("LocationCoalescer.Skip() should not skip when disabled")
+	}
+	c.Stored(context.Background(), l1)
+}
+
+func TestLocationCoalescer_Skip(t *testing.T) {
+	now := time.Now()
+	c := NewLocationCoalescer(10, 10*time.Second)
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+	acme := app.WithTenant(ctx, "acme")
+
+	// about 5 and 20 metres north of l1
+	near := model.Location{VehicleId: l1.VehicleId, Lat: l1.Lat + 5/metersPerDegree, Lng: l1.Lng}
+	far := model.Location{VehicleId: l1.VehicleId, Lat: l1.Lat + 20/metersPerDegree, Lng: l1.Lng}
+
+	c.Stored(ctx, l1)
+
+	steps := []struct {
+		name    string
+		ctx     context.Context
+		l       model.Location
+		advance time.Duration
+		want    bool
+	}{
+		{name: "should skip the same location", ctx: ctx, l: l1, want: true},
+		{name: "should skip a close location", ctx: ctx, l: near, advance: 5 * time.Second, want: true},
+		{name: "should not skip a far location", ctx: ctx, l: far},
+		{name: "should not skip once the stored location is old", ctx: ctx, l: l1, advance: 5 * time.Second},
+		{name: "should not skip unknown vehicles", ctx: ctx, l: l2},
+		{name: "should not skip the vehicle of another tenant", ctx: acme, l: l1},
+	}
+	for _, s := range steps {
+		now = now.Add(s.advance)
+		if got := c.Skip(s.ctx, s.l); got != s.want {
+			t.Errorf("%s: LocationCoalescer.Skip() = %v, want %v", s.name, got, s.want)
+		}
+	}
+
+	// the vehicles not seen for a while are forgotten
+	now = now.Add(locationCoalescerIdle)
+	c.Skip(ctx, l2)
+	if n := len(c.locations); n != 0 {
+		t.Errorf("LocationCoalescer remembers %d idle vehicles, want 0", n)
+	}
+}
+
+func TestLocationService_SaveLocation_Coalesce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mock.NewMockLocationRepository(ctrl)
+	repo.EXPECT().Save(gomock.Any(), gomock.Any()).Return(nil).Times(3)
+
+	vs := mock.NewMockVehicleService(ctrl)
+	vs.EXPECT().GetVehicleById(gomock.Any(), v1.Id).Return(&v1, nil).AnyTimes()
+
+	now := time.Now()
+	coalescer := NewLocationCoalescer(10, 10*time.Second)
+	coalescer.now = func() time.Time { return now }
+
+	// only the stored pings are written to the last-seen repository
+	lastSeen := mock.NewMockLastSeenRepository(ctrl)
+	lastSeen.EXPECT().Touch(gomock.Any(), v1.Id, gomock.Any()).Return(nil).Times(3)
+
+	s := NewLocationService(repo, logger.NewLoggerMock(), vs, nil, nil, coalescer, lastSeen, nil)
+
+	steps := []struct {
+		advance time.Duration
+		lat     float64
+	}{
+		{lat: 1.0}, // stored
+		{lat: 1.0}, // skipped
+		{advance: time.Second, lat: 1.0 + 1/metersPerDegree}, // skipped
+		{advance: 10 * time.Second, lat: 1.0},                // stored, the stored location is old
+		{lat: 1.0 + 50/metersPerDegree},                      // stored, moved
+	}
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		if err := s.SaveLocation(context.Background(), d1.Id,
+			app.SaveLocationRequest{VehicleId: v1.Id, Lat: step.lat, Lng: 1.0}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the coalescer keeps the last-seen of the skipped pings
+	now = now.Add(time.Second)
+	if err := s.SaveLocation(context.Background(), d1.Id,
+		app.SaveLocationRequest{VehicleId: v1.Id, Lat: 1.0 + 50/metersPerDegree, Lng: 1.0}); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := coalescer.LastSeen(context.Background(), v1.Id); !ok || !got.Equal(now) {
+		t.Errorf("LocationCoalescer.LastSeen() = %v, %v, want %v", got, ok, now)
+	}
+}
+
+type redisCommandCounter struct {
+	commands int
+}
+
+func (h *redisCommandCounter) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	h.commands++
+	return ctx, nil
+}
+
+func (h *redisCommandCounter) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	return nil
+}
+
+func (h *redisCommandCounter) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	h.commands += len(cmds)
+	return ctx, nil
+}
+
+func (h *redisCommandCounter) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
+
+func TestLocationService_SaveLocation_CoalesceRedisCommands(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	db := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer db.Close()
+	counter := &redisCommandCounter{}
+	db.AddHook(counter)
+
+	vs := mock.NewMockVehicleService(ctrl)
+	vs.EXPECT().GetVehicleById(gomock.Any(), v1.Id).Return(&v1, nil).AnyTimes()
+
+	s := NewLocationService(NewLocationRepository(db, logger.NewLoggerMock()), logger.NewLoggerMock(), vs, nil, nil,
+		NewLocationCoalescer(10, 10*time.Second), NewLastSeenRepository(db, logger.NewLoggerMock(), 10*time.Second, time.Hour), nil)
+
+	save := func() {
+		if err := s.SaveLocation(context.Background(), d1.Id,
+			app.SaveLocationRequest{VehicleId: v1.Id, Lat: 1.0, Lng: 1.0}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the stored ping writes the location and the last-seen, with the trim of the last-seen
+	save()
+	if counter.commands != 3 {
+		t.Fatalf("SaveLocation() sent %d redis commands, want 3", counter.commands)
+	}
+
+	for i := 0; i < 20; i++ {
+		save()
+	}
+	if counter.commands != 3 {
+		t.Errorf("20 coalesced SaveLocation() sent %d redis commands, want 0", counter.commands-3)
+	}
+}
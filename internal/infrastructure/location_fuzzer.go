@@ -0,0 +1,106 @@
+package infrastructure
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	PrivacyModeNone   = "none"
+	PrivacyModeGrid   = "grid"
+	PrivacyModeJitter = "jitter"
+
+	metersPerDegree = geoEarthRadius * math.Pi / 180 // metersPerDegree is the length of a degree of latitude
+)
+
+// LocationFuzzer hides the exact position of the vehicles. The grid mode snaps
+// the points to the center of a grid cell of radius metres, the jitter mode moves
+// them up to radius metres away. The jitter is derived from the vehicle and the
+// time window, so repeating the search does not average it out, by a HMAC of
+// the secret, so the callers can not compute it and subtract it.
+type LocationFuzzer struct {
+	mode   string
+	radius float64
+	window time.Duration
+	secret []byte
+	now    func() time.Time
+}
+
+// NewLocationFuzzer returns the fuzzer of the mode, it returns nil, which leaves
+// the points as they are, for the none mode. The jitter mode requires a secret.
+func NewLocationFuzzer(mode string, radius float64, window time.Duration, secret string) (*LocationFuzzer, error) {
+	switch mode {
+	case "", PrivacyModeNone:
+		return nil, nil
+	case PrivacyModeGrid, PrivacyModeJitter:
+	default:
+		return nil, fmt.Errorf("unknown privacy mode: %s", mode)
+	}
+
+	if radius <= 0 {
+		return nil, fmt.Errorf("invalid privacy radius: %v", radius)
+	}
+
+	if mode == PrivacyModeJitter && secret == "" {
+		return nil, fmt.Errorf("privacy secret is required by the %s mode", mode)
+	}
+
+	if window <= 0 {
+		window = time.Hour
+	}
+
+	return &LocationFuzzer{
+		mode:   mode,
+		radius: radius,
+		window: window,
+		secret: []byte(secret),
+		now:    time.Now,
+	}, nil
+}
+
+// Fuzz returns the fuzzed position of the vehicle
+func (f *LocationFuzzer) Fuzz(vehicleId string, lat, lng float64) (float64, float64) {
+	if f == nil {
+		return lat, lng
+	}
+
+	if f.mode == PrivacyModeGrid {
+		return f.snap(lat, lng)
+	}
+
+	return f.jitter(vehicleId, lat, lng)
+}
+
+func (f *LocationFuzzer) snap(lat, lng float64) (float64, float64) {
+	latStep := f.radius / metersPerDegree
+	lat = (math.Floor(lat/latStep) + 0.5) * latStep
+
+	// the cells get narrower towards the poles, so the longitude step follows the snapped latitude
+	lngStep := latStep / math.Max(math.Cos(lat*math.Pi/180), 0.01)
+	lng = (math.Floor(lng/lngStep) + 0.5) * lngStep
+
+	return clampLatitude(lat), normalizeLongitude(lng)
+}
+
+func (f *LocationFuzzer) jitter(vehicleId string, lat, lng float64) (float64, float64) {
+	h := hmac.New(sha256.New, f.secret)
+	_, _ = fmt.Fprintf(h, "%s:%d", vehicleId, f.now().UnixNano()/int64(f.window))
+	sum := h.Sum(nil)
+
+	// uniform in the disc: sqrt of the first 64 bits for the distance, the next ones for the bearing
+	d := f.radius * math.Sqrt(float64(binary.BigEndian.Uint64(sum[:8]))/float64(math.MaxUint64))
+	bearing := 2 * math.Pi * float64(binary.BigEndian.Uint64(sum[8:16])) / float64(math.MaxUint64)
+
+	lat += d * math.Cos(bearing) / metersPerDegree
+	lng += d * math.Sin(bearing) / (metersPerDegree * math.Max(math.Cos(lat*math.Pi/180), 0.01))
+
+	return clampLatitude(lat), normalizeLongitude(lng)
+}
+
+func clampLatitude(lat float64) float64 {
+	return math.Max(geoMinLatitude, math.Min(geoMaxLatitude, lat))
+}
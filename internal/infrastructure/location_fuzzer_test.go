@@ -0,0 +1,96 @@
+package infrastructure
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewLocationFuzzer(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		radius  float64
+		secret  string
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "should return nil for none mode", mode: PrivacyModeNone, wantNil: true},
+		{name: "should return nil for empty mode", mode: "", wantNil: true},
+		{name: "should return grid fuzzer", mode: PrivacyModeGrid, radius: 200},
+		{name: "should return jitter fuzzer", mode: PrivacyModeJitter, radius: 200, secret: "secret"},
+		{name: "should fail with jitter mode without secret", mode: PrivacyModeJitter, radius: 200, wantErr: true},
+		{name: "should fail with unknown mode", mode: "blur", radius: 200, wantErr: true},
+		{name: "should fail with invalid radius", mode: PrivacyModeGrid, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewLocationFuzzer(tt.mode, tt.radius, time.Minute, tt.secret)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewLocationFuzzer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (got == nil) != tt.wantNil {
+				t.Errorf("NewLocationFuzzer() = %v, wantNil %v", got, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestLocationFuzzer_Grid(t *testing.T) {
+	f, _ := NewLocationFuzzer(PrivacyModeGrid, 200, time.Minute, "")
+
+	points := [][2]float64{{41.0082, 28.9784}, {-33.8688, 151.2093}, {0, 0}, {60.1699, 24.9384}, {40.7128, -74.0060}}
+	for _, p := range points {
+		lat, lng := f.Fuzz("v1", p[0], p[1])
+
+		// the cell diagonal is the furthest a point can be from the cell center
+		if d := Distance(p[0], p[1], lat, lng); d > 200*1.5 {
+			t.Errorf("Fuzz(%v) moved the point %v metres", p, d)
+		}
+
+		// every point of the cell snaps to the same center
+		if lat2, lng2 := f.Fuzz("v2", lat, lng); lat2 != lat || lng2 != lng {
+			t.Errorf("Fuzz(%v, %v) = %v, %v, want the same cell center", lat, lng, lat2, lng2)
+		}
+	}
+}
+
+func TestLocationFuzzer_Jitter(t *testing.T) {
+	now := time.Now()
+
+	f, _ := NewLocationFuzzer(PrivacyModeJitter, 200, time.Minute, "secret")
+	f.now = func() time.Time { return now }
+
+	lat, lng := f.Fuzz("v1", 41.0082, 28.9784)
+	if d := Distance(41.0082, 28.9784, lat, lng); d > 200+1 {
+		t.Errorf("Fuzz() moved the point %v metres", d)
+	}
+
+	// repeating the search within the window returns the same point
+	if lat2, lng2 := f.Fuzz("v1", 41.0082, 28.9784); lat2 != lat || lng2 != lng {
+		t.Errorf("Fuzz() = %v, %v, want %v, %v", lat2, lng2, lat, lng)
+	}
+
+	if lat2, lng2 := f.Fuzz("v2", 41.0082, 28.9784); lat2 == lat && lng2 == lng {
+		t.Error("Fuzz() should jitter the vehicles differently")
+	}
+
+	now = now.Add(time.Minute)
+	if lat2, lng2 := f.Fuzz("v1", 41.0082, 28.9784); lat2 == lat && lng2 == lng {
+		t.Error("Fuzz() should change the jitter in the next window")
+	}
+}
+
+func TestLocationFuzzer_JitterSecret(t *testing.T) {
+	now := time.Now()
+
+	f, _ := NewLocationFuzzer(PrivacyModeJitter, 200, time.Minute, "secret")
+	f.now = func() time.Time { return now }
+	other, _ := NewLocationFuzzer(PrivacyModeJitter, 200, time.Minute, "other-secret")
+	other.now = f.now
+
+	// the jitter can not be computed from the vehicle id and the window alone
+	lat, lng := f.Fuzz("v1", 41.0082, 28.9784)
+	if lat2, lng2 := other.Fuzz("v1", 41.0082, 28.9784); lat2 == lat && lng2 == lng {
+		t.Error("Fuzz() should jitter differently with another secret")
+	}
+}
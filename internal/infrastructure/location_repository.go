@@ -2,6 +2,8 @@ package infrastructure
 
 import (
 	"context"
+	"sort"
+	"strconv"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/orkungursel/hey-taxi-location-api/internal/domain/model"
@@ -12,15 +14,38 @@ const (
 	dbKey        = "drivers" // dbKey is the key to store drivers in redis
 	maxLimit     = 100       // maxLimit is the maximum limit for the search
 	defaultLimit = 20        // defaultLimit is the default limit for the search
+	geoAddBatch  = 500       // geoAddBatch is the max number of members of a single GEOADD
 )
 
+// saveToShardScript adds the vehicle to its shard and removes it from the previous
+// shard held by the index. The previous shard is not declared as it is only known
+// inside the script, so the keys must not be spread over redis cluster slots.
+//
+// KEYS: index, shard, registry
+// ARGV: vehicle id, lng, lat
+var saveToShardScript = redis.NewScript(`
+redis.call("GEOADD", KEYS[2], ARGV[2], ARGV[3], ARGV[1])
+
+local old = redis.call("HGET", KEYS[1], ARGV[1])
+if old ~= KEYS[2] then
+	redis.call("SADD", KEYS[3], KEYS[2])
+	redis.call("HSET", KEYS[1], ARGV[1], KEYS[2])
+	if old then
+		redis.call("ZREM", old, ARGV[1])
+	end
+end
+
+return 1
+`)
+
 type LocationRepository struct {
-	db     *redis.Client
+	db     redis.UniversalClient
 	logger logger.ILogger
 	dbKey  string
+	shards *GeoShardRouter
 }
 
-func NewLocationRepository(db *redis.Client, logger logger.ILogger) *LocationRepository {
+func NewLocationRepository(db redis.UniversalClient, logger logger.ILogger) *LocationRepository {
 	return &LocationRepository{
 		db:     db,
 		logger: logger,
@@ -28,10 +53,118 @@ func NewLocationRepository(db *redis.Client, logger logger.ILogger) *LocationRep
 	}
 }
 
+// NewShardedLocationRepository creates a repository which spreads the locations
+// over several sorted sets by region, see GeoShardRouter for the shard keys
+func NewShardedLocationRepository(db redis.UniversalClient, logger logger.ILogger,
+	precision int, regions []string) (*LocationRepository, error) {
+
+	r := NewLocationRepository(db, logger)
+
+	shards, err := NewGeoShardRouter(r.dbKey, precision, regions)
+	if err != nil {
+		return nil, err
+	}
+	r.shards = shards
+
+	return r, nil
+}
+
 // Save saves the location of the driver to redis database
 func (r *LocationRepository) Save(ctx context.Context, in model.Location) error {
 	d := MapLocationToRedisGeoLocation(in)
-	return r.db.GeoAdd(ctx, r.dbKey, d).Err()
+	if r.shards == nil {
+		return r.db.GeoAdd(ctx, tenantKey(ctx, r.dbKey), d).Err()
+	}
+
+	return r.saveToShard(ctx, d)
+}
+
+// SaveBatch saves the locations of the tenant of the context, unsharded they
+// are written with pipelined GEOADDs of up to geoAddBatch members. A single
+// invalid location is skipped, so it does not fail the whole batch.
+func (r *LocationRepository) SaveBatch(ctx context.Context, in []model.Location) error {
+	if r.shards != nil {
+		for _, l := range in {
+			if err := r.saveToShard(ctx, MapLocationToRedisGeoLocation(l)); err != nil {
+				r.logger.Warnf("failed to save location of vehicle %s: %v", l.VehicleId, err)
+			}
+		}
+		return nil
+	}
+
+	d := make([]*redis.GeoLocation, 0, len(in))
+	for _, l := range in {
+		if err := ValidateCoordinates(l.Lat, l.Lng); err != nil {
+			r.logger.Warnf("skipped location of vehicle %s: %v", l.VehicleId, err)
+			continue
+		}
+		d = append(d, MapLocationToRedisGeoLocation(l))
+	}
+
+	if len(d) == 0 {
+		return nil
+	}
+
+	key := tenantKey(ctx, r.dbKey)
+	_, err := r.db.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i := 0; i < len(d); i += geoAddBatch {
+			end := i + geoAddBatch
+			if end > len(d) {
+				end = len(d)
+			}
+			pipe.GeoAdd(ctx, key, d[i:end]...)
+		}
+		return nil
+	})
+
+	return err
+}
+
+// saveToShard adds the location to its shard and removes it from the
+// previous shard when the vehicle has crossed a shard border. The move is
+// atomic, so concurrent saves of the vehicle leave it in a single shard.
+func (r *LocationRepository) saveToShard(ctx context.Context, d *redis.GeoLocation) error {
+	if err := ValidateCoordinates(d.Latitude, d.Longitude); err != nil {
+		return err
+	}
+
+	key := tenantKey(ctx, r.shards.KeyFor(d.Latitude, d.Longitude))
+	indexKey := tenantKey(ctx, r.shards.IndexKey())
+	registryKey := tenantKey(ctx, r.shards.RegistryKey())
+	lng := strconv.FormatFloat(d.Longitude, 'f', -1, 64)
+	lat := strconv.FormatFloat(d.Latitude, 'f', -1, 64)
+
+	return saveToShardScript.Run(ctx, r.db, []string{indexKey, key, registryKey}, d.Name, lng, lat).Err()
+}
+
+// Count returns the number of drivers of the tenant of the context, i.e. the
+// members of the drivers set or of all its shards
+func (r *LocationRepository) Count(ctx context.Context) (int64, error) {
+	if r.shards == nil {
+		return r.db.ZCard(ctx, tenantKey(ctx, r.dbKey)).Result()
+	}
+
+	keys, err := r.db.SMembers(ctx, tenantKey(ctx, r.shards.RegistryKey())).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	cmds := make([]*redis.IntCmd, len(keys))
+	if _, err := r.db.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.ZCard(ctx, key)
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	var n int64
+	for _, cmd := range cmds {
+		n += cmd.Val()
+	}
+
+	return n, nil
 }
 
 // Search searches for drivers in redis database
@@ -48,9 +181,14 @@ func (r *LocationRepository) Search(ctx context.Context, lat, lng, radius float6
 		WithCoord: true,
 		WithDist:  true,
 		Count:     limit,
+		Sort:      "ASC",
 	}
 
-	d, err := r.db.GeoRadius(ctx, r.dbKey, lat, lng, q).Result()
+	if r.shards != nil {
+		return r.searchShards(ctx, lat, lng, q)
+	}
+
+	d, err := r.db.GeoRadius(ctx, tenantKey(ctx, r.dbKey), lng, lat, q).Result()
 
 	if err != nil {
 		return nil, err
@@ -63,3 +201,66 @@ func (r *LocationRepository) Search(ctx context.Context, lat, lng, radius float6
 
 	return res, nil
 }
+
+// searchShards searches every shard the circle overlaps and merges the results by distance,
+// each vehicle is returned once
+func (r *LocationRepository) searchShards(ctx context.Context, lat, lng float64,
+	q *redis.GeoRadiusQuery) ([]model.Location, error) {
+
+	m, err := UnitToMeters(q.Unit)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := r.shards.KeysFor(lat, lng, q.Radius*m)
+	if keys == nil {
+		// registry holds the keys already namespaced by the tenant
+		if keys, err = r.db.SMembers(ctx, tenantKey(ctx, r.shards.RegistryKey())).Result(); err != nil {
+			return nil, err
+		}
+	} else {
+		for i := range keys {
+			keys[i] = tenantKey(ctx, keys[i])
+		}
+	}
+
+	cmds := make([]*redis.GeoLocationCmd, len(keys))
+	if _, err := r.db.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.GeoRadius(ctx, key, lng, lat, q)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	res := make([]model.Location, 0)
+	for _, cmd := range cmds {
+		for _, v := range cmd.Val() {
+			res = append(res, *MapRedisGeoLocationToDomain(v))
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Dist < res[j].Dist
+	})
+
+	// a vehicle is in a single shard, but a search racing with its move may
+	// see it in both, the nearest one is kept
+	seen := make(map[string]bool, len(res))
+	unique := res[:0]
+	for _, l := range res {
+		if seen[l.VehicleId] {
+			continue
+		}
+		seen[l.VehicleId] = true
+		unique = append(unique, l)
+	}
+	res = unique
+
+	if len(res) > q.Count {
+		res = res[:q.Count]
+	}
+
+	return res, nil
+}
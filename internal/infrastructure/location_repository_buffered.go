@@ -0,0 +1,224 @@
+package infrastructure
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-location-api/internal/app"
+	"github.com/orkungursel/hey-taxi-location-api/internal/domain/model"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger"
+)
+
+// locationBatchSaver is implemented by the repositories which can write several
+// locations at once, the others are written one by one
+type locationBatchSaver interface {
+	SaveBatch(ctx context.Context, in []model.Location) error
+}
+
+type bufferedLocation struct {
+	tenant   string
+	location model.Location
+}
+
+// BufferedLocationRepository collects the saves and writes them in batches every
+// window, only the last location of a vehicle is written. At most size vehicles
+// wait for the next batch, the saves of the other vehicles block until there is
+// room again or their context is done. The failed batches are not retried, the
+// next ping of the vehicle replaces its location anyway.
+type BufferedLocationRepository struct {
+	repo    app.LocationRepository
+	logger  logger.ILogger
+	window  time.Duration
+	size    int
+	mu      sync.Mutex
+	pending map[string]bufferedLocation
+	room    chan struct{} // closed when a batch is written
+	full    chan struct{} // asks for an early flush
+	stop    chan struct{}
+	done    chan struct{}
+	closed  bool
+	flushMu sync.Mutex
+	written uint64
+	dropped uint64
+}
+
+func NewBufferedLocationRepository(repo app.LocationRepository, logger logger.ILogger,
+	window time.Duration, size int) *BufferedLocationRepository {
+
+	return &BufferedLocationRepository{
+		repo:    repo,
+		logger:  logger,
+		window:  window,
+		size:    size,
+		pending: make(map[string]bufferedLocation),
+		room:    make(chan struct{}),
+		full:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start writes the batches in the background until Close is called
+func (r *BufferedLocationRepository) Start() {
+	go func() {
+		defer close(r.done)
+
+		ticker := time.NewTicker(r.window)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+			case <-r.full:
+			}
+
+			r.Flush(context.Background())
+		}
+	}()
+}
+
+// Close stops the background writer and writes the pending locations, the
+// saves after Close are written straight to the repository
+func (r *BufferedLocationRepository) Close(ctx context.Context) error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	r.mu.Unlock()
+
+	close(r.stop)
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return r.Flush(ctx)
+}
+
+// Save queues the location of the vehicle, replacing the queued one if any
+func (r *BufferedLocationRepository) Save(ctx context.Context, in model.Location) error {
+	key := tenantKey(ctx, in.VehicleId)
+
+	for {
+		r.mu.Lock()
+		if r.closed {
+			r.mu.Unlock()
+			return r.repo.Save(ctx, in)
+		}
+
+		if _, ok := r.pending[key]; ok || len(r.pending) < r.size {
+			r.pending[key] = bufferedLocation{tenant: app.TenantFromContext(ctx), location: in}
+			full := len(r.pending) >= r.size
+			r.mu.Unlock()
+
+			if full {
+				r.requestFlush()
+			}
+			return nil
+		}
+
+		room := r.room
+		r.mu.Unlock()
+
+		r.requestFlush()
+
+		select {
+		case <-room:
+		case <-ctx.Done():
+			return app.ErrSaveQueueFull
+		}
+	}
+}
+
+// Search reads from the repository, the queued locations are not visible yet
+func (r *BufferedLocationRepository) Search(ctx context.Context, lat, lng, radius float64,
+	unit string, limit int) ([]model.Location, error) {
+
+	return r.repo.Search(ctx, lat, lng, radius, unit, limit)
+}
+
+// Flush writes the queued locations, grouped by their tenants
+func (r *BufferedLocationRepository) Flush(ctx context.Context) error {
+	r.flushMu.Lock()
+	defer r.flushMu.Unlock()
+
+	r.mu.Lock()
+	pending := r.pending
+	room := r.room
+	r.pending = make(map[string]bufferedLocation)
+	r.room = make(chan struct{})
+	r.mu.Unlock()
+
+	// the blocked saves are let in once the batch is written
+	defer close(room)
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	tenants := make(map[string][]model.Location)
+	for _, b := range pending {
+		tenants[b.tenant] = append(tenants[b.tenant], b.location)
+	}
+
+	var lastErr error
+	for tenant, locations := range tenants {
+		if err := r.saveBatch(app.WithTenant(ctx, tenant), locations); err != nil {
+			r.logger.Errorf("failed to write %d locations: %v", len(locations), err)
+			atomic.AddUint64(&r.dropped, uint64(len(locations)))
+			lastErr = err
+			continue
+		}
+		atomic.AddUint64(&r.written, uint64(len(locations)))
+	}
+
+	return lastErr
+}
+
+func (r *BufferedLocationRepository) saveBatch(ctx context.Context, locations []model.Location) error {
+	if b, ok := r.repo.(locationBatchSaver); ok {
+		return b.SaveBatch(ctx, locations)
+	}
+
+	var lastErr error
+	for _, l := range locations {
+		if err := r.repo.Save(ctx, l); err != nil {
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
+
+// requestFlush wakes the background writer up, unless it is already asked to
+func (r *BufferedLocationRepository) requestFlush() {
+	select {
+	case r.full <- struct{}{}:
+	default:
+	}
+}
+
+// Depth returns the number of vehicles waiting for the next batch
+func (r *BufferedLocationRepository) Depth() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.pending)
+}
+
+// Written returns the number of locations written in batches so far
+func (r *BufferedLocationRepository) Written() uint64 {
+	return atomic.LoadUint64(&r.written)
+}
+
+// Dropped returns the number of locations lost with the failed batches
+func (r *BufferedLocationRepository) Dropped() uint64 {
+	return atomic.LoadUint64(&r.dropped)
+}
@@ -0,0 +1,144 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-location-api/internal/app"
+	"github.com/orkungursel/hey-taxi-location-api/internal/domain/model"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger/mock"
+)
+
+func TestBufferedLocationRepository_Flush(t *testing.T) {
+	t.Parallel()
+
+	repo, db := SetupLocationRepositoryMocks()
+	r := NewBufferedLocationRepository(repo, mock.NewLoggerMock(), time.Hour, 10)
+
+	ctx := context.Background()
+	acme := app.WithTenant(ctx, "acme")
+
+	saves := []struct {
+		ctx context.Context
+		in  model.Location
+	}{
+		{ctx, model.Location{VehicleId: "v1", Lat: 10, Lng: 10}},
+		{ctx, model.Location{VehicleId: "v1", Lat: 20, Lng: 20}},
+		{acme, model.Location{VehicleId: "v1", Lat: 30, Lng: 30}},
+		{ctx, model.Location{VehicleId: "v2", Lat: 40, Lng: 40}},
+	}
+	for _, s := range saves {
+		if err := r.Save(s.ctx, s.in); err != nil {
+			t.Fatalf("BufferedLocationRepository.Save() error = %v", err)
+		}
+	}
+
+	if got := r.Depth(); got != 3 {
+		t.Errorf("BufferedLocationRepository.Depth() = %v, want 3", got)
+	}
+	if n := db.Exists(ctx, dbKey).Val(); n != 0 {
+		t.Fatalf("locations are written before the flush")
+	}
+
+	if err := r.Flush(ctx); err != nil {
+		t.Fatalf("BufferedLocationRepository.Flush() error = %v", err)
+	}
+
+	if got := r.Depth(); got != 0 {
+		t.Errorf("BufferedLocationRepository.Depth() = %v after the flush, want 0", got)
+	}
+	if got := r.Written(); got != 3 {
+		t.Errorf("BufferedLocationRepository.Written() = %v, want 3", got)
+	}
+
+	pos := db.GeoPos(ctx, dbKey, "v1").Val()
+	if len(pos) != 1 || pos[0] == nil || pos[0].Latitude < 19.9 || pos[0].Latitude > 20.1 {
+		t.Errorf("last location of v1 is not written: %v", pos)
+	}
+	if n := db.ZCard(ctx, tenantKey(acme, dbKey)).Val(); n != 1 {
+		t.Errorf("tenant has %d locations, want 1", n)
+	}
+}
+
+func TestBufferedLocationRepository_Backpressure(t *testing.T) {
+	t.Parallel()
+
+	repo := NewMemoryLocationRepository(mock.NewLoggerMock())
+	r := NewBufferedLocationRepository(repo, mock.NewLoggerMock(), time.Hour, 1)
+
+	ctx := context.Background()
+	if err := r.Save(ctx, model.Location{VehicleId: "v1", Lat: 10, Lng: 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the queued vehicle is replaced even though the queue is full
+	if err := r.Save(ctx, model.Location{VehicleId: "v1", Lat: 11, Lng: 11}); err != nil {
+		t.Fatal(err)
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := r.Save(timeout, model.Location{VehicleId: "v2", Lat: 10, Lng: 10}); !errors.Is(err, app.ErrSaveQueueFull) {
+		t.Fatalf("BufferedLocationRepository.Save() error = %v, want %v", err, app.ErrSaveQueueFull)
+	}
+
+	saved := make(chan error, 1)
+	go func() {
+		saved <- r.Save(ctx, model.Location{VehicleId: "v2", Lat: 10, Lng: 10})
+	}()
+
+	select {
+	case <-saved:
+		t.Fatal("BufferedLocationRepository.Save() did not block while the queue is full")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	if err := r.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-saved:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("BufferedLocationRepository.Save() is still blocked after the flush")
+	}
+
+	if got := r.Depth(); got != 1 {
+		t.Errorf("BufferedLocationRepository.Depth() = %v, want 1", got)
+	}
+}
+
+func TestBufferedLocationRepository_Close(t *testing.T) {
+	t.Parallel()
+
+	repo := NewMemoryLocationRepository(mock.NewLoggerMock())
+	r := NewBufferedLocationRepository(repo, mock.NewLoggerMock(), time.Hour, 10)
+	r.Start()
+
+	ctx := context.Background()
+	if err := r.Save(ctx, model.Location{VehicleId: "v1", Lat: 10, Lng: 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Close(ctx); err != nil {
+		t.Fatalf("BufferedLocationRepository.Close() error = %v", err)
+	}
+
+	// saved after the close, written through
+	if err := r.Save(ctx, model.Location{VehicleId: "v2", Lat: 10, Lng: 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := r.Search(ctx, 10, 10, 1, "km", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Errorf("BufferedLocationRepository.Search() = %v, want both vehicles", got)
+	}
+}
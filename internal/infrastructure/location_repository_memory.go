@@ -0,0 +1,166 @@
+package infrastructure
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/orkungursel/hey-taxi-location-api/internal/app"
+	"github.com/orkungursel/hey-taxi-location-api/internal/domain/model"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger"
+)
+
+const (
+	memoryGeohashPrecision = 4 // memoryGeohashPrecision is the geohash length of a bucket, ~39km x 20km
+)
+
+// MemoryLocationRepository is an in-process LocationRepository that indexes
+// the locations in geohash buckets. It mirrors the redis implementation and is
+// meant for local development and tests.
+type MemoryLocationRepository struct {
+	mu        sync.RWMutex
+	logger    logger.ILogger
+	precision int
+	indexes   map[string]*memoryLocationIndex // indexes by tenant
+}
+
+type memoryLocationIndex struct {
+	locations map[string]model.Location      // locations by vehicle id
+	buckets   map[string]map[string]struct{} // vehicle ids by geohash
+}
+
+func NewMemoryLocationRepository(logger logger.ILogger) *MemoryLocationRepository {
+	return &MemoryLocationRepository{
+		logger:    logger,
+		precision: memoryGeohashPrecision,
+		indexes:   make(map[string]*memoryLocationIndex),
+	}
+}
+
+// Count returns the number of drivers of the tenant of the context
+func (r *MemoryLocationRepository) Count(ctx context.Context) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	idx, ok := r.indexes[app.TenantFromContext(ctx)]
+	if !ok {
+		return 0, nil
+	}
+
+	return int64(len(idx.locations)), nil
+}
+
+// Save saves the location of the driver to the memory index
+func (r *MemoryLocationRepository) Save(ctx context.Context, in model.Location) error {
+	if err := ValidateCoordinates(in.Lat, in.Lng); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tenant := app.TenantFromContext(ctx)
+	idx, ok := r.indexes[tenant]
+	if !ok {
+		idx = &memoryLocationIndex{
+			locations: make(map[string]model.Location),
+			buckets:   make(map[string]map[string]struct{}),
+		}
+		r.indexes[tenant] = idx
+	}
+
+	if old, ok := idx.locations[in.VehicleId]; ok {
+		idx.removeFromBucket(EncodeGeohash(old.Lat, old.Lng, r.precision), in.VehicleId)
+	}
+
+	hash := EncodeGeohash(in.Lat, in.Lng, r.precision)
+	bucket, ok := idx.buckets[hash]
+	if !ok {
+		bucket = make(map[string]struct{})
+		idx.buckets[hash] = bucket
+	}
+
+	bucket[in.VehicleId] = struct{}{}
+	idx.locations[in.VehicleId] = model.Location{
+		VehicleId: in.VehicleId,
+		Lat:       in.Lat,
+		Lng:       in.Lng,
+	}
+
+	return nil
+}
+
+// Search searches for drivers in the memory index
+func (r *MemoryLocationRepository) Search(ctx context.Context, lat, lng, radius float64,
+	unit string, limit int) ([]model.Location, error) {
+
+	if limit == 0 || limit > maxLimit {
+		limit = defaultLimit
+	}
+
+	if err := ValidateCoordinates(lat, lng); err != nil {
+		return nil, err
+	}
+
+	m, err := UnitToMeters(unit)
+	if err != nil {
+		return nil, err
+	}
+
+	meters := radius * m
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	res := make([]model.Location, 0)
+
+	idx, ok := r.indexes[app.TenantFromContext(ctx)]
+	if !ok {
+		return res, nil
+	}
+
+	add := func(l model.Location) {
+		if d := Distance(lat, lng, l.Lat, l.Lng); d <= meters {
+			l.Dist = RoundDistance(d / m)
+			res = append(res, l)
+		}
+	}
+
+	if hashes := GeohashesInRadius(lat, lng, meters, r.precision); hashes != nil {
+		for _, h := range hashes {
+			for id := range idx.buckets[h] {
+				add(idx.locations[id])
+			}
+		}
+	} else {
+		for _, l := range idx.locations {
+			add(l)
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].Dist == res[j].Dist {
+			return res[i].VehicleId < res[j].VehicleId
+		}
+		return res[i].Dist < res[j].Dist
+	})
+
+	if len(res) > limit {
+		res = res[:limit]
+	}
+
+	return res, nil
+}
+
+// removeFromBucket removes the vehicle from the bucket and drops the bucket when it gets empty
+func (idx *memoryLocationIndex) removeFromBucket(hash, vehicleId string) {
+	bucket, ok := idx.buckets[hash]
+	if !ok {
+		return
+	}
+
+	delete(bucket, vehicleId)
+	if len(bucket) == 0 {
+		delete(idx.buckets, hash)
+	}
+}
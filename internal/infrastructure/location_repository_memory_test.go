@@ -0,0 +1,214 @@
+package infrastructure
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/orkungursel/hey-taxi-location-api/internal/app"
+	"github.com/orkungursel/hey-taxi-location-api/internal/domain/model"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger/mock"
+)
+
+func TestMemoryLocationRepository_Save(t *testing.T) {
+	t.Parallel()
+
+	repo := NewMemoryLocationRepository(mock.NewLoggerMock())
+
+	tests := []struct {
+		name    string
+		in      model.Location
+		wantErr bool
+	}{
+		{
+			name: "should success",
+			in:   model.Location{VehicleId: "driver", Lat: 1.0, Lng: 1.0},
+		},
+		{
+			name: "should success with lat 0 and lng 0",
+			in:   model.Location{VehicleId: "driver-2", Lat: 0, Lng: 0},
+		},
+		{
+			name: "should move the vehicle to its new bucket",
+			in:   model.Location{VehicleId: "driver", Lat: 40.0, Lng: 29.0},
+		},
+		{
+			name:    "fail if coordinates are out of range",
+			in:      model.Location{Lat: 100.0, Lng: 100.0},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := repo.Save(context.Background(), tt.in); (err != nil) != tt.wantErr {
+				t.Errorf("MemoryLocationRepository.Save() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	idx := repo.indexes[""]
+	if len(idx.locations) != 2 {
+		t.Errorf("MemoryLocationRepository.Save() stored %d locations, want 2", len(idx.locations))
+	}
+
+	if _, ok := idx.buckets[EncodeGeohash(1.0, 1.0, repo.precision)]; ok {
+		t.Errorf("MemoryLocationRepository.Save() old bucket is not removed")
+	}
+}
+
+func TestMemoryLocationRepository_Search(t *testing.T) {
+	t.Parallel()
+
+	repo := NewMemoryLocationRepository(mock.NewLoggerMock())
+
+	d1 := model.Location{VehicleId: "driver1", Lat: 1.0, Lng: 1.0}
+	d2 := model.Location{VehicleId: "driver2", Lat: 20.0, Lng: 20.0}
+	d3 := model.Location{VehicleId: "driver3", Lat: 1.0, Lng: 179.99}
+	d4 := model.Location{VehicleId: "driver4", Lat: 1.0, Lng: -179.99}
+
+	for _, l := range []model.Location{d1, d2, d3, d4} {
+		_ = repo.Save(context.Background(), l)
+	}
+
+	type args struct {
+		lat    float64
+		lng    float64
+		radius float64
+		unit   string
+		limit  int
+	}
+
+	tests := []struct {
+		name    string
+		args    args
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "should return 1 result with radius 10 km and lat 1 and lng 1",
+			args: args{lat: 1.0, lng: 1.0, radius: 10.0, unit: "km"},
+			want: []string{d1.VehicleId},
+		},
+		{
+			name: "should return 2 results ordered by distance with radius 3000 km",
+			args: args{lat: 1.0, lng: 1.0, radius: 3000.0, unit: "km"},
+			want: []string{d1.VehicleId, d2.VehicleId},
+		},
+		{
+			name: "should respect the limit",
+			args: args{lat: 1.0, lng: 1.0, radius: 3000.0, unit: "km", limit: 1},
+			want: []string{d1.VehicleId},
+		},
+		{
+			name: "should support other units",
+			args: args{lat: 1.0, lng: 1.0, radius: 5.0, unit: "mi"},
+			want: []string{d1.VehicleId},
+		},
+		{
+			name: "should search across the antimeridian",
+			args: args{lat: 1.0, lng: 180.0, radius: 10.0, unit: "km"},
+			want: []string{d3.VehicleId, d4.VehicleId},
+		},
+		{
+			name: "should return empty list when nothing is in range",
+			args: args{lat: 30.0, lng: 30.0, radius: 10.0, unit: "m"},
+			want: []string{},
+		},
+		{
+			name:    "should return error on unknown unit",
+			args:    args{lat: 1.0, lng: 1.0, radius: 10.0, unit: "yd"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.Search(context.Background(), tt.args.lat, tt.args.lng, tt.args.radius, tt.args.unit, tt.args.limit)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("MemoryLocationRepository.Search() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("MemoryLocationRepository.Search() = %v, want %v", got, tt.want)
+				return
+			}
+			for i, id := range tt.want {
+				if got[i].VehicleId != id {
+					t.Errorf("MemoryLocationRepository.Search()[%d] = %v, want %v", i, got[i].VehicleId, id)
+				}
+			}
+		})
+	}
+}
+
+// TestMemoryLocationRepository_SameAsRedis runs the same queries against both
+// implementations and expects the same vehicles, order and distances.
+func TestMemoryLocationRepository_SameAsRedis(t *testing.T) {
+	t.Parallel()
+
+	redisRepo, _ := SetupLocationRepositoryMocks()
+	memoryRepo := NewMemoryLocationRepository(mock.NewLoggerMock())
+
+	locations := []model.Location{
+		{VehicleId: "v1", Lat: 41.0082, Lng: 28.9784},
+		{VehicleId: "v2", Lat: 41.0151, Lng: 28.9795},
+		{VehicleId: "v3", Lat: 40.9923, Lng: 29.0244},
+		{VehicleId: "v4", Lat: 39.9334, Lng: 32.8597},
+		{VehicleId: "v5", Lat: 38.4237, Lng: 27.1428},
+	}
+
+	for _, repo := range []app.LocationRepository{redisRepo, memoryRepo} {
+		for _, l := range locations {
+			if err := repo.Save(context.Background(), l); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	queries := []struct {
+		radius float64
+		unit   string
+		limit  int
+	}{
+		{radius: 1, unit: "km"},
+		{radius: 5000, unit: "m"},
+		{radius: 500, unit: "km"},
+		{radius: 500, unit: "km", limit: 2},
+		{radius: 1000, unit: "mi"},
+		{radius: 20000, unit: "ft"},
+	}
+
+	for _, q := range queries {
+		want, err := redisRepo.Search(context.Background(), 41.0082, 28.9784, q.radius, q.unit, q.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := memoryRepo.Search(context.Background(), 41.0082, 28.9784, q.radius, q.unit, q.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		m, _ := UnitToMeters(q.unit)
+
+		if len(got) != len(want) {
+			t.Errorf("query %+v: got %v, want %v", q, got, want)
+			continue
+		}
+
+		for i := range want {
+			if got[i].VehicleId != want[i].VehicleId {
+				t.Errorf("query %+v: got[%d] = %v, want %v", q, i, got[i].VehicleId, want[i].VehicleId)
+			}
+
+			// redis stores 52 bit geohashes, so the distances may differ up to a meter
+			if math.Abs(got[i].Dist-want[i].Dist) > 1/m {
+				t.Errorf("query %+v: got[%d].Dist = %v, want %v", q, i, got[i].Dist, want[i].Dist)
+			}
+		}
+	}
+}
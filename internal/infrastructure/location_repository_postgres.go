@@ -0,0 +1,109 @@
+package infrastructure
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/orkungursel/hey-taxi-location-api/internal/app"
+	"github.com/orkungursel/hey-taxi-location-api/internal/domain/model"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger"
+)
+
+const (
+	// postgresSaveLocationQuery upserts the current location and appends it to the history
+	postgresSaveLocationQuery = `
+WITH point AS (
+	SELECT ST_SetSRID(ST_MakePoint($3, $2), 4326)::geography AS location
+), history AS (
+	INSERT INTO vehicle_location_history (tenant, vehicle_id, location)
+	SELECT $4, $1, location FROM point
+)
+INSERT INTO vehicle_locations (tenant, vehicle_id, location, updated_at)
+SELECT $4, $1, location, now() FROM point
+ON CONFLICT (tenant, vehicle_id) DO UPDATE
+SET location = EXCLUDED.location, updated_at = EXCLUDED.updated_at`
+
+	// postgresSearchLocationQuery uses spherical distances, like redis does
+	postgresSearchLocationQuery = `
+WITH ref AS (
+	SELECT ST_SetSRID(ST_MakePoint($2, $1), 4326)::geography AS location
+)
+SELECT l.vehicle_id,
+	ST_Y(l.location::geometry) AS lat,
+	ST_X(l.location::geometry) AS lng,
+	ST_Distance(l.location, ref.location, false) AS dist
+FROM vehicle_locations l, ref
+WHERE l.tenant = $5 AND ST_DWithin(l.location, ref.location, $3, false)
+ORDER BY dist ASC, l.vehicle_id ASC
+LIMIT $4`
+)
+
+// PostgresLocationRepository stores the locations in PostgreSQL with PostGIS.
+// The current location of every vehicle is kept in vehicle_locations and every
+// saved point is appended to vehicle_location_history for analytics. Rows are
+// scoped by the tenant of the context.
+type PostgresLocationRepository struct {
+	db     *pgxpool.Pool
+	logger logger.ILogger
+}
+
+func NewPostgresLocationRepository(db *pgxpool.Pool, logger logger.ILogger) *PostgresLocationRepository {
+	return &PostgresLocationRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Save saves the location of the driver to postgres database
+func (r *PostgresLocationRepository) Save(ctx context.Context, in model.Location) error {
+	if err := ValidateCoordinates(in.Lat, in.Lng); err != nil {
+		return err
+	}
+
+	_, err := r.db.Exec(ctx, postgresSaveLocationQuery, in.VehicleId, in.Lat, in.Lng,
+		app.TenantFromContext(ctx))
+
+	return err
+}
+
+// Search searches for drivers in postgres database
+func (r *PostgresLocationRepository) Search(ctx context.Context, lat, lng, radius float64,
+	unit string, limit int) ([]model.Location, error) {
+
+	if limit == 0 || limit > maxLimit {
+		limit = defaultLimit
+	}
+
+	if err := ValidateCoordinates(lat, lng); err != nil {
+		return nil, err
+	}
+
+	m, err := UnitToMeters(unit)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, postgresSearchLocationQuery, lat, lng, radius*m, limit,
+		app.TenantFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make([]model.Location, 0)
+	for rows.Next() {
+		var l model.Location
+		if err := rows.Scan(&l.VehicleId, &l.Lat, &l.Lng, &l.Dist); err != nil {
+			return nil, err
+		}
+
+		l.Dist = RoundDistance(l.Dist / m)
+		res = append(res, l)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
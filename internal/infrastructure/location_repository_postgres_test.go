@@ -0,0 +1,124 @@
+package infrastructure
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/orkungursel/hey-taxi-location-api/internal/app"
+	"github.com/orkungursel/hey-taxi-location-api/internal/domain/model"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger/mock"
+)
+
+// SetupPostgresLocationRepository connects to the database given by POSTGRES_TEST_DSN,
+// e.g. the postgis container in docker-compose.yaml, and skips the test when it is not set.
+func SetupPostgresLocationRepository(t *testing.T) (*PostgresLocationRepository, *pgxpool.Pool) {
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_TEST_DSN is not set")
+	}
+
+	ctx := context.Background()
+
+	db, err := pgxpool.Connect(ctx, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(db.Close)
+
+	if err := MigratePostgres(ctx, db, mock.NewLoggerMock()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Exec(ctx, "TRUNCATE vehicle_locations, vehicle_location_history"); err != nil {
+		t.Fatal(err)
+	}
+
+	return NewPostgresLocationRepository(db, mock.NewLoggerMock()), db
+}
+
+func TestLoadPostgresMigrations(t *testing.T) {
+	migrations, err := loadPostgresMigrations()
+	if err != nil {
+		t.Fatalf("loadPostgresMigrations() error = %v", err)
+	}
+
+	if len(migrations) == 0 {
+		t.Fatal("loadPostgresMigrations() returned no migrations")
+	}
+
+	for i, m := range migrations {
+		if m.version != i+1 {
+			t.Errorf("loadPostgresMigrations()[%d].version = %d, want %d", i, m.version, i+1)
+		}
+		if m.sql == "" {
+			t.Errorf("loadPostgresMigrations()[%d] is empty", i)
+		}
+	}
+}
+
+func TestPostgresLocationRepository(t *testing.T) {
+	repo, db := SetupPostgresLocationRepository(t)
+	ctx := context.Background()
+
+	d1 := model.Location{VehicleId: "driver1", Lat: 1.0, Lng: 1.0}
+	d2 := model.Location{VehicleId: "driver2", Lat: 20.0, Lng: 20.0}
+
+	if err := repo.Save(ctx, model.Location{VehicleId: "driver", Lat: 100.0, Lng: 100.0}); err == nil {
+		t.Errorf("PostgresLocationRepository.Save() should fail with invalid coordinates")
+	}
+
+	// driver1 moves, only the last location should be searchable
+	for _, l := range []model.Location{{VehicleId: d1.VehicleId, Lat: 30.0, Lng: 30.0}, d1, d2} {
+		if err := repo.Save(ctx, l); err != nil {
+			t.Fatalf("PostgresLocationRepository.Save() error = %v", err)
+		}
+	}
+
+	var history int
+	if err := db.QueryRow(ctx, "SELECT count(*) FROM vehicle_location_history").Scan(&history); err != nil {
+		t.Fatal(err)
+	}
+	if history != 3 {
+		t.Errorf("vehicle_location_history has %d rows, want 3", history)
+	}
+
+	acme := app.WithTenant(ctx, "acme")
+	if err := repo.Save(acme, model.Location{VehicleId: "acme-driver", Lat: 1.0, Lng: 1.0}); err != nil {
+		t.Fatalf("PostgresLocationRepository.Save() error = %v", err)
+	}
+	if got, _ := repo.Search(acme, 1.0, 1.0, 10, "km", 0); len(got) != 1 || got[0].VehicleId != "acme-driver" {
+		t.Errorf("PostgresLocationRepository.Search() = %v, want only acme-driver", got)
+	}
+
+	tests := []struct {
+		name   string
+		radius float64
+		unit   string
+		limit  int
+		want   []string
+	}{
+		{name: "should return 1 result with radius 10 km", radius: 10, unit: "km", want: []string{d1.VehicleId}},
+		{name: "should return 2 results with radius 3000 km", radius: 3000, unit: "km", want: []string{d1.VehicleId, d2.VehicleId}},
+		{name: "should respect the limit", radius: 3000, unit: "km", limit: 1, want: []string{d1.VehicleId}},
+		{name: "should support other units", radius: 5, unit: "mi", want: []string{d1.VehicleId}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.Search(ctx, 1.0, 1.0, tt.radius, tt.unit, tt.limit)
+			if err != nil {
+				t.Fatalf("PostgresLocationRepository.Search() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("PostgresLocationRepository.Search() = %v, want %v", got, tt.want)
+			}
+			for i, id := range tt.want {
+				if got[i].VehicleId != id {
+					t.Errorf("PostgresLocationRepository.Search()[%d] = %v, want %v", i, got[i].VehicleId, id)
+				}
+			}
+		})
+	}
+}
@@ -2,6 +2,9 @@ package infrastructure
 
 import (
 	"context"
+	"fmt"
+	"math"
+	"sync"
 	"testing"
 
 	"github.com/alicebob/miniredis/v2"
@@ -167,3 +170,229 @@ func TestLocationRepository_Search(t *testing.T) {
 		})
 	}
 }
+
+func TestLocationRepository_Search_Order(t *testing.T) {
+	t.Parallel()
+
+	repo, _ := SetupLocationRepositoryMocks()
+
+	// lat and lng differ, so swapped coordinates would miss or misplace them, and the
+	// nearest one has the highest geohash, so the unsorted results would be reversed
+	far := model.Location{VehicleId: "far", Lat: 41.00, Lng: 29.00}
+	near := model.Location{VehicleId: "near", Lat: 41.06, Lng: 29.06}
+	middle := model.Location{VehicleId: "middle", Lat: 41.02, Lng: 29.02}
+	for _, l := range []model.Location{far, near, middle} {
+		if err := repo.Save(context.Background(), l); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := repo.Search(context.Background(), 41.05, 29.05, 50, "km", 0)
+	if err != nil {
+		t.Fatalf("LocationRepository.Search() error = %v", err)
+	}
+
+	want := []model.Location{near, middle, far}
+	if len(got) != len(want) {
+		t.Fatalf("LocationRepository.Search() = %v, want %v", got, want)
+	}
+	for i, w := range want {
+		if got[i].VehicleId != w.VehicleId {
+			t.Errorf("LocationRepository.Search()[%d] = %v, want %v", i, got[i].VehicleId, w.VehicleId)
+		}
+		// geohash encoding keeps the coordinates to about 1e-6 degrees
+		if math.Abs(got[i].Lat-w.Lat) > 1e-5 || math.Abs(got[i].Lng-w.Lng) > 1e-5 {
+			t.Errorf("LocationRepository.Search()[%d] = %v, %v, want %v, %v", i, got[i].Lat, got[i].Lng, w.Lat, w.Lng)
+		}
+		if i > 0 && got[i].Dist < got[i-1].Dist {
+			t.Errorf("LocationRepository.Search()[%d] dist = %v, want nearest first", i, got[i].Dist)
+		}
+	}
+}
+
+func TestLocationRepository_Sharded(t *testing.T) {
+	t.Parallel()
+
+	_, db := SetupLocationRepositoryMocks()
+	repo, err := NewShardedLocationRepository(db, mock.NewLoggerMock(), 3, []string{"sxk=istanbul"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	west := model.Location{VehicleId: "west", Lat: 10, Lng: -0.01}
+	east := model.Location{VehicleId: "east", Lat: 10, Lng: 0.01}
+	moving := model.Location{VehicleId: "moving", Lat: 41.0082, Lng: 28.9784}
+
+	for _, l := range []model.Location{west, east, moving} {
+		if err := repo.Save(ctx, l); err != nil {
+			t.Fatalf("LocationRepository.Save() error = %v", err)
+		}
+	}
+
+	if n := db.ZCard(ctx, dbKey+":istanbul").Val(); n != 1 {
+		t.Errorf("istanbul shard has %d members, want 1", n)
+	}
+
+	// moving vehicle leaves istanbul shard and joins east
+	moving.Lat, moving.Lng = 10, 0.02
+	if err := repo.Save(ctx, moving); err != nil {
+		t.Fatalf("LocationRepository.Save() error = %v", err)
+	}
+
+	if n := db.ZCard(ctx, dbKey+":istanbul").Val(); n != 0 {
+		t.Errorf("istanbul shard has %d members after the move, want 0", n)
+	}
+	if got := db.HGet(ctx, dbKey+":vehicle-shards", moving.VehicleId).Val(); got != dbKey+":gh:s1b" {
+		t.Errorf("vehicle shard index = %v, want %v", got, dbKey+":gh:s1b")
+	}
+
+	tests := []struct {
+		name   string
+		lat    float64
+		lng    float64
+		radius float64
+		limit  int
+		want   []string
+	}{
+		{name: "should search across the shard border", lat: 10, lng: -0.001, radius: 5, want: []string{"west", "east", "moving"}},
+		{name: "should apply the limit after merging", lat: 10, lng: 0.016, radius: 5, limit: 2, want: []string{"moving", "east"}},
+		{name: "should search every shard when radius is large", lat: 10, lng: -0.001, radius: 5000, want: []string{"west", "east", "moving"}},
+		{name: "should not return the vehicle from its old shard", lat: 41.0082, lng: 28.9784, radius: 10, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.Search(ctx, tt.lat, tt.lng, tt.radius, "km", tt.limit)
+			if err != nil {
+				t.Fatalf("LocationRepository.Search() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("LocationRepository.Search() = %v, want %v", got, tt.want)
+			}
+			for i, id := range tt.want {
+				if got[i].VehicleId != id {
+					t.Errorf("LocationRepository.Search()[%d] = %v, want %v", i, got[i].VehicleId, id)
+				}
+			}
+		})
+	}
+}
+
+func TestLocationRepository_Sharded_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	_, db := SetupLocationRepositoryMocks()
+	repo, err := NewShardedLocationRepository(db, mock.NewLoggerMock(), 3, []string{"sxk=istanbul"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	locations := []model.Location{
+		{VehicleId: "v1", Lat: 41.0082, Lng: 28.9784},
+		{VehicleId: "v1", Lat: 10, Lng: -0.01},
+		{VehicleId: "v1", Lat: 10, Lng: 0.01},
+	}
+
+	// concurrent saves across the shard borders leave the vehicle in a single shard
+	var wg sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func(l model.Location) {
+			defer wg.Done()
+			if err := repo.Save(ctx, l); err != nil {
+				t.Errorf("LocationRepository.Save() error = %v", err)
+			}
+		}(locations[i%len(locations)])
+	}
+	wg.Wait()
+
+	if n, err := repo.Count(ctx); err != nil || n != 1 {
+		t.Errorf("LocationRepository.Count() = %v, %v, want 1", n, err)
+	}
+
+	shard := db.HGet(ctx, dbKey+":vehicle-shards", "v1").Val()
+	if n := db.ZCard(ctx, shard).Val(); n != 1 {
+		t.Errorf("indexed shard %s has %d members, want 1", shard, n)
+	}
+}
+
+func TestLocationRepository_Sharded_Dedupe(t *testing.T) {
+	t.Parallel()
+
+	_, db := SetupLocationRepositoryMocks()
+	repo, err := NewShardedLocationRepository(db, mock.NewLoggerMock(), 3, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := repo.Save(ctx, model.Location{VehicleId: "v1", Lat: 10, Lng: 0.01}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a search racing with a move sees the vehicle in its old shard too
+	db.GeoAdd(ctx, repo.shards.KeyFor(10, -0.03), &redis.GeoLocation{Name: "v1", Latitude: 10, Longitude: -0.03})
+
+	got, err := repo.Search(ctx, 10, 0, 10, "km", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].VehicleId != "v1" || got[0].Lng < 0 {
+		t.Errorf("LocationRepository.Search() = %v, want the nearest v1 once", got)
+	}
+}
+
+func TestLocationRepository_SaveBatch(t *testing.T) {
+	t.Parallel()
+
+	repo, db := SetupLocationRepositoryMocks()
+	ctx := context.Background()
+
+	in := make([]model.Location, geoAddBatch+10)
+	for i := range in {
+		in[i] = model.Location{VehicleId: fmt.Sprintf("v%d", i), Lat: 41, Lng: 29}
+	}
+	// redis rejects the latitude, it should not fail the other locations
+	in = append(in, model.Location{VehicleId: "pole", Lat: 89, Lng: 29})
+
+	if err := repo.SaveBatch(ctx, in); err != nil {
+		t.Fatalf("LocationRepository.SaveBatch() error = %v", err)
+	}
+
+	if n := db.ZCard(ctx, dbKey).Val(); n != int64(geoAddBatch+10) {
+		t.Errorf("LocationRepository.SaveBatch() saved %d locations, want %d", n, geoAddBatch+10)
+	}
+}
+
+func TestLocationRepository_Count(t *testing.T) {
+	t.Parallel()
+
+	_, db := SetupLocationRepositoryMocks()
+	sharded, err := NewShardedLocationRepository(db, mock.NewLoggerMock(), 3, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, repo := range []*LocationRepository{NewLocationRepository(db, mock.NewLoggerMock()), sharded} {
+		ctx := context.Background()
+		for _, l := range []model.Location{
+			{VehicleId: "v1", Lat: 10, Lng: 10},
+			{VehicleId: "v2", Lat: 40, Lng: 40},
+			{VehicleId: "v1", Lat: 40, Lng: 40},
+		} {
+			if err := repo.Save(ctx, l); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		got, err := repo.Count(ctx)
+		if err != nil {
+			t.Fatalf("LocationRepository.Count() error = %v", err)
+		}
+		if got != 2 {
+			t.Errorf("LocationRepository.Count() = %v, want 2", got)
+		}
+	}
+}
@@ -3,20 +3,25 @@ package infrastructure
 import (
 	"context"
 	"errors"
+	"sort"
+	"time"
 
 	"github.com/orkungursel/hey-taxi-location-api/internal/app"
 	"github.com/orkungursel/hey-taxi-location-api/internal/domain/model"
 	"github.com/orkungursel/hey-taxi-location-api/pkg/logger"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/metrics"
 )
 
 const (
 	defaultLocationSearchRadius = 200
+	defaultLocationSearchUnit   = "km"
+	searchUnitMeters            = 1000 // searchUnitMeters is the length of the default search unit
 )
 
 var (
 	ErrEmptyUserId          = errors.New("user id is empty")
 	ErrVehicleService       = errors.New("vehicle service error")
-	ErrVehicleNotFound      = errors.New("vehicle not found")
+	ErrVehicleNotFound      = app.ErrVehicleNotFound
 	ErrVehicleOwnerNotMatch = errors.New("vehicle owner not match")
 )
 
@@ -24,14 +29,29 @@ type LocationService struct {
 	repo           app.LocationRepository
 	vehicleService app.VehicleService
 	logger         logger.ILogger
+	fuzzer         *LocationFuzzer
+	assignments    app.AssignmentRepository
+	coalescer      *LocationCoalescer
+	lastSeen       app.LastSeenRepository
+	metrics        *metrics.Metrics
 }
 
+// NewLocationService returns the location service, the search results are fuzzed
+// by the fuzzer, if any, except for the dispatch and the assigned riders. The
+// saves are coalesced by the coalescer, if any, and counted by m, if any. Every
+// stored ping refreshes the last-seen of the vehicle in lastSeen, if any.
 func NewLocationService(repo app.LocationRepository, logger logger.ILogger,
-	vehicleService app.VehicleService) *LocationService {
+	vehicleService app.VehicleService, fuzzer *LocationFuzzer, assignments app.AssignmentRepository,
+	coalescer *LocationCoalescer, lastSeen app.LastSeenRepository, m *metrics.Metrics) *LocationService {
 	return &LocationService{
 		repo:           repo,
 		logger:         logger,
 		vehicleService: vehicleService,
+		fuzzer:         fuzzer,
+		assignments:    assignments,
+		coalescer:      coalescer,
+		lastSeen:       lastSeen,
+		metrics:        m,
 	}
 }
 
@@ -46,6 +66,9 @@ func (s *LocationService) SaveLocation(ctx context.Context, userId string, in ap
 	}
 
 	vehicle, err := s.vehicleService.GetVehicleById(ctx, in.VehicleId)
+	if errors.Is(err, app.ErrVehicleNotFound) {
+		return ErrVehicleNotFound
+	}
 	if err != nil {
 		s.logger.Error(ctx, "vehicle service error", err)
 		return ErrVehicleService
@@ -70,26 +93,65 @@ func (s *LocationService) SaveLocation(ctx context.Context, userId string, in ap
 		return err
 	}
 
-	return s.repo.Save(ctx, l)
+	// the vehicle barely moved since its stored location, the coalescer keeps its last-seen
+	if s.coalescer.Skip(ctx, l) {
+		s.metrics.LocationSave(metrics.ResultCoalesced)
+		return nil
+	}
+
+	if err := s.repo.Save(ctx, l); err != nil {
+		s.metrics.LocationSave(metrics.ResultError)
+		return err
+	}
+
+	s.coalescer.Stored(ctx, l)
+	s.touch(ctx, l.VehicleId)
+	s.metrics.LocationSave(metrics.ResultStored)
+
+	return nil
+}
+
+// touch refreshes the last-seen of the vehicle, a failure does not fail the ping
+func (s *LocationService) touch(ctx context.Context, vehicleId string) {
+	if s.lastSeen == nil {
+		return
+	}
+
+	if err := s.lastSeen.Touch(ctx, vehicleId, time.Now()); err != nil {
+		s.logger.Warn(ctx, "failed to refresh last seen", vehicleId, err)
+	}
 }
 
 // Search searches for drivers
-func (s *LocationService) SearchLocations(ctx context.Context, q app.SearchLocationRequest) ([]app.LocationResponse, error) {
-	res, err := s.repo.Search(ctx, q.Lat, q.Lng, defaultLocationSearchRadius, "km", 0)
+func (s *LocationService) SearchLocations(ctx context.Context,
+	q app.SearchLocationRequest) (_ []app.LocationResponse, err error) {
+
+	defer func() { s.metrics.LocationSearch(err) }()
+
+	res, err := s.repo.Search(ctx, q.Lat, q.Lng, defaultLocationSearchRadius, defaultLocationSearchUnit, 0)
 	if err != nil {
 		return nil, err
 	}
 
 	data := make([]app.LocationResponse, 0)
+	if len(res) == 0 {
+		return data, nil
+	}
 
-	for _, v := range res {
-		vehicle, err := s.vehicleService.GetVehicleById(ctx, v.VehicleId)
-		if err != nil {
-			s.logger.Error(ctx, "vehicle service error", err)
-			return nil, ErrVehicleService
-		}
+	ids := make([]string, len(res))
+	for i, v := range res {
+		ids[i] = v.VehicleId
+	}
+
+	vehicles, err := s.vehicleService.GetVehiclesByIds(ctx, ids)
+	if err != nil {
+		s.logger.Error(ctx, "vehicle service error", err)
+		return nil, ErrVehicleService
+	}
 
-		if vehicle == nil {
+	for _, v := range res {
+		vehicle, ok := vehicles[v.VehicleId]
+		if !ok || vehicle == nil {
 			continue
 		}
 
@@ -101,5 +163,86 @@ func (s *LocationService) SearchLocations(ctx context.Context, q app.SearchLocat
 		})
 	}
 
-	return data, err
+	s.applyPrivacy(ctx, q, data)
+
+	return data, nil
+}
+
+// applyPrivacy fuzzes the positions which the caller may not see exactly,
+// the distances are recomputed so they do not give the exact positions away
+// and the results are sorted again by the recomputed distances
+func (s *LocationService) applyPrivacy(ctx context.Context, q app.SearchLocationRequest,
+	data []app.LocationResponse) {
+
+	if s.fuzzer == nil {
+		return
+	}
+
+	claims := app.ClaimsFromContext(ctx)
+	if claims != nil && app.SeesFullLocations(claims) {
+		return
+	}
+
+	riders := s.assignedRiders(ctx, claims, data)
+
+	fuzzed := false
+	for i := range data {
+		if canSeeExactLocation(claims, data[i].Vehicle, riders) {
+			continue
+		}
+
+		lat, lng := s.fuzzer.Fuzz(data[i].Vehicle.Id, data[i].Lat, data[i].Lng)
+		data[i].Lat = lat
+		data[i].Lng = lng
+		data[i].Dist = RoundDistance(Distance(q.Lat, q.Lng, lat, lng) / searchUnitMeters)
+		fuzzed = true
+	}
+
+	if fuzzed {
+		sort.SliceStable(data, func(i, j int) bool {
+			return data[i].Dist < data[j].Dist
+		})
+	}
+}
+
+// assignedRiders returns the riders assigned to the vehicles which the caller does
+// not drive, they are read at once. A failure is logged and returns no riders.
+func (s *LocationService) assignedRiders(ctx context.Context, claims app.Claims,
+	data []app.LocationResponse) map[string]string {
+
+	if s.assignments == nil || claims == nil || claims.GetSubject() == "" {
+		return nil
+	}
+
+	ids := make([]string, 0, len(data))
+	for _, v := range data {
+		if v.Vehicle.Driver.Id != claims.GetSubject() {
+			ids = append(ids, v.Vehicle.Id)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	riders, err := s.assignments.GetAssignedRiders(ctx, ids)
+	if err != nil {
+		s.logger.Warnf("failed to get the assigned riders of %d vehicles: %v", len(ids), err)
+		return nil
+	}
+
+	return riders
+}
+
+// canSeeExactLocation reports whether the caller is the driver or the assigned rider of the vehicle
+func canSeeExactLocation(claims app.Claims, vehicle model.Vehicle, riders map[string]string) bool {
+	if claims == nil || claims.GetSubject() == "" {
+		return false
+	}
+
+	if vehicle.Driver.Id == claims.GetSubject() {
+		return true
+	}
+
+	return riders[vehicle.Id] == claims.GetSubject()
 }
@@ -5,7 +5,9 @@ import (
 	"errors"
 	"reflect"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt"
 	"github.com/golang/mock/gomock"
 	"github.com/orkungursel/hey-taxi-location-api/internal/app"
 	"github.com/orkungursel/hey-taxi-location-api/internal/app/mock"
@@ -40,6 +42,7 @@ func TestLocationService_SaveLocation(t *testing.T) {
 		repository     func() app.LocationRepository
 		vehicleService func() app.VehicleService
 		wantErr        bool
+		wantErrIs      error
 	}{
 		{
 			name: "should success when data is valid",
@@ -62,6 +65,29 @@ func TestLocationService_SaveLocation(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "should fail with not found when vehicle service does not know the vehicle",
+			repository: func() app.LocationRepository {
+				r := mock.NewMockLocationRepository(ctrl)
+				r.EXPECT().Save(gomock.Any(), gomock.Any()).Times(0)
+				return r
+			},
+			vehicleService: func() app.VehicleService {
+				s := mock.NewMockVehicleService(ctrl)
+				s.EXPECT().GetVehicleById(gomock.Any(), v1.Id).Return(nil, app.ErrVehicleNotFound).Times(1)
+				return s
+			},
+			args: args{
+				userId: d1.Id,
+				in: app.SaveLocationRequest{
+					VehicleId: v1.Id,
+					Lat:       1.0,
+					Lng:       1.0,
+				},
+			},
+			wantErr:   true,
+			wantErrIs: app.ErrVehicleNotFound,
+		},
 		{
 			name: "should fail when no data provided",
 			repository: func() app.LocationRepository {
@@ -114,11 +140,15 @@ func TestLocationService_SaveLocation(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			locationService := NewLocationService(tt.repository(), loggerMock, tt.vehicleService())
+			locationService := NewLocationService(tt.repository(), loggerMock, tt.vehicleService(), nil, nil, nil, nil, nil)
 
-			if err := locationService.SaveLocation(context.Background(), tt.args.userId, tt.args.in); (err != nil) != tt.wantErr {
+			err := locationService.SaveLocation(context.Background(), tt.args.userId, tt.args.in)
+			if (err != nil) != tt.wantErr {
 				t.Errorf("LocationService.SaveLocation() error = %v, wantErr %v", err, tt.wantErr)
 			}
+			if tt.wantErrIs != nil && !errors.Is(err, tt.wantErrIs) {
+				t.Errorf("LocationService.SaveLocation() error = %v, want %v", err, tt.wantErrIs)
+			}
 		})
 	}
 }
@@ -150,8 +180,8 @@ func TestLocationService_SearchLocations(t *testing.T) {
 			},
 			vehicleService: func() app.VehicleService {
 				vs := mock.NewMockVehicleService(ctrl)
-				vs.EXPECT().GetVehicleById(gomock.Any(), v1.Id).
-					Return(&v1, nil).Times(1)
+				vs.EXPECT().GetVehiclesByIds(gomock.Any(), []string{v1.Id}).
+					Return(map[string]*model.Vehicle{v1.Id: &v1}, nil).Times(1)
 				return vs
 			},
 			args: args{
@@ -174,8 +204,29 @@ func TestLocationService_SearchLocations(t *testing.T) {
 			},
 			vehicleService: func() app.VehicleService {
 				userService := mock.NewMockVehicleService(ctrl)
-				userService.EXPECT().GetVehicleById(gomock.Any(), gomock.Any()).
-					Return(nil, nil).Times(1)
+				userService.EXPECT().GetVehiclesByIds(gomock.Any(), gomock.Any()).
+					Return(map[string]*model.Vehicle{}, nil).Times(1)
+				return userService
+			},
+			args: args{
+				q: app.SearchLocationRequest{
+					Lat: 1.0,
+					Lng: 1.0,
+				},
+			},
+			want: []app.LocationResponse{},
+		},
+		{
+			name: "should skip location when vehicle is nil",
+			repository: func() app.LocationRepository {
+				repo, _ := SetupLocationRepositoryMocks()
+				_ = repo.Save(context.Background(), l1)
+				return repo
+			},
+			vehicleService: func() app.VehicleService {
+				userService := mock.NewMockVehicleService(ctrl)
+				userService.EXPECT().GetVehiclesByIds(gomock.Any(), gomock.Any()).
+					Return(map[string]*model.Vehicle{v1.Id: nil}, nil).Times(1)
 				return userService
 			},
 			args: args{
@@ -196,7 +247,7 @@ func TestLocationService_SearchLocations(t *testing.T) {
 			},
 			vehicleService: func() app.VehicleService {
 				userService := mock.NewMockVehicleService(ctrl)
-				userService.EXPECT().GetVehicleById(gomock.Any(), gomock.Any()).Times(0)
+				userService.EXPECT().GetVehiclesByIds(gomock.Any(), gomock.Any()).Times(0)
 				return userService
 			},
 			args: args{
@@ -236,7 +287,7 @@ func TestLocationService_SearchLocations(t *testing.T) {
 			},
 			vehicleService: func() app.VehicleService {
 				userService := mock.NewMockVehicleService(ctrl)
-				userService.EXPECT().GetVehicleById(gomock.Any(), gomock.Any()).Return(nil, errors.New("error"))
+				userService.EXPECT().GetVehiclesByIds(gomock.Any(), gomock.Any()).Return(nil, errors.New("error"))
 				return userService
 			},
 			args: args{
@@ -251,7 +302,7 @@ func TestLocationService_SearchLocations(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			locationService := NewLocationService(tt.repository(), loggerMock, tt.vehicleService())
+			locationService := NewLocationService(tt.repository(), loggerMock, tt.vehicleService(), nil, nil, nil, nil, nil)
 			got, err := locationService.SearchLocations(context.Background(), tt.args.q)
 
 			if (err != nil) != tt.wantErr {
@@ -264,3 +315,105 @@ func TestLocationService_SearchLocations(t *testing.T) {
 		})
 	}
 }
+
+func TestLocationService_SearchLocations_Privacy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mock.NewMockLocationRepository(ctrl)
+	repo.EXPECT().Search(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return([]model.Location{l1}, nil).AnyTimes()
+
+	vs := mock.NewMockVehicleService(ctrl)
+	vs.EXPECT().GetVehiclesByIds(gomock.Any(), gomock.Any()).
+		Return(map[string]*model.Vehicle{v1.Id: &v1}, nil).AnyTimes()
+
+	assignments := NewMemoryAssignmentRepository()
+	assignments.Assign(context.Background(), v1.Id, "assigned-rider")
+
+	fuzzer, _ := NewLocationFuzzer(PrivacyModeGrid, 1000, time.Minute, "")
+	s := NewLocationService(repo, logger.NewLoggerMock(), vs, fuzzer, assignments, nil, nil, nil)
+
+	claims := func(subject, role string) context.Context {
+		return app.WithClaims(context.Background(), &Claims{Role: role, StandardClaims: jwt.StandardClaims{Subject: subject}})
+	}
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		wantExact bool
+	}{
+		{name: "should fuzz for anonymous callers", ctx: context.Background()},
+		{name: "should fuzz for riders", ctx: claims("rider", app.RoleRider)},
+		{name: "should fuzz for other drivers", ctx: claims("other-driver", app.RoleDriver)},
+		{name: "should not fuzz for the assigned rider", ctx: claims("assigned-rider", app.RoleRider), wantExact: true},
+		{name: "should not fuzz for the driver of the vehicle", ctx: claims(d1.Id, app.RoleDriver), wantExact: true},
+		{name: "should not fuzz for dispatchers", ctx: claims("dispatcher", app.RoleDispatcher), wantExact: true},
+		{name: "should not fuzz for admins", ctx: claims("admin", app.RoleAdmin), wantExact: true},
+		{name: "should fuzz for services without the full scope", ctx: app.WithClaims(context.Background(),
+			&ServiceClaims{Service: "pricing", Scopes: []string{app.ScopeLocationSearch}})},
+		{name: "should not fuzz for services with the full scope", ctx: app.WithClaims(context.Background(),
+			&ServiceClaims{Service: "dispatch", Scopes: []string{app.ScopeLocationSearch, app.ScopeLocationFull}}),
+			wantExact: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.SearchLocations(tt.ctx, app.SearchLocationRequest{Lat: 1.0, Lng: 1.0})
+			if err != nil {
+				t.Fatalf("LocationService.SearchLocations() error = %v", err)
+			}
+			if len(got) != 1 {
+				t.Fatalf("LocationService.SearchLocations() = %v, want 1 result", got)
+			}
+
+			exact := got[0].Lat == l1.Lat && got[0].Lng == l1.Lng
+			if exact != tt.wantExact {
+				t.Errorf("LocationService.SearchLocations() = %v, %v, wantExact %v", got[0].Lat, got[0].Lng, tt.wantExact)
+			}
+
+			// the distance must match the returned point, not the exact one
+			if want := RoundDistance(Distance(1.0, 1.0, got[0].Lat, got[0].Lng) / 1000); !exact && got[0].Dist != want {
+				t.Errorf("LocationService.SearchLocations() dist = %v, want %v", got[0].Dist, want)
+			}
+		})
+	}
+}
+
+func TestLocationService_SearchLocations_PrivacyOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// the nearest vehicle is snapped to the center of a farther grid cell
+	near := model.Location{VehicleId: v1.Id, Lat: 0.0895, Lng: 0, Dist: 3.39}
+	far := model.Location{VehicleId: v2.Id, Lat: 0.17, Lng: 0, Dist: 5.56}
+
+	repo := mock.NewMockLocationRepository(ctrl)
+	repo.EXPECT().Search(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return([]model.Location{near, far}, nil)
+
+	vs := mock.NewMockVehicleService(ctrl)
+	vs.EXPECT().GetVehiclesByIds(gomock.Any(), gomock.Any()).
+		Return(map[string]*model.Vehicle{v1.Id: &v1, v2.Id: &v2}, nil)
+
+	// the assignments of all the vehicles are read at once
+	assignments := mock.NewMockAssignmentRepository(ctrl)
+	assignments.EXPECT().GetAssignedRiders(gomock.Any(), []string{v1.Id, v2.Id}).
+		Return(map[string]string{v2.Id: "other-rider"}, nil).Times(1)
+
+	fuzzer, _ := NewLocationFuzzer(PrivacyModeGrid, 10000, time.Minute, "")
+	s := NewLocationService(repo, logger.NewLoggerMock(), vs, fuzzer, assignments, nil, nil, nil)
+
+	ctx := app.WithClaims(context.Background(), &Claims{Role: app.RoleRider, StandardClaims: jwt.StandardClaims{Subject: "rider"}})
+	got, err := s.SearchLocations(ctx, app.SearchLocationRequest{Lat: 0.12, Lng: 0})
+	if err != nil {
+		t.Fatalf("LocationService.SearchLocations() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("LocationService.SearchLocations() = %v, want 2 results", got)
+	}
+
+	if got[0].Vehicle.Id != v2.Id || got[0].Dist > got[1].Dist {
+		t.Errorf("LocationService.SearchLocations() = %v (%v), %v (%v), want sorted by the fuzzed distance",
+			got[0].Vehicle.Id, got[0].Dist, got[1].Vehicle.Id, got[1].Dist)
+	}
+}
@@ -0,0 +1,160 @@
+package infrastructure
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	shardAutoKeyPrefix  = "gh"             // shardAutoKeyPrefix separates automatic shards from the named ones
+	shardRegistrySuffix = "shards"         // shardRegistrySuffix is the set of every shard key in use
+	shardIndexSuffix    = "vehicle-shards" // shardIndexSuffix is the hash of the current shard key of each vehicle
+)
+
+type geoShardRegion struct {
+	prefix string
+	key    string
+}
+
+// GeoShardRouter maps points to the sorted set keys they are stored in.
+// Named regions are matched by the longest geohash prefix first, e.g. sxk=istanbul
+// stores every point in the "sxk" cell into <dbKey>:istanbul. Points outside of
+// the regions go to <dbKey>:gh:<geohash> with the given precision, or to the
+// dbKey itself when the precision is zero.
+type GeoShardRouter struct {
+	dbKey     string
+	precision int
+	regions   []geoShardRegion
+}
+
+// NewGeoShardRouter creates a router from "prefix=name" region definitions
+func NewGeoShardRouter(dbKey string, precision int, regions []string) (*GeoShardRouter, error) {
+	if precision < 0 || precision > 12 {
+		return nil, fmt.Errorf("invalid shard precision: %d", precision)
+	}
+
+	r := &GeoShardRouter{
+		dbKey:     dbKey,
+		precision: precision,
+		regions:   make([]geoShardRegion, 0, len(regions)),
+	}
+
+	seen := make(map[string]bool, len(regions))
+
+	for _, region := range regions {
+		region = strings.TrimSpace(region)
+		if region == "" {
+			continue
+		}
+
+		parts := strings.SplitN(region, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid shard region: %s", region)
+		}
+
+		prefix := strings.ToLower(strings.TrimSpace(parts[0]))
+		name := strings.TrimSpace(parts[1])
+
+		for _, c := range prefix {
+			if !strings.ContainsRune(geohashAlphabet, c) {
+				return nil, fmt.Errorf("invalid geohash prefix in shard region: %s", region)
+			}
+		}
+
+		if name == shardAutoKeyPrefix || name == shardRegistrySuffix || name == shardIndexSuffix {
+			return nil, fmt.Errorf("reserved shard name in shard region: %s", region)
+		}
+
+		if seen[prefix] {
+			return nil, fmt.Errorf("duplicate geohash prefix in shard region: %s", region)
+		}
+		seen[prefix] = true
+
+		r.regions = append(r.regions, geoShardRegion{prefix: prefix, key: dbKey + ":" + name})
+	}
+
+	sort.SliceStable(r.regions, func(i, j int) bool {
+		return len(r.regions[i].prefix) > len(r.regions[j].prefix)
+	})
+
+	return r, nil
+}
+
+// RegistryKey returns the key of the set holding every shard key in use
+func (r *GeoShardRouter) RegistryKey() string {
+	return r.dbKey + ":" + shardRegistrySuffix
+}
+
+// IndexKey returns the key of the hash holding the shard key of each vehicle
+func (r *GeoShardRouter) IndexKey() string {
+	return r.dbKey + ":" + shardIndexSuffix
+}
+
+// hashPrecision is the geohash length needed to decide the shard of a point
+func (r *GeoShardRouter) hashPrecision() int {
+	p := r.precision
+	for _, region := range r.regions {
+		if len(region.prefix) > p {
+			p = len(region.prefix)
+		}
+	}
+
+	return p
+}
+
+// keyForHash returns the shard key of the geohash
+func (r *GeoShardRouter) keyForHash(hash string) string {
+	for _, region := range r.regions {
+		if strings.HasPrefix(hash, region.prefix) {
+			return region.key
+		}
+	}
+
+	if r.precision == 0 {
+		return r.dbKey
+	}
+
+	return r.dbKey + ":" + shardAutoKeyPrefix + ":" + hash[:r.precision]
+}
+
+// KeyFor returns the shard key of the point
+func (r *GeoShardRouter) KeyFor(lat, lng float64) string {
+	p := r.hashPrecision()
+	if p == 0 {
+		return r.dbKey
+	}
+
+	return r.keyForHash(EncodeGeohash(lat, lng, p))
+}
+
+// KeysFor returns the shard keys which may contain points in the circle.
+// It returns nil when the circle is too large to enumerate its cells,
+// in that case every known shard should be searched.
+func (r *GeoShardRouter) KeysFor(lat, lng, meters float64) []string {
+	p := r.hashPrecision()
+	if p == 0 {
+		return []string{r.dbKey}
+	}
+
+	hashes := GeohashesInRadius(lat, lng, meters, p)
+	if hashes == nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	keys := make([]string, 0)
+	for _, h := range hashes {
+		k := r.keyForHash(h)
+		if seen[k] {
+			continue
+		}
+
+		seen[k] = true
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
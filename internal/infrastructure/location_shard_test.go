@@ -0,0 +1,78 @@
+package infrastructure
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewGeoShardRouter(t *testing.T) {
+	tests := []struct {
+		name      string
+		precision int
+		regions   []string
+		wantErr   bool
+	}{
+		{name: "should create router without regions", precision: 3},
+		{name: "should create router with regions", precision: 3, regions: []string{"sxk=istanbul", " sxp = ankara "}},
+		{name: "should fail with invalid region", regions: []string{"sxk"}, wantErr: true},
+		{name: "should fail with invalid geohash", regions: []string{"sxa=istanbul"}, wantErr: true},
+		{name: "should fail with duplicate prefix", regions: []string{"sxk=istanbul", "sxk=ankara"}, wantErr: true},
+		{name: "should fail with reserved name", regions: []string{"sxk=shards"}, wantErr: true},
+		{name: "should fail with invalid precision", precision: 13, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewGeoShardRouter(dbKey, tt.precision, tt.regions); (err != nil) != tt.wantErr {
+				t.Errorf("NewGeoShardRouter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGeoShardRouter_KeyFor(t *testing.T) {
+	r, _ := NewGeoShardRouter(dbKey, 2, []string{"sx=turkey", "sxk=istanbul"})
+	noAuto, _ := NewGeoShardRouter(dbKey, 0, []string{"sxk=istanbul"})
+
+	tests := []struct {
+		name   string
+		router *GeoShardRouter
+		lat    float64
+		lng    float64
+		want   string
+	}{
+		{name: "should prefer the longest prefix", router: r, lat: 41.0082, lng: 28.9784, want: dbKey + ":istanbul"},
+		{name: "should match the shorter prefix", router: r, lat: 39.9334, lng: 32.8597, want: dbKey + ":turkey"},
+		{name: "should fallback to automatic shard", router: r, lat: 10, lng: 0.01, want: dbKey + ":gh:s1"},
+		{name: "should fallback to db key", router: noAuto, lat: 10, lng: 0.01, want: dbKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.router.KeyFor(tt.lat, tt.lng); got != tt.want {
+				t.Errorf("GeoShardRouter.KeyFor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGeoShardRouter_KeysFor(t *testing.T) {
+	r, _ := NewGeoShardRouter(dbKey, 3, nil)
+
+	tests := []struct {
+		name   string
+		lat    float64
+		lng    float64
+		meters float64
+		want   []string
+	}{
+		{name: "should return single shard", lat: 10.7, lng: 0.7, meters: 1000, want: []string{dbKey + ":gh:s1b"}},
+		{name: "should return every shard the circle straddles", lat: 10, lng: 0, meters: 5000, want: []string{dbKey + ":gh:ecz", dbKey + ":gh:s1b"}},
+		{name: "should return nil when circle is too large", lat: 10, lng: 0, meters: 5000000, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.KeysFor(tt.lat, tt.lng, tt.meters); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GeoShardRouter.KeysFor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
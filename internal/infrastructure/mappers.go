@@ -3,6 +3,12 @@ package infrastructure
 import (
 	"github.com/go-redis/redis/v8"
 	"github.com/orkungursel/hey-taxi-location-api/internal/domain/model"
+	"github.com/orkungursel/hey-taxi-location-api/proto"
+)
+
+const (
+	phoneMaskPrefix = 3 // phoneMaskPrefix is the number of the leading characters left visible, e.g. +90
+	phoneMaskSuffix = 2 // phoneMaskSuffix is the number of the trailing digits left visible
 )
 
 func MapRedisGeoLocationToDomain(g redis.GeoLocation) *model.Location {
@@ -32,3 +38,45 @@ func MapUserToDriver(u model.User) *model.Driver {
 		Picture:  u.Picture,
 	}
 }
+
+func MapGrpcVehicleToDomain(v *proto.GetVehicleResponse) *model.Vehicle {
+	return &model.Vehicle{
+		Id:    v.GetId(),
+		Name:  v.GetName(),
+		Plate: v.GetPlate(),
+		Type:  v.GetType(),
+		Class: v.GetClass(),
+		Seats: int(v.GetSeats()),
+		Color: v.GetColor(),
+		Driver: model.Driver{
+			Id:        v.GetDriver().GetId(),
+			Name:      v.GetDriver().GetName(),
+			Nickname:  v.GetDriver().GetNickname(),
+			Email:     v.GetDriver().GetEmail(),
+			Picture:   v.GetDriver().GetAvatar(),
+			Rating:    v.GetDriver().GetRating(),
+			PhoneMask: MaskPhone(v.GetDriver().GetPhone()),
+		},
+	}
+}
+
+// MaskPhone hides the digits of the phone number except the country prefix and the
+// last ones, short numbers are masked completely
+func MaskPhone(phone string) string {
+	r := []rune(phone)
+	if len(r) == 0 {
+		return ""
+	}
+
+	visible := func(i int) bool {
+		return len(r) > phoneMaskPrefix+phoneMaskSuffix+2 && (i < phoneMaskPrefix || i >= len(r)-phoneMaskSuffix)
+	}
+
+	for i, c := range r {
+		if c >= '0' && c <= '9' && !visible(i) {
+			r[i] = '*'
+		}
+	}
+
+	return string(r)
+}
@@ -0,0 +1,90 @@
+package infrastructure
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/orkungursel/hey-taxi-location-api/internal/domain/model"
+	"github.com/orkungursel/hey-taxi-location-api/proto"
+)
+
+func TestMapGrpcVehicleToDomain(t *testing.T) {
+	tests := []struct {
+		name string
+		in   *proto.GetVehicleResponse
+		want *model.Vehicle
+	}{
+		{
+			name: "should map the vehicle and the driver profile",
+			in: &proto.GetVehicleResponse{
+				Id:    "vehicle_id",
+				Name:  "name",
+				Plate: "plate",
+				Type:  "type",
+				Class: "class",
+				Seats: 4,
+				Color: "yellow",
+				Driver: &proto.DriverDetailsResponse{
+					Id:       "driver_id",
+					Name:     "driver_name",
+					Nickname: "driver_nickname",
+					Email:    "driver_email",
+					Avatar:   "driver_picture",
+					Rating:   4.8,
+					Phone:    "+905551234567",
+				},
+			},
+			want: &model.Vehicle{
+				Id:    "vehicle_id",
+				Name:  "name",
+				Plate: "plate",
+				Type:  "type",
+				Class: "class",
+				Seats: 4,
+				Color: "yellow",
+				Driver: model.Driver{
+					Id:        "driver_id",
+					Name:      "driver_name",
+					Nickname:  "driver_nickname",
+					Email:     "driver_email",
+					Picture:   "driver_picture",
+					Rating:    4.8,
+					PhoneMask: "+90********67",
+				},
+			},
+		},
+		{
+			name: "should map the vehicle without driver",
+			in:   &proto.GetVehicleResponse{Id: "vehicle_id"},
+			want: &model.Vehicle{Id: "vehicle_id"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MapGrpcVehicleToDomain(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MapGrpcVehicleToDomain() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskPhone(t *testing.T) {
+	tests := []struct {
+		phone string
+		want  string
+	}{
+		{phone: "", want: ""},
+		{phone: "+905551234567", want: "+90********67"},
+		{phone: "+90 555 123 45 67", want: "+90 *** *** ** 67"},
+		{phone: "05551234567", want: "055******67"},
+		{phone: "1234567", want: "*******"},
+		{phone: "112", want: "***"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.phone, func(t *testing.T) {
+			if got := MaskPhone(tt.phone); got != tt.want {
+				t.Errorf("MaskPhone() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
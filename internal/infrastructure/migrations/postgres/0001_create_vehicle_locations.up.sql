@@ -0,0 +1,10 @@
+CREATE EXTENSION IF NOT EXISTS postgis;
+
+CREATE TABLE IF NOT EXISTS vehicle_locations (
+    vehicle_id TEXT PRIMARY KEY,
+    location   GEOGRAPHY(POINT, 4326) NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS vehicle_locations_location_idx
+    ON vehicle_locations USING GIST (location);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS vehicle_location_history (
+    id          BIGSERIAL PRIMARY KEY,
+    vehicle_id  TEXT NOT NULL,
+    location    GEOGRAPHY(POINT, 4326) NOT NULL,
+    recorded_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS vehicle_location_history_vehicle_id_recorded_at_idx
+    ON vehicle_location_history (vehicle_id, recorded_at);
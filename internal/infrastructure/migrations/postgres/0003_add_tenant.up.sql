@@ -0,0 +1,8 @@
+ALTER TABLE vehicle_locations ADD COLUMN IF NOT EXISTS tenant TEXT NOT NULL DEFAULT '';
+ALTER TABLE vehicle_locations DROP CONSTRAINT IF EXISTS vehicle_locations_pkey;
+ALTER TABLE vehicle_locations ADD PRIMARY KEY (tenant, vehicle_id);
+
+ALTER TABLE vehicle_location_history ADD COLUMN IF NOT EXISTS tenant TEXT NOT NULL DEFAULT '';
+DROP INDEX IF EXISTS vehicle_location_history_vehicle_id_recorded_at_idx;
+CREATE INDEX IF NOT EXISTS vehicle_location_history_tenant_vehicle_id_recorded_at_idx
+    ON vehicle_location_history (tenant, vehicle_id, recorded_at);
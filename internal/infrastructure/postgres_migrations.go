@@ -0,0 +1,147 @@
+package infrastructure
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger"
+)
+
+const (
+	postgresMigrationsDir    = "migrations/postgres"
+	postgresMigrationsLockId = 2718281828 // postgresMigrationsLockId is the advisory lock held while migrating
+)
+
+//go:embed migrations/postgres/*.sql
+var postgresMigrations embed.FS
+
+type postgresMigration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// loadPostgresMigrations reads the embedded migrations ordered by version.
+// Migration files are named as <version>_<name>.up.sql
+func loadPostgresMigrations() ([]postgresMigration, error) {
+	entries, err := postgresMigrations.ReadDir(postgresMigrationsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]postgresMigration, 0, len(entries))
+	seen := make(map[int]string, len(entries))
+
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), ".up.sql")
+		if name == e.Name() {
+			continue
+		}
+
+		parts := strings.SplitN(name, "_", 2)
+		version, err := strconv.Atoi(parts[0])
+		if err != nil || len(parts) != 2 {
+			return nil, fmt.Errorf("invalid migration file name: %s", e.Name())
+		}
+
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("duplicate migration version %d: %s, %s", version, other, e.Name())
+		}
+		seen[version] = e.Name()
+
+		b, err := postgresMigrations.ReadFile(path.Join(postgresMigrationsDir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, postgresMigration{version: version, name: parts[1], sql: string(b)})
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].version < res[j].version })
+
+	return res, nil
+}
+
+// MigratePostgres applies the pending schema migrations. It holds an advisory
+// lock, so it is safe to run from every instance on startup.
+func MigratePostgres(ctx context.Context, db *pgxpool.Pool, logger logger.ILogger) error {
+	migrations, err := loadPostgresMigrations()
+	if err != nil {
+		return err
+	}
+
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", postgresMigrationsLockId); err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", postgresMigrationsLockId)
+	}()
+
+	if _, err := conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`); err != nil {
+		return err
+	}
+
+	applied := make(map[int]bool)
+	rows, err := conn.Query(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			rows.Close()
+			return err
+		}
+		applied[v] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, m.sql); err != nil {
+			_ = tx.Rollback(ctx)
+			return fmt.Errorf("migration %d_%s failed: %w", m.version, m.name, err)
+		}
+
+		if _, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)",
+			m.version, m.name); err != nil {
+			_ = tx.Rollback(ctx)
+			return err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return err
+		}
+
+		logger.Infof("applied postgres migration %d_%s", m.version, m.name)
+	}
+
+	return nil
+}
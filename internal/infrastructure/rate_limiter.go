@@ -0,0 +1,89 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/orkungursel/hey-taxi-location-api/internal/app"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger"
+)
+
+const (
+	rateLimitDbKey = "rate-limit" // rateLimitDbKey is the prefix of the keys of the token buckets
+)
+
+// takeTokenScript refills the bucket by the elapsed time and takes a token from
+// it, it returns 1 and 0 if allowed, otherwise 0 and the milliseconds to wait
+var takeTokenScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) / rate)
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", tostring(now))
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst / rate))
+
+return {allowed, wait}
+`)
+
+// RateLimiter keeps the token buckets in redis, so the limits are shared by
+// all the instances
+type RateLimiter struct {
+	db     redis.UniversalClient
+	logger logger.ILogger
+	dbKey  string
+	now    func() time.Time
+}
+
+func NewRateLimiter(db redis.UniversalClient, logger logger.ILogger) *RateLimiter {
+	return &RateLimiter{
+		db:     db,
+		logger: logger,
+		dbKey:  rateLimitDbKey,
+		now:    time.Now,
+	}
+}
+
+// Allow takes a request from the bucket of the key
+func (r *RateLimiter) Allow(ctx context.Context, key string, limit app.RateLimit) (bool, time.Duration, error) {
+	if limit.PerMinute <= 0 || limit.Burst <= 0 {
+		return true, 0, nil
+	}
+
+	if key == "" {
+		return false, 0, errors.New("key is empty")
+	}
+
+	rate := float64(limit.PerMinute) / float64(time.Minute.Milliseconds())
+	res, err := takeTokenScript.Run(ctx, r.db, []string{tenantKey(ctx, r.dbKey+":"+key)},
+		strconv.FormatFloat(rate, 'g', -1, 64), limit.Burst, r.now().UnixMilli()).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	if len(res) != 2 {
+		return false, 0, errors.New("unexpected rate limit script result")
+	}
+
+	return res[0] == 1, time.Duration(res[1]) * time.Millisecond, nil
+}
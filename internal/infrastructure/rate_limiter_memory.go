@@ -0,0 +1,96 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-location-api/internal/app"
+)
+
+const (
+	// memoryRateLimiterPrune is how often the full buckets are dropped
+	memoryRateLimiterPrune = time.Minute
+)
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time // when the bucket is full again
+}
+
+// MemoryRateLimiter keeps the token buckets in-process, for a single instance
+// or the tests
+type MemoryRateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	pruned  time.Time
+	now     func() time.Time
+}
+
+func NewMemoryRateLimiter() *MemoryRateLimiter {
+	return &MemoryRateLimiter{
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes a request from the bucket of the key
+func (r *MemoryRateLimiter) Allow(ctx context.Context, key string, limit app.RateLimit) (bool, time.Duration, error) {
+	if limit.PerMinute <= 0 || limit.Burst <= 0 {
+		return true, 0, nil
+	}
+
+	if key == "" {
+		return false, 0, errors.New("key is empty")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	r.prune(now)
+
+	key = tenantKey(ctx, key)
+	b, ok := r.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(limit.Burst), last: now}
+		r.buckets[key] = b
+	}
+
+	tokens, allowed, wait := takeToken(b.tokens, b.last, now, limit)
+	b.tokens = tokens
+	b.last = now
+	b.full = now.Add(time.Duration((float64(limit.Burst) - tokens) * float64(time.Minute) / float64(limit.PerMinute)))
+
+	return allowed, wait, nil
+}
+
+// prune drops the buckets which are full again, they are the same as new ones
+func (r *MemoryRateLimiter) prune(now time.Time) {
+	if now.Sub(r.pruned) < memoryRateLimiterPrune {
+		return
+	}
+	r.pruned = now
+
+	for key, b := range r.buckets {
+		if !now.Before(b.full) {
+			delete(r.buckets, key)
+		}
+	}
+}
+
+// takeToken is the token bucket of takeTokenScript, it returns the tokens left,
+// whether a token was taken and how long to wait otherwise
+func takeToken(tokens float64, last, now time.Time, limit app.RateLimit) (float64, bool, time.Duration) {
+	rate := float64(limit.PerMinute) / float64(time.Minute)
+	tokens = math.Min(float64(limit.Burst), tokens+math.Max(0, float64(now.Sub(last)))*rate)
+
+	if tokens >= 1 {
+		return tokens - 1, true, 0
+	}
+
+	return tokens, false, time.Duration(math.Ceil((1 - tokens) / rate))
+}
@@ -0,0 +1,132 @@
+package infrastructure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/orkungursel/hey-taxi-location-api/internal/app"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger/mock"
+)
+
+func TestRateLimiters(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	db := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer db.Close()
+
+	now := time.Now()
+	clock := func() time.Time { return now }
+
+	redisLimiter := NewRateLimiter(db, mock.NewLoggerMock())
+	redisLimiter.now = clock
+	memoryLimiter := NewMemoryRateLimiter()
+	memoryLimiter.now = clock
+
+	limiters := map[string]app.RateLimiter{
+		"redis":  redisLimiter,
+		"memory": memoryLimiter,
+	}
+
+	limit := app.RateLimit{PerMinute: 60, Burst: 3}
+	acme := app.WithTenant(context.Background(), "acme")
+
+	for name, limiter := range limiters {
+		t.Run(name, func(t *testing.T) {
+			now = time.Now()
+			key := "save:driver:" + name
+
+			steps := []struct {
+				name        string
+				ctx         context.Context
+				key         string
+				limit       app.RateLimit
+				advance     time.Duration
+				wantAllowed bool
+				wantWait    time.Duration
+				wantErr     bool
+			}{
+				{name: "should allow the burst", key: key, limit: limit, wantAllowed: true},
+				{name: "should allow the burst", key: key, limit: limit, wantAllowed: true},
+				{name: "should allow the burst", key: key, limit: limit, wantAllowed: true},
+				{name: "should reject over the burst", key: key, limit: limit, wantWait: time.Second},
+				{name: "should tell the rest of the wait", key: key, limit: limit, advance: 400 * time.Millisecond,
+					wantWait: 600 * time.Millisecond},
+				{name: "should allow once refilled", key: key, limit: limit, advance: 600 * time.Millisecond, wantAllowed: true},
+				{name: "should reject again", key: key, limit: limit, wantWait: time.Second},
+				{name: "should keep the other keys apart", key: key + ":other", limit: limit, wantAllowed: true},
+				{name: "should keep the tenants apart", ctx: acme, key: key, limit: limit, wantAllowed: true},
+				{name: "should not limit without limit", key: key, limit: app.RateLimit{}, wantAllowed: true},
+				{name: "should refill up to the burst", key: key, limit: limit, advance: time.Hour, wantAllowed: true},
+				{name: "should refill up to the burst", key: key, limit: limit, wantAllowed: true},
+				{name: "should refill up to the burst", key: key, limit: limit, wantAllowed: true},
+				{name: "should refill up to the burst", key: key, limit: limit, wantWait: time.Second},
+				{name: "should fail with empty key", key: "", limit: limit, wantErr: true},
+			}
+			for i, s := range steps {
+				ctx := s.ctx
+				if ctx == nil {
+					ctx = context.Background()
+				}
+				now = now.Add(s.advance)
+
+				allowed, wait, err := limiter.Allow(ctx, s.key, s.limit)
+				if (err != nil) != s.wantErr {
+					t.Fatalf("%d %s: Allow() error = %v, wantErr %v", i, s.name, err, s.wantErr)
+				}
+				if allowed != s.wantAllowed || wait != s.wantWait {
+					t.Errorf("%d %s: Allow() = %v, %v, want %v, %v", i, s.name, allowed, wait, s.wantAllowed, s.wantWait)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiter_Expire(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	db := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer db.Close()
+
+	limiter := NewRateLimiter(db, mock.NewLoggerMock())
+	if _, _, err := limiter.Allow(context.Background(), "search:rider:r1", app.RateLimit{PerMinute: 60, Burst: 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the bucket expires once it would be full again
+	if ttl := mr.TTL(rateLimitDbKey + ":search:rider:r1"); ttl <= 0 || ttl > 10*time.Second {
+		t.Errorf("Allow() ttl = %v, want up to 10s", ttl)
+	}
+}
+
+func TestMemoryRateLimiter_Prune(t *testing.T) {
+	now := time.Now()
+	limiter := NewMemoryRateLimiter()
+	limiter.now = func() time.Time { return now }
+	limit := app.RateLimit{PerMinute: 60, Burst: 10}
+
+	for _, key := range []string{"a", "b"} {
+		if _, _, err := limiter.Allow(context.Background(), key, limit); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, _, err := limiter.Allow(context.Background(), "c", limit); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(limiter.buckets) != 1 {
+		t.Errorf("MemoryRateLimiter buckets = %d, want 1", len(limiter.buckets))
+	}
+}
@@ -0,0 +1,61 @@
+package infrastructure
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/metrics"
+)
+
+type redisMetricsStartKey struct{}
+
+// RedisMetricsHook observes the latency of the redis commands, a pipeline is
+// observed once under the "pipeline" command
+type RedisMetricsHook struct {
+	metrics *metrics.Metrics
+}
+
+func NewRedisMetricsHook(m *metrics.Metrics) *RedisMetricsHook {
+	return &RedisMetricsHook{metrics: m}
+}
+
+func (h *RedisMetricsHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, redisMetricsStartKey{}, time.Now()), nil
+}
+
+func (h *RedisMetricsHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	h.observe(ctx, cmd.Name(), cmd.Err())
+	return nil
+}
+
+func (h *RedisMetricsHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, redisMetricsStartKey{}, time.Now()), nil
+}
+
+func (h *RedisMetricsHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if cmd.Err() != nil && cmd.Err() != redis.Nil {
+			err = cmd.Err()
+			break
+		}
+	}
+
+	h.observe(ctx, "pipeline", err)
+	return nil
+}
+
+func (h *RedisMetricsHook) observe(ctx context.Context, command string, err error) {
+	start, ok := ctx.Value(redisMetricsStartKey{}).(time.Time)
+	if !ok {
+		return
+	}
+
+	// a missing key is an answer, not a failure
+	if err == redis.Nil {
+		err = nil
+	}
+
+	h.metrics.ObserveRedisCommand(command, err, time.Since(start))
+}
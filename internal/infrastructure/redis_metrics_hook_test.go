@@ -0,0 +1,42 @@
+package infrastructure
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/metrics"
+)
+
+func TestRedisMetricsHook(t *testing.T) {
+	_, db := SetupLocationRepositoryMocks()
+
+	m := metrics.New()
+	db.AddHook(NewRedisMetricsHook(m))
+
+	ctx := context.Background()
+	db.Set(ctx, "key", "value", 0)
+	if err := db.Get(ctx, "missing").Err(); err != redis.Nil {
+		t.Fatalf("Get() error = %v, want redis.Nil", err)
+	}
+	db.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Get(ctx, "key")
+		return nil
+	})
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	for _, w := range []string{
+		`hey_taxi_location_redis_command_duration_seconds_count{command="set",result="ok"} 1`,
+		`hey_taxi_location_redis_command_duration_seconds_count{command="get",result="ok"} 1`,
+		`hey_taxi_location_redis_command_duration_seconds_count{command="pipeline",result="ok"} 1`,
+	} {
+		if !strings.Contains(rec.Body.String(), w) {
+			t.Errorf("metrics do not contain %q", w)
+		}
+	}
+}
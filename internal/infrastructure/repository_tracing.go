@@ -0,0 +1,103 @@
+package infrastructure
+
+import (
+	"context"
+
+	"github.com/orkungursel/hey-taxi-location-api/internal/app"
+	"github.com/orkungursel/hey-taxi-location-api/internal/domain/model"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracedLocationRepository starts a span for every call of the repository, the
+// redis commands of the call are its children
+type TracedLocationRepository struct {
+	repo app.LocationRepository
+}
+
+func NewTracedLocationRepository(repo app.LocationRepository) *TracedLocationRepository {
+	return &TracedLocationRepository{repo: repo}
+}
+
+func (r *TracedLocationRepository) Save(ctx context.Context, in model.Location) error {
+	ctx, span := tracing.Tracer().Start(ctx, "LocationRepository.Save",
+		trace.WithAttributes(attribute.String("vehicle.id", in.VehicleId)))
+	defer span.End()
+
+	return endSpan(span, r.repo.Save(ctx, in))
+}
+
+func (r *TracedLocationRepository) Search(ctx context.Context, lat, lng, radius float64,
+	unit string, limit int) ([]model.Location, error) {
+
+	ctx, span := tracing.Tracer().Start(ctx, "LocationRepository.Search",
+		trace.WithAttributes(
+			attribute.Float64("search.radius", radius),
+			attribute.String("search.unit", unit),
+		))
+	defer span.End()
+
+	res, err := r.repo.Search(ctx, lat, lng, radius, unit, limit)
+	span.SetAttributes(attribute.Int("search.results", len(res)))
+
+	return res, endSpan(span, err)
+}
+
+// TracedVehicleRepository starts a span for every call of the repository
+type TracedVehicleRepository struct {
+	repo app.VehicleRepository
+}
+
+func NewTracedVehicleRepository(repo app.VehicleRepository) *TracedVehicleRepository {
+	return &TracedVehicleRepository{repo: repo}
+}
+
+func (r *TracedVehicleRepository) Get(ctx context.Context, vehicleId string) (*model.Vehicle, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "VehicleRepository.Get",
+		trace.WithAttributes(attribute.String("vehicle.id", vehicleId)))
+	defer span.End()
+
+	vehicle, err := r.repo.Get(ctx, vehicleId)
+	span.SetAttributes(attribute.Bool("vehicle.found", vehicle != nil))
+
+	return vehicle, endSpan(span, err)
+}
+
+func (r *TracedVehicleRepository) GetMany(ctx context.Context, vehicleIds []string) (map[string]*model.Vehicle, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "VehicleRepository.GetMany",
+		trace.WithAttributes(attribute.Int("vehicle.count", len(vehicleIds))))
+	defer span.End()
+
+	vehicles, err := r.repo.GetMany(ctx, vehicleIds)
+	span.SetAttributes(attribute.Int("vehicle.found", len(vehicles)))
+
+	return vehicles, endSpan(span, err)
+}
+
+func (r *TracedVehicleRepository) Save(ctx context.Context, vehicle *model.Vehicle) error {
+	ctx, span := tracing.Tracer().Start(ctx, "VehicleRepository.Save",
+		trace.WithAttributes(attribute.String("vehicle.id", vehicle.Id)))
+	defer span.End()
+
+	return endSpan(span, r.repo.Save(ctx, vehicle))
+}
+
+func (r *TracedVehicleRepository) Delete(ctx context.Context, vehicleId string) error {
+	ctx, span := tracing.Tracer().Start(ctx, "VehicleRepository.Delete",
+		trace.WithAttributes(attribute.String("vehicle.id", vehicleId)))
+	defer span.End()
+
+	return endSpan(span, r.repo.Delete(ctx, vehicleId))
+}
+
+// endSpan marks the span as failed if err is not nil and returns err
+func endSpan(span trace.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return err
+}
@@ -0,0 +1,113 @@
+package infrastructure
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/orkungursel/hey-taxi-location-api/internal/domain/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// setupTestTracing records the spans of the global tracer provider
+func setupTestTracing(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+
+	return recorder
+}
+
+func TestTracedLocationRepository(t *testing.T) {
+	recorder := setupTestTracing(t)
+
+	repo, db := SetupLocationRepositoryMocks()
+	db.AddHook(NewRedisTracingHook())
+	r := NewTracedLocationRepository(repo)
+
+	ctx := context.Background()
+	if err := r.Save(ctx, model.Location{VehicleId: "v1", Lat: 10, Lng: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Search(ctx, 10, 10, 1, "km", 10); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	names := make(map[string]sdktrace.ReadOnlySpan)
+	for _, s := range spans {
+		names[s.Name()] = s
+	}
+
+	for parent, child := range map[string]string{
+		"LocationRepository.Save":   "redis GEOADD",
+		"LocationRepository.Search": "redis GEORADIUS_RO",
+	} {
+		p, ok := names[parent]
+		if !ok {
+			t.Fatalf("span %s is not recorded, got %d spans", parent, len(spans))
+		}
+		c, ok := names[child]
+		if !ok {
+			t.Fatalf("span %s is not recorded", child)
+		}
+		if c.Parent().SpanID() != p.SpanContext().SpanID() {
+			t.Errorf("span %s is not a child of %s", child, parent)
+		}
+	}
+}
+
+func TestVehicleServiceTracingInterceptor(t *testing.T) {
+	recorder := setupTestTracing(t)
+
+	interceptor := NewVehicleServiceTracingInterceptor()
+
+	var got metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		got, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), tenantMetadataKey, "acme")
+	if err := interceptor(ctx, "/vehicle.VehicleService/GetVehicle", nil, nil, nil, invoker); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Name() != "vehicle.VehicleService/GetVehicle" {
+		t.Fatalf("spans = %v, want the client span", spans)
+	}
+
+	if len(got.Get("traceparent")) != 1 {
+		t.Errorf("trace context is not propagated, metadata = %v", got)
+	}
+	if v := got.Get(tenantMetadataKey); len(v) != 1 || v[0] != "acme" {
+		t.Errorf("metadata of the call is lost, metadata = %v", got)
+	}
+}
+
+func TestRedisTracingHook_Nil(t *testing.T) {
+	recorder := setupTestTracing(t)
+
+	_, db := SetupLocationRepositoryMocks()
+	db.AddHook(NewRedisTracingHook())
+
+	if err := db.Get(context.Background(), "missing").Err(); err != redis.Nil {
+		t.Fatalf("Get() error = %v, want redis.Nil", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Status().Code != codes.Unset {
+		t.Errorf("missing key should not fail the span, spans = %v", spans)
+	}
+}
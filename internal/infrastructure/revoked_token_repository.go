@@ -0,0 +1,58 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger"
+)
+
+const (
+	revokedTokenDbKey = "revoked-token" // revokedTokenDbKey is the prefix of the keys of the revoked token ids
+)
+
+// RevokedTokenRepository reads the revoked access tokens from the
+// "revoked-token:<jti>" keys, which expire together with the tokens
+type RevokedTokenRepository struct {
+	db     redis.UniversalClient
+	logger logger.ILogger
+	dbKey  string
+}
+
+func NewRevokedTokenRepository(db redis.UniversalClient, logger logger.ILogger) *RevokedTokenRepository {
+	return &RevokedTokenRepository{
+		db:     db,
+		logger: logger,
+		dbKey:  revokedTokenDbKey,
+	}
+}
+
+// IsRevoked returns true if the token id is revoked
+func (r *RevokedTokenRepository) IsRevoked(ctx context.Context, tokenId string) (bool, error) {
+	if tokenId == "" {
+		return false, errors.New("tokenId is empty")
+	}
+
+	n, err := r.db.Exists(ctx, r.dbKey+":"+tokenId).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+// Revoke revokes the token id until the token expires
+func (r *RevokedTokenRepository) Revoke(ctx context.Context, tokenId string, expiresAt time.Time) error {
+	if tokenId == "" {
+		return errors.New("tokenId is empty")
+	}
+
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+
+	return r.db.Set(ctx, r.dbKey+":"+tokenId, 1, ttl).Err()
+}
@@ -0,0 +1,70 @@
+package infrastructure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger/mock"
+)
+
+func TestRevokedTokenRepository(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	db := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer db.Close()
+
+	ctx := context.Background()
+	repo := NewRevokedTokenRepository(db, mock.NewLoggerMock())
+
+	if err := repo.Revoke(ctx, "jti1", time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Revoke(ctx, "jti2", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Revoke(ctx, "", time.Now().Add(time.Minute)); err == nil {
+		t.Errorf("Revoke() should fail with empty token id")
+	}
+
+	// the identity service writes the revocations
+	_ = mr.Set(revokedTokenDbKey+":jti3", "1")
+
+	tests := []struct {
+		name    string
+		tokenId string
+		want    bool
+		wantErr bool
+	}{
+		{name: "should return true for revoked token", tokenId: "jti1", want: true},
+		{name: "should return false for already expired token", tokenId: "jti2", want: false},
+		{name: "should return true for token revoked by another service", tokenId: "jti3", want: true},
+		{name: "should return false for unknown token", tokenId: "jti4", want: false},
+		{name: "should fail with empty token id", tokenId: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.IsRevoked(ctx, tt.tokenId)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("IsRevoked() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("IsRevoked() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("should forget the revocation once the token expired", func(t *testing.T) {
+		mr.FastForward(2 * time.Minute)
+
+		if got, _ := repo.IsRevoked(ctx, "jti1"); got {
+			t.Errorf("IsRevoked() = %v, want false", got)
+		}
+	})
+}
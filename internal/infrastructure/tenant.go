@@ -0,0 +1,28 @@
+package infrastructure
+
+import (
+	"context"
+	"strings"
+
+	"github.com/orkungursel/hey-taxi-location-api/internal/app"
+)
+
+const (
+	tenantKeyPrefix = "tenant" // tenantKeyPrefix namespaces the keys of the tenants
+)
+
+// tenantKey namespaces the key by the tenant of the context, the keys of the
+// default tenant are left as they are unless they start with the prefix. Those
+// are put in the "tenant::" namespace, which no tenant name can produce, so a
+// default tenant id like "tenant:acme:v1" does not reach the keys of acme.
+func tenantKey(ctx context.Context, key string) string {
+	if t := app.TenantFromContext(ctx); t != "" {
+		return tenantKeyPrefix + ":" + t + ":" + key
+	}
+
+	if strings.HasPrefix(key, tenantKeyPrefix+":") {
+		return tenantKeyPrefix + "::" + key
+	}
+
+	return key
+}
@@ -0,0 +1,128 @@
+package infrastructure
+
+import (
+	"context"
+	"testing"
+
+	"github.com/orkungursel/hey-taxi-location-api/internal/app"
+	"github.com/orkungursel/hey-taxi-location-api/internal/domain/model"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger/mock"
+)
+
+func TestTenantKey(t *testing.T) {
+	if got := tenantKey(context.Background(), "drivers"); got != "drivers" {
+		t.Errorf("tenantKey() = %v, want %v", got, "drivers")
+	}
+
+	ctx := app.WithTenant(context.Background(), "acme")
+	if got := tenantKey(ctx, "drivers"); got != "tenant:acme:drivers" {
+		t.Errorf("tenantKey() = %v, want %v", got, "tenant:acme:drivers")
+	}
+
+	// a default tenant key which looks namespaced must not reach the keys of the tenant
+	if got := tenantKey(context.Background(), "tenant:acme:drivers"); got != "tenant::tenant:acme:drivers" {
+		t.Errorf("tenantKey() = %v, want %v", got, "tenant::tenant:acme:drivers")
+	}
+}
+
+func TestLocationRepositories_TenantIsolation(t *testing.T) {
+	t.Parallel()
+
+	redisRepo, db := SetupLocationRepositoryMocks()
+	shardedRepo, _ := NewShardedLocationRepository(db, mock.NewLoggerMock(), 3, []string{"s00=null-island"})
+
+	repos := map[string]app.LocationRepository{
+		"redis":   redisRepo,
+		"sharded": shardedRepo,
+		"memory":  NewMemoryLocationRepository(mock.NewLoggerMock()),
+	}
+
+	acme := app.WithTenant(context.Background(), "acme")
+	globex := app.WithTenant(context.Background(), "globex")
+
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			// the same vehicle id is used by both tenants on purpose
+			_ = repo.Save(acme, model.Location{VehicleId: "vehicle", Lat: 1.0, Lng: 1.0})
+			_ = repo.Save(acme, model.Location{VehicleId: "acme-only", Lat: 1.0, Lng: 1.001})
+			_ = repo.Save(globex, model.Location{VehicleId: "vehicle", Lat: 0.5, Lng: 0.5})
+
+			tests := []struct {
+				name string
+				ctx  context.Context
+				lat  float64
+				lng  float64
+				want []string
+			}{
+				{name: "acme sees only its vehicles", ctx: acme, lat: 1.0, lng: 1.0, want: []string{"vehicle", "acme-only"}},
+				{name: "globex does not see acme vehicles", ctx: globex, lat: 1.0, lng: 1.0, want: []string{}},
+				{name: "globex sees its own location of the vehicle", ctx: globex, lat: 0.5, lng: 0.5, want: []string{"vehicle"}},
+				{name: "default tenant sees nothing", ctx: context.Background(), lat: 1.0, lng: 1.0, want: []string{}},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					got, err := repo.Search(tt.ctx, tt.lat, tt.lng, 10, "km", 0)
+					if err != nil {
+						t.Fatalf("Search() error = %v", err)
+					}
+					if len(got) != len(tt.want) {
+						t.Fatalf("Search() = %v, want %v", got, tt.want)
+					}
+					for i, id := range tt.want {
+						if got[i].VehicleId != id {
+							t.Errorf("Search()[%d] = %v, want %v", i, got[i].VehicleId, id)
+						}
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestVehicleRepositories_TenantIsolation(t *testing.T) {
+	redisRepo, _ := SetupVehicleRepositoryMocks()
+
+	repos := map[string]app.VehicleRepository{
+		"redis":  redisRepo,
+		"memory": NewMemoryVehicleRepository(mock.NewLoggerMock()),
+	}
+
+	acme := app.WithTenant(context.Background(), "acme")
+	globex := app.WithTenant(context.Background(), "globex")
+
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			if err := repo.Save(acme, &model.Vehicle{Id: "vehicle", Plate: "acme"}); err != nil {
+				t.Fatal(err)
+			}
+
+			if v, err := repo.Get(globex, "vehicle"); err == nil {
+				t.Errorf("Get() leaked vehicle of other tenant: %v", v)
+			}
+
+			if err := repo.Save(globex, &model.Vehicle{Id: "vehicle", Plate: "globex"}); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := repo.Delete(globex, "vehicle"); err != nil {
+				t.Fatal(err)
+			}
+
+			v, err := repo.Get(acme, "vehicle")
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if v.Plate != "acme" {
+				t.Errorf("Get() = %v, want plate acme", v)
+			}
+
+			// the default tenant can not reach the vehicle by a namespaced id
+			if err := repo.Save(context.Background(), &model.Vehicle{Id: "tenant:acme:vehicle", Plate: "default"}); err != nil {
+				t.Fatal(err)
+			}
+			if v, err := repo.Get(acme, "vehicle"); err != nil || v.Plate != "acme" {
+				t.Errorf("Get() = %v, %v, want plate acme", v, err)
+			}
+		})
+	}
+}
@@ -1,12 +1,53 @@
 package infrastructure
 
-import "github.com/golang-jwt/jwt"
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+)
 
 type Claims struct {
-	Role string `json:"role,omitempty"`
+	Role     string        `json:"role,omitempty"`
+	Tenant   string        `json:"tenant,omitempty"`
+	Scope    string        `json:"scope,omitempty"` // space separated scopes
+	Audience ClaimAudience `json:"aud,omitempty"`   // shadows the single string aud of the standard claims
 	jwt.StandardClaims
 }
 
+// ClaimAudience is the aud claim, which is either a single string or an array of strings
+type ClaimAudience []string
+
+func (a *ClaimAudience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = nil
+		if s != "" {
+			*a = ClaimAudience{s}
+		}
+		return nil
+	}
+
+	var l []string
+	if err := json.Unmarshal(b, &l); err != nil {
+		return err
+	}
+	*a = l
+
+	return nil
+}
+
+// Contains reports whether aud is one of the audiences
+func (a ClaimAudience) Contains(aud string) bool {
+	for _, v := range a {
+		if v == aud {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (c *Claims) GetSubject() string {
 	return c.Subject
 }
@@ -19,10 +60,18 @@ func (c *Claims) GetIssuer() string {
 	return c.StandardClaims.Issuer
 }
 
-func (c *Claims) GetAudience() string {
-	return c.StandardClaims.Audience
+func (c *Claims) GetTenant() string {
+	return c.Tenant
+}
+
+func (c *Claims) GetAudience() []string {
+	return c.Audience
 }
 
 func (c *Claims) GetTokenId() string {
 	return c.StandardClaims.Id
 }
+
+func (c *Claims) GetScopes() []string {
+	return strings.Fields(c.Scope)
+}
@@ -0,0 +1,35 @@
+package infrastructure
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestClaims_UnmarshalAudience(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "should decode single audience", json: `{"aud":"location-api"}`, want: []string{"location-api"}},
+		{name: "should decode audience list", json: `{"aud":["hey-taxi","location-api"]}`,
+			want: []string{"hey-taxi", "location-api"}},
+		{name: "should decode empty audience", json: `{"aud":""}`},
+		{name: "should decode missing audience", json: `{}`},
+		{name: "should reject invalid audience", json: `{"aud":1}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c Claims
+			err := json.Unmarshal([]byte(tt.json), &c)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("json.Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := c.GetAudience(); !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Claims.GetAudience() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,364 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/orkungursel/hey-taxi-location-api/internal/app"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// tokenKeysMinRefresh limits the reloads triggered by the tokens signed with an unknown key
+	tokenKeysMinRefresh = 10 * time.Second
+	jwksRequestTimeout  = 5 * time.Second // also bounds a load shared by the concurrent callers
+)
+
+// ErrUnknownTokenKey is returned when no key has the key id of the token
+var ErrUnknownTokenKey = app.ErrTokenUnknownKey
+
+// tokenKeysLoader loads the public keys by their key ids
+type tokenKeysLoader func(ctx context.Context) (map[string]interface{}, error)
+
+// TokenKeys caches the public keys of the access tokens by their key ids. The
+// keys are reloaded every refresh interval, and earlier if a token refers to
+// an unknown key, so the signing key can be rotated without a redeploy. The
+// loads are shared by the concurrent callers and do not block the lookups.
+type TokenKeys struct {
+	mu      sync.RWMutex
+	keys    map[string]interface{}
+	loaded  time.Time
+	group   singleflight.Group
+	refresh time.Duration
+	load    tokenKeysLoader
+	logger  logger.ILogger
+	now     func() time.Time
+}
+
+func newTokenKeys(load tokenKeysLoader, refresh time.Duration, logger logger.ILogger) *TokenKeys {
+	return &TokenKeys{
+		refresh: refresh,
+		load:    load,
+		logger:  logger,
+		now:     time.Now,
+	}
+}
+
+// NewPemTokenKeys returns the keys of a PEM file, or of the PEM files in a
+// directory which are named by their key ids, e.g. 2022-03.pem. A single file
+// is used whatever the key id of the token is.
+func NewPemTokenKeys(path string, refresh time.Duration, logger logger.ILogger) (*TokenKeys, error) {
+	k := newTokenKeys(func(_ context.Context) (map[string]interface{}, error) {
+		return loadPemKeys(path)
+	}, refresh, logger)
+
+	if err := k.reload(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+// NewJwksTokenKeys returns the keys served by a JWKS endpoint, they are
+// fetched on the first use
+func NewJwksTokenKeys(url string, client *http.Client, refresh time.Duration, logger logger.ILogger) *TokenKeys {
+	if client == nil {
+		client = &http.Client{Timeout: jwksRequestTimeout}
+	}
+
+	return newTokenKeys(func(ctx context.Context) (map[string]interface{}, error) {
+		return fetchJwks(ctx, client, url)
+	}, refresh, logger)
+}
+
+// Key returns the public key of the key id. Expired keys are served while
+// they are reloaded in the background.
+func (k *TokenKeys) Key(ctx context.Context, kid string) (interface{}, error) {
+	keys, loaded := k.current()
+
+	if keys == nil {
+		// a failing source is retried at most every tokenKeysMinRefresh
+		if loaded.IsZero() || k.now().Sub(loaded) >= tokenKeysMinRefresh {
+			if err := k.reload(ctx); err != nil {
+				return nil, err
+			}
+			keys, loaded = k.current()
+		}
+	} else if k.refresh > 0 && k.now().Sub(loaded) >= k.refresh {
+		k.startReload()
+	}
+
+	if key, ok := lookupKey(keys, kid); ok {
+		return key, nil
+	}
+
+	// the key may have been rotated since the last load
+	if k.now().Sub(loaded) >= tokenKeysMinRefresh {
+		if err := k.reload(ctx); err == nil {
+			keys, _ = k.current()
+			if key, ok := lookupKey(keys, kid); ok {
+				return key, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnknownTokenKey, kid)
+}
+
+// Check returns an error if no key is loaded, the keys are loaded if they were
+// never tried or the last failure is older than tokenKeysMinRefresh
+func (k *TokenKeys) Check(ctx context.Context) error {
+	keys, loaded := k.current()
+	if keys != nil {
+		return nil
+	}
+
+	if loaded.IsZero() || k.now().Sub(loaded) >= tokenKeysMinRefresh {
+		return k.reload(ctx)
+	}
+
+	return errors.New("no token key is loaded")
+}
+
+// current returns the loaded keys and the time of the last load
+func (k *TokenKeys) current() (map[string]interface{}, time.Time) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.keys, k.loaded
+}
+
+func lookupKey(keys map[string]interface{}, kid string) (interface{}, bool) {
+	if key, ok := keys[kid]; ok {
+		return key, true
+	}
+
+	// a single key without an id, or a token without a key id, uses the only key
+	if len(keys) == 1 {
+		for id, key := range keys {
+			if id == "" || kid == "" {
+				return key, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// reload waits for the load of the keys until ctx is done
+func (k *TokenKeys) reload(ctx context.Context) error {
+	select {
+	case res := <-k.startReload():
+		return res.Err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// startReload loads the keys unless a load is already in flight, the previous
+// keys are kept on failure. The load time is updated either way, so a failing
+// source is not retried on every token.
+func (k *TokenKeys) startReload() <-chan singleflight.Result {
+	return k.group.DoChan("keys", func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), jwksRequestTimeout)
+		defer cancel()
+
+		keys, err := k.load(ctx)
+		if err == nil && len(keys) == 0 {
+			err = errors.New("no token key found")
+		}
+
+		k.mu.Lock()
+		defer k.mu.Unlock()
+
+		k.loaded = k.now()
+		if err != nil {
+			if k.keys != nil {
+				k.logger.Warnf("failed to reload token keys, keeping the previous ones: %v", err)
+			}
+			return nil, err
+		}
+
+		k.keys = keys
+		k.logger.Debugf("loaded %d token keys", len(keys))
+
+		return nil, nil
+	})
+}
+
+func loadPemKeys(path string) (map[string]interface{}, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		key, err := loadPemKey(path)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"": key}, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(path, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]interface{}, len(files))
+	for _, file := range files {
+		key, err := loadPemKey(file)
+		if err != nil {
+			return nil, err
+		}
+		keys[strings.TrimSuffix(filepath.Base(file), ".pem")] = key
+	}
+
+	return keys, nil
+}
+
+// loadPemKey loads the RSA or ECDSA public key of the file
+func loadPemKey(file string) (interface{}, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(b); err == nil {
+		return key, nil
+	}
+
+	key, err := jwt.ParseECPublicKeyFromPEM(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	return key, nil
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func fetchJwks(ctx context.Context, client *http.Client, url string) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected jwks response status: %s", res.Status)
+	}
+
+	set := &jwks{}
+	if err := json.NewDecoder(res.Body).Decode(set); err != nil {
+		return nil, fmt.Errorf("failed to decode jwks: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+
+		switch key.Kty {
+		case "RSA":
+			pub, err := parseRSAJwk(key)
+			if err != nil {
+				return nil, fmt.Errorf("invalid jwk %q: %w", key.Kid, err)
+			}
+			keys[key.Kid] = pub
+		case "EC":
+			pub, err := parseECJwk(key)
+			if err != nil {
+				return nil, fmt.Errorf("invalid jwk %q: %w", key.Kid, err)
+			}
+			keys[key.Kid] = pub
+		}
+	}
+
+	return keys, nil
+}
+
+func parseRSAJwk(key jwk) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(key.N)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := base64.RawURLEncoding.DecodeString(key.E)
+	if err != nil {
+		return nil, err
+	}
+
+	exp := new(big.Int).SetBytes(e)
+	if len(n) == 0 || !exp.IsInt64() || exp.Int64() < 2 || exp.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid rsa key")
+	}
+
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+}
+
+func parseECJwk(key jwk) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch key.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", key.Crv)
+	}
+
+	x, err := base64.RawURLEncoding.DecodeString(key.X)
+	if err != nil {
+		return nil, err
+	}
+
+	y, err := base64.RawURLEncoding.DecodeString(key.Y)
+	if err != nil {
+		return nil, err
+	}
+
+	pub := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	if !curve.IsOnCurve(pub.X, pub.Y) {
+		return nil, errors.New("invalid ec key")
+	}
+
+	return pub, nil
+}
@@ -0,0 +1,393 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/orkungursel/hey-taxi-location-api/config"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger/mock"
+)
+
+func generateTestRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+func writeTestPublicKey(t *testing.T, file string, pub interface{}) {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func signTestToken(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.Claims) string {
+	t.Helper()
+
+	return signTestTokenWithMethod(t, jwt.SigningMethodRS256, key, kid, claims)
+}
+
+func signTestTokenWithMethod(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.Claims) string {
+	t.Helper()
+
+	tkn := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		tkn.Header["kid"] = kid
+	}
+
+	s, err := tkn.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+// jwksTestServer serves the public keys of the private keys by their key ids
+type jwksTestServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	keys     map[string]*rsa.PrivateKey
+	fail     bool
+	requests int32
+}
+
+func newJwksTestServer(t *testing.T, keys map[string]*rsa.PrivateKey) *jwksTestServer {
+	s := &jwksTestServer{keys: keys}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.requests, 1)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		set := jwks{}
+		for kid, key := range s.keys {
+			set.Keys = append(set.Keys, jwk{
+				Kid: kid,
+				Kty: "RSA",
+				Use: "sig",
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		set.Keys = append(set.Keys, jwk{Kid: "enc", Kty: "RSA", Use: "enc"}, jwk{Kid: "oct", Kty: "oct"})
+
+		_ = json.NewEncoder(w).Encode(set)
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *jwksTestServer) setKeys(keys map[string]*rsa.PrivateKey, fail bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys = keys
+	s.fail = fail
+}
+
+func TestTokenKeys_Jwks(t *testing.T) {
+	k1, k2 := generateTestRSAKey(t), generateTestRSAKey(t)
+	srv := newJwksTestServer(t, map[string]*rsa.PrivateKey{"k1": k1})
+
+	now := time.Now()
+	keys := NewJwksTokenKeys(srv.URL, srv.Client(), time.Minute, mock.NewLoggerMock())
+	keys.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	expectKey := func(kid string, want *rsa.PrivateKey, wantRequests int32) {
+		t.Helper()
+
+		got, err := keys.Key(ctx, kid)
+		if want == nil {
+			if !errors.Is(err, ErrUnknownTokenKey) {
+				t.Errorf("TokenKeys.Key(%q) error = %v, want %v", kid, err, ErrUnknownTokenKey)
+			}
+		} else if err != nil {
+			t.Errorf("TokenKeys.Key(%q) error = %v", kid, err)
+		} else if !want.PublicKey.Equal(got) {
+			t.Errorf("TokenKeys.Key(%q) returned another key", kid)
+		}
+
+		if r := atomic.LoadInt32(&srv.requests); wantRequests >= 0 && r != wantRequests {
+			t.Errorf("TokenKeys.Key(%q) jwks requests = %d, want %d", kid, r, wantRequests)
+		}
+	}
+
+	// fetched on the first use, then served from the cache
+	expectKey("k1", k1, 1)
+	expectKey("k1", k1, 1)
+	expectKey("", k1, 1)
+
+	// unknown keys do not reach the endpoint more than once every tokenKeysMinRefresh
+	expectKey("k2", nil, 1)
+
+	// the rotated key is fetched as soon as a token refers to it
+	srv.setKeys(map[string]*rsa.PrivateKey{"k1": k1, "k2": k2}, false)
+	now = now.Add(tokenKeysMinRefresh)
+	expectKey("k2", k2, 2)
+	expectKey("", nil, 2)
+
+	// the expired keys are served while they are reloaded, and kept while the endpoint fails
+	srv.setKeys(nil, true)
+	now = now.Add(time.Minute)
+	expectKey("k2", k2, -1)
+	waitForTokenKeysReload(t, keys, now)
+	expectKey("k1", k1, 3)
+
+	// the retired keys are dropped on refresh
+	srv.setKeys(map[string]*rsa.PrivateKey{"k2": k2}, false)
+	now = now.Add(time.Minute)
+	expectKey("k2", k2, -1)
+	waitForTokenKeysReload(t, keys, now)
+	expectKey("k1", nil, 4)
+}
+
+// waitForTokenKeysReload waits for the background reload started at now
+func waitForTokenKeysReload(t *testing.T, keys *TokenKeys, now time.Time) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, loaded := keys.current(); !loaded.Before(now) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("token keys are not reloaded")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestTokenKeys_Concurrent(t *testing.T) {
+	k1 := generateTestRSAKey(t)
+	release := make(chan struct{})
+	var loads int32
+
+	keys := newTokenKeys(func(context.Context) (map[string]interface{}, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return map[string]interface{}{"k1": &k1.PublicKey}, nil
+	}, time.Minute, mock.NewLoggerMock())
+
+	// a caller gives up on the load without stopping it for the others
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := keys.Key(canceled, "k1"); !errors.Is(err, context.Canceled) {
+		t.Errorf("TokenKeys.Key() error = %v, want %v", err, context.Canceled)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got, err := keys.Key(context.Background(), "k1"); err != nil || !k1.PublicKey.Equal(got) {
+				t.Errorf("TokenKeys.Key(k1) = %v, %v", got, err)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&loads); n != 1 {
+		t.Errorf("token keys are loaded %d times, want 1", n)
+	}
+}
+
+func TestTokenKeys_Jwks_Unavailable(t *testing.T) {
+	srv := newJwksTestServer(t, nil)
+	srv.setKeys(nil, true)
+
+	keys := NewJwksTokenKeys(srv.URL, srv.Client(), time.Minute, mock.NewLoggerMock())
+
+	if _, err := keys.Key(context.Background(), "k1"); err == nil {
+		t.Errorf("TokenKeys.Key() should fail while the endpoint is not available")
+	}
+}
+
+func TestTokenKeys_Check(t *testing.T) {
+	srv := newJwksTestServer(t, nil)
+	srv.setKeys(nil, true)
+
+	now := time.Now()
+	keys := NewJwksTokenKeys(srv.URL, srv.Client(), time.Minute, mock.NewLoggerMock())
+	keys.now = func() time.Time { return now }
+
+	if err := keys.Check(context.Background()); err == nil {
+		t.Fatal("TokenKeys.Check() should fail while the endpoint is not available")
+	}
+
+	srv.setKeys(map[string]*rsa.PrivateKey{"k1": generateTestRSAKey(t)}, false)
+
+	// the failed load is not retried before tokenKeysMinRefresh
+	if err := keys.Check(context.Background()); err == nil {
+		t.Error("TokenKeys.Check() should not retry right after a failure")
+	}
+
+	now = now.Add(tokenKeysMinRefresh)
+	if err := keys.Check(context.Background()); err != nil {
+		t.Errorf("TokenKeys.Check() error = %v", err)
+	}
+	if n := atomic.LoadInt32(&srv.requests); n != 2 {
+		t.Errorf("jwks endpoint is requested %d times, want 2", n)
+	}
+}
+
+func TestTokenKeys_Jwks_EC(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(jwks{Keys: []jwk{{
+			Kid: "ec",
+			Kty: "EC",
+			Crv: "P-256",
+			X:   base64.RawURLEncoding.EncodeToString(key.X.Bytes()),
+			Y:   base64.RawURLEncoding.EncodeToString(key.Y.Bytes()),
+		}}})
+	}))
+	defer srv.Close()
+
+	keys := NewJwksTokenKeys(srv.URL, srv.Client(), time.Minute, mock.NewLoggerMock())
+	if got, err := keys.Key(context.Background(), "ec"); err != nil || !key.PublicKey.Equal(got) {
+		t.Errorf("TokenKeys.Key(ec) = %v, %v", got, err)
+	}
+}
+
+func TestTokenKeys_Pem(t *testing.T) {
+	dir := t.TempDir()
+	k1, k2 := generateTestRSAKey(t), generateTestRSAKey(t)
+	writeTestPublicKey(t, filepath.Join(dir, "k1.pem"), &k1.PublicKey)
+
+	t.Run("should use a single file whatever the key id is", func(t *testing.T) {
+		keys, err := NewPemTokenKeys(filepath.Join(dir, "k1.pem"), 0, mock.NewLoggerMock())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, kid := range []string{"", "k1", "other"} {
+			if got, err := keys.Key(context.Background(), kid); err != nil || !k1.PublicKey.Equal(got) {
+				t.Errorf("TokenKeys.Key(%q) = %v, %v", kid, got, err)
+			}
+		}
+	})
+
+	t.Run("should fail with a missing file", func(t *testing.T) {
+		if _, err := NewPemTokenKeys(filepath.Join(dir, "missing.pem"), 0, mock.NewLoggerMock()); err == nil {
+			t.Errorf("NewPemTokenKeys() should fail")
+		}
+	})
+
+	t.Run("should reload the keys of a directory", func(t *testing.T) {
+		keys, err := NewPemTokenKeys(dir, time.Minute, mock.NewLoggerMock())
+		if err != nil {
+			t.Fatal(err)
+		}
+		now := time.Now()
+		keys.now = func() time.Time { return now }
+		ctx := context.Background()
+
+		if got, err := keys.Key(ctx, "k1"); err != nil || !k1.PublicKey.Equal(got) {
+			t.Errorf("TokenKeys.Key(k1) = %v, %v", got, err)
+		}
+
+		writeTestPublicKey(t, filepath.Join(dir, "k2.pem"), &k2.PublicKey)
+		if _, err := keys.Key(ctx, "k2"); !errors.Is(err, ErrUnknownTokenKey) {
+			t.Errorf("TokenKeys.Key(k2) error = %v, want %v", err, ErrUnknownTokenKey)
+		}
+
+		now = now.Add(tokenKeysMinRefresh)
+		if got, err := keys.Key(ctx, "k2"); err != nil || !k2.PublicKey.Equal(got) {
+			t.Errorf("TokenKeys.Key(k2) = %v, %v", got, err)
+		}
+
+		// an invalid file does not drop the loaded keys
+		if err := os.WriteFile(filepath.Join(dir, "k3.pem"), []byte("invalid"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(time.Minute)
+		if got, err := keys.Key(ctx, "k1"); err != nil || !k1.PublicKey.Equal(got) {
+			t.Errorf("TokenKeys.Key(k1) = %v, %v", got, err)
+		}
+	})
+}
+
+func TestTokenService_ParseToken_Jwks(t *testing.T) {
+	k1, k2 := generateTestRSAKey(t), generateTestRSAKey(t)
+	srv := newJwksTestServer(t, map[string]*rsa.PrivateKey{"k1": k1, "k2": k2})
+
+	t.Setenv("JWT_JWKS_URL", srv.URL)
+	t.Setenv("JWT_ISSUER", issuer)
+	ts := NewTokenService(config.New(), mock.NewLoggerMock(), nil)
+
+	claims := &Claims{
+		Role: "user",
+		StandardClaims: jwt.StandardClaims{
+			Subject:   "6239382950b3186db8dd3141",
+			Issuer:    issuer,
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		},
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "should validate the token signed by the first key", token: signTestToken(t, k1, "k1", claims)},
+		{name: "should validate the token signed by the second key", token: signTestToken(t, k2, "k2", claims)},
+		{name: "should fail with the key of another key id", token: signTestToken(t, k1, "k2", claims), wantErr: true},
+		{name: "should fail with an unknown key id", token: signTestToken(t, k1, "k3", claims), wantErr: true},
+		{name: "should fail without key id while there are several keys", token: signTestToken(t, k1, "", claims), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ts.ParseToken(context.Background(), tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TokenService.ParseToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.GetSubject() != claims.Subject {
+				t.Errorf("TokenService.ParseToken() subject = %v, want %v", got.GetSubject(), claims.Subject)
+			}
+		})
+	}
+}
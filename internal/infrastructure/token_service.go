@@ -2,9 +2,12 @@ package infrastructure
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"crypto/rsa"
-	"io/ioutil"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/golang-jwt/jwt"
 	"github.com/orkungursel/hey-taxi-location-api/config"
@@ -14,15 +17,26 @@ import (
 )
 
 type TokenService struct {
-	config               *config.Config
-	logger               logger.ILogger
-	accessTokenPublicKey *rsa.PublicKey
+	config     *config.Config
+	logger     logger.ILogger
+	keys       *TokenKeys
+	algorithms map[string]bool
+	audience   []string
+	leeway     time.Duration
+	revoked    app.RevokedTokenRepository
+	now        func() time.Time
 }
 
-func NewTokenService(config *config.Config, logger logger.ILogger) (s *TokenService) {
+// NewTokenService returns the token service, the revoked tokens are not
+// checked if revoked is nil
+func NewTokenService(config *config.Config, logger logger.ILogger,
+	revoked app.RevokedTokenRepository) (s *TokenService) {
+
 	s = &TokenService{
-		config: config,
-		logger: logger,
+		config:  config,
+		logger:  logger,
+		revoked: revoked,
+		now:     time.Now,
 	}
 
 	s.init()
@@ -31,32 +45,55 @@ func NewTokenService(config *config.Config, logger logger.ILogger) (s *TokenServ
 }
 
 func (s *TokenService) init() *TokenService {
-	// get public key from file
-	b, err := ioutil.ReadFile(s.config.Jwt.AccessTokenPublicKeyFile)
-	if err != nil {
-		panic(err)
+	s.algorithms = make(map[string]bool, len(s.config.Jwt.Algorithms))
+	for _, alg := range s.config.Jwt.Algorithms {
+		// only the asymmetric algorithms, the keys are public
+		switch jwt.GetSigningMethod(alg).(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS, *jwt.SigningMethodECDSA:
+			s.algorithms[alg] = true
+		default:
+			panic(fmt.Sprintf("unsupported jwt algorithm: %s", alg))
+		}
+	}
+
+	for _, aud := range s.config.Jwt.Audience {
+		if aud != "" {
+			s.audience = append(s.audience, aud)
+		}
 	}
 
-	atpuk, err := jwt.ParseRSAPublicKeyFromPEM(b)
+	s.leeway = time.Duration(s.config.Jwt.Leeway) * time.Second
+
+	refresh := time.Duration(s.config.Jwt.KeysRefresh) * time.Second
+
+	// get public keys from the jwks endpoint, or from the file
+	if s.config.Jwt.JwksUrl != "" {
+		s.keys = NewJwksTokenKeys(s.config.Jwt.JwksUrl, nil, refresh, s.logger)
+		return s
+	}
+
+	keys, err := NewPemTokenKeys(s.config.Jwt.AccessTokenPublicKeyFile, refresh, s.logger)
 	if err != nil {
 		panic(err)
 	}
 
-	s.accessTokenPublicKey = atpuk
+	s.keys = keys
 
 	return s
 }
 
-// ExtractToken extracts token from http request
+// CheckKeys returns an error if the public keys of the access tokens are not available
+func (t *TokenService) CheckKeys(ctx context.Context) error {
+	return t.keys.Check(ctx)
+}
+
+// ValidateAccessTokenFromRequest extracts the access token from http request and parses it
 func (t *TokenService) ValidateAccessTokenFromRequest(ctx context.Context, r *http.Request) (app.Claims, error) {
-	token := r.Header.Get("Authorization")
-	if token == "" {
-		return nil, errors.New("token is empty")
+	token, err := t.ExtractAccessToken(r)
+	if err != nil {
+		return nil, err
 	}
 
-	// remove bearer prefix
-	token = token[7:]
-
 	claims, err := t.ParseToken(ctx, token)
 	if err != nil {
 		return nil, err
@@ -65,32 +102,181 @@ func (t *TokenService) ValidateAccessTokenFromRequest(ctx context.Context, r *ht
 	return claims, nil
 }
 
-// ParseToken parses a token
+// ExtractAccessToken returns the bearer token of the Authorization header. The GET
+// requests without the header may pass the token by the query parameter or the cookie.
+func (t *TokenService) ExtractAccessToken(r *http.Request) (string, error) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		return parseBearerToken(header)
+	}
+
+	if r.Method != http.MethodGet {
+		return "", app.ErrTokenMissing
+	}
+
+	if name := t.config.Jwt.QueryParam; name != "" {
+		if token := r.URL.Query().Get(name); token != "" {
+			return token, nil
+		}
+	}
+
+	if name := t.config.Jwt.Cookie; name != "" {
+		if cookie, err := r.Cookie(name); err == nil && cookie.Value != "" {
+			return cookie.Value, nil
+		}
+	}
+
+	return "", app.ErrTokenMissing
+}
+
+// parseBearerToken returns the token of the "Bearer <token>" header, the scheme
+// is case insensitive
+func parseBearerToken(header string) (string, error) {
+	parts := strings.Fields(header)
+	if len(parts) == 0 {
+		return "", app.ErrTokenMissing
+	}
+
+	if !strings.EqualFold(parts[0], "Bearer") {
+		return "", app.ErrTokenScheme
+	}
+
+	if len(parts) != 2 {
+		return "", app.ErrTokenMalformed
+	}
+
+	return parts[1], nil
+}
+
+// ParseToken parses a token, the returned error is one of the app.ErrToken
+// errors which tell why the token is rejected
 func (t *TokenService) ParseToken(ctx context.Context, token string) (app.Claims, error) {
 	claims := &Claims{}
 
-	tkn, err := jwt.ParseWithClaims(token, claims, t.provideAccessTokenPublicKey)
-
+	// the time based claims are verified below with the leeway
+	parser := &jwt.Parser{SkipClaimsValidation: true}
+	_, err := parser.ParseWithClaims(token, claims, func(tkn *jwt.Token) (interface{}, error) {
+		return t.provideAccessTokenPublicKey(ctx, tkn)
+	})
 	if err != nil {
-		return nil, err
+		return nil, t.mapTokenError(err)
 	}
 
-	if tkn.Method.Alg() != jwt.SigningMethodRS256.Alg() {
-		return nil, errors.New("invalid algorithm")
+	now := t.now()
+	if !claims.VerifyExpiresAt(now.Add(-t.leeway).Unix(), true) {
+		return nil, app.ErrTokenExpired
 	}
 
-	if !tkn.Valid {
-		return nil, errors.New("token is invalid")
+	if !claims.VerifyNotBefore(now.Add(t.leeway).Unix(), false) ||
+		!claims.VerifyIssuedAt(now.Add(t.leeway).Unix(), false) {
+		return nil, app.ErrTokenNotYetValid
 	}
 
 	if !claims.VerifyIssuer(t.config.Jwt.Issuer, true) {
-		return nil, errors.New("invalid issuer")
+		return nil, app.ErrTokenIssuer
+	}
+
+	if !t.verifyAudience(claims) {
+		return nil, app.ErrTokenAudience
+	}
+
+	if err := t.verifyNotRevoked(ctx, claims); err != nil {
+		return nil, err
 	}
 
 	return claims, nil
 }
 
-// provideAccessTokenPublicKey provides access token public key to veriy token
-func (t *TokenService) provideAccessTokenPublicKey(_ *jwt.Token) (interface{}, error) {
-	return t.accessTokenPublicKey, nil
+func (t *TokenService) verifyAudience(claims *Claims) bool {
+	if len(t.audience) == 0 {
+		return true
+	}
+
+	for _, aud := range t.audience {
+		if claims.Audience.Contains(aud) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (t *TokenService) verifyNotRevoked(ctx context.Context, claims *Claims) error {
+	if t.revoked == nil || claims.GetTokenId() == "" {
+		return nil
+	}
+
+	revoked, err := t.revoked.IsRevoked(ctx, claims.GetTokenId())
+	if err != nil && t.config.Jwt.RevocationFailOpen {
+		t.logger.Warnf("failed to check token revocation, accepting token %s: %v", claims.GetTokenId(), err)
+		return nil
+	}
+	if err != nil {
+		t.logger.Errorf("failed to check token revocation, rejecting token %s: %v", claims.GetTokenId(), err)
+		return app.NewInternalServerError(fmt.Errorf("failed to check token revocation: %w", err))
+	}
+
+	if revoked {
+		return app.ErrTokenRevoked
+	}
+
+	return nil
+}
+
+// provideAccessTokenPublicKey provides the public key of the token's key id to verify token,
+// if the algorithm of the token is allowed and fits the key
+func (t *TokenService) provideAccessTokenPublicKey(ctx context.Context, tkn *jwt.Token) (interface{}, error) {
+	if !t.algorithms[tkn.Method.Alg()] {
+		return nil, app.ErrTokenAlgorithm
+	}
+
+	kid, _ := tkn.Header["kid"].(string)
+	key, err := t.keys.Key(ctx, kid)
+	if err != nil {
+		return nil, err
+	}
+
+	switch tkn.Method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		if _, ok := key.(*rsa.PublicKey); !ok {
+			return nil, app.ErrTokenAlgorithm
+		}
+	case *jwt.SigningMethodECDSA:
+		if _, ok := key.(*ecdsa.PublicKey); !ok {
+			return nil, app.ErrTokenAlgorithm
+		}
+	}
+
+	return key, nil
+}
+
+// mapTokenError maps the parse errors of the jwt package to the app.ErrToken errors
+func (t *TokenService) mapTokenError(err error) error {
+	var ae *app.Error
+	if errors.As(err, &ae) {
+		return ae
+	}
+
+	var ve *jwt.ValidationError
+	if !errors.As(err, &ve) {
+		return app.ErrTokenMalformed
+	}
+
+	if ve.Inner != nil && errors.As(ve.Inner, &ae) {
+		return ae
+	}
+
+	switch {
+	case ve.Errors&jwt.ValidationErrorMalformed != 0:
+		return app.ErrTokenMalformed
+	case ve.Errors&jwt.ValidationErrorSignatureInvalid != 0:
+		return app.ErrTokenSignature
+	case ve.Inner != nil:
+		t.logger.Warnf("failed to load the token keys: %v", ve.Inner)
+		return app.ErrTokenUnknownKey
+	case ve.Errors&jwt.ValidationErrorUnverifiable != 0:
+		// the algorithm is not known by the jwt package
+		return app.ErrTokenAlgorithm
+	}
+
+	return app.ErrTokenMalformed
 }
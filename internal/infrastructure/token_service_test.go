@@ -2,14 +2,23 @@ package infrastructure
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
 	"net/http"
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
 	"github.com/golang-jwt/jwt"
 	"github.com/orkungursel/hey-taxi-location-api/config"
+	"github.com/orkungursel/hey-taxi-location-api/internal/app"
 	"github.com/orkungursel/hey-taxi-location-api/internal/domain/model"
 	. "github.com/orkungursel/hey-taxi-location-api/pkg/logger/mock"
 )
@@ -35,9 +44,9 @@ func SetTokenServiceEnvForTesting(t *testing.T) {
 func TestNewTokenService(t *testing.T) {
 	SetTokenServiceEnvForTesting(t)
 
-	ts := NewTokenService(config.New(), NewLoggerMock())
+	ts := NewTokenService(config.New(), NewLoggerMock(), nil)
 
-	if ts.accessTokenPublicKey == nil {
+	if key, err := ts.keys.Key(context.Background(), ""); err != nil || key == nil {
 		t.Errorf("access token publicKey is empty")
 	}
 }
@@ -45,7 +54,7 @@ func TestNewTokenService(t *testing.T) {
 func TestTokenService_ValidateAccessTokenFromRequest(t *testing.T) {
 	SetTokenServiceEnvForTesting(t)
 
-	ts := NewTokenService(config.New(), NewLoggerMock())
+	ts := NewTokenService(config.New(), NewLoggerMock(), nil)
 
 	type args struct {
 		ctx context.Context
@@ -76,7 +85,7 @@ func TestTokenService_ValidateAccessTokenFromRequest(t *testing.T) {
 func TestTokenService_parseToken(t *testing.T) {
 	SetTokenServiceEnvForTesting(t)
 
-	ts := NewTokenService(config.New(), NewLoggerMock())
+	ts := NewTokenService(config.New(), NewLoggerMock(), nil)
 
 	u := &model.User{
 		Id: "6239382950b3186db8dd3141",
@@ -198,3 +207,210 @@ func TestTokenService_parseToken(t *testing.T) {
 		})
 	}
 }
+
+func TestTokenService_ParseToken_Validation(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey := generateTestRSAKey(t)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestPublicKey(t, filepath.Join(dir, "rsa.pem"), &rsaKey.PublicKey)
+	writeTestPublicKey(t, filepath.Join(dir, "ec.pem"), &ecKey.PublicKey)
+
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	revoked := NewRevokedTokenRepository(redis.NewClient(&redis.Options{Addr: mr.Addr()}), NewLoggerMock())
+	if err := revoked.Revoke(context.Background(), "revoked-jti", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("JWT_ACCESS_TOKEN_PUBLIC_KEY_FILE", dir)
+	t.Setenv("JWT_ISSUER", issuer)
+	t.Setenv("JWT_AUDIENCE", "location-api,hey-taxi")
+	t.Setenv("JWT_LEEWAY", "30")
+	t.Setenv("JWT_ALGORITHMS", "RS256,ES256")
+	ts := NewTokenService(config.New(), NewLoggerMock(), revoked)
+
+	now := time.Now()
+	claims := func(f func(c *Claims)) *Claims {
+		c := &Claims{
+			Role:     "user",
+			Audience: ClaimAudience{"location-api"},
+			StandardClaims: jwt.StandardClaims{
+				Subject:   "subject",
+				Issuer:    issuer,
+				ExpiresAt: now.Add(time.Hour).Unix(),
+				IssuedAt:  now.Unix(),
+			},
+		}
+		if f != nil {
+			f(c)
+		}
+		return c
+	}
+	rs256 := func(kid string, c *Claims) string {
+		return signTestTokenWithMethod(t, jwt.SigningMethodRS256, rsaKey, kid, c)
+	}
+	valid := rs256("rsa", claims(nil))
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "should accept RS256", token: valid},
+		{name: "should accept ES256", token: signTestTokenWithMethod(t, jwt.SigningMethodES256, ecKey, "ec", claims(nil))},
+		{name: "should accept another audience", token: rs256("rsa", claims(func(c *Claims) { c.Audience = ClaimAudience{"hey-taxi"} }))},
+		{name: "should accept audience list",
+			token: rs256("rsa", claims(func(c *Claims) { c.Audience = ClaimAudience{"other", "location-api"} }))},
+		{name: "should accept expired token within the leeway",
+			token: rs256("rsa", claims(func(c *Claims) { c.ExpiresAt = now.Add(-10 * time.Second).Unix() }))},
+		{name: "should accept not revoked token id", token: rs256("rsa", claims(func(c *Claims) { c.Id = "jti" }))},
+		{name: "should reject empty token", token: "", wantErr: app.ErrTokenMalformed},
+		{name: "should reject malformed token", token: "a.b.c", wantErr: app.ErrTokenMalformed},
+		{name: "should reject invalid signature", token: valid[:len(valid)-4] + "AAAA", wantErr: app.ErrTokenSignature},
+		{name: "should reject not allowed algorithm",
+			token:   signTestTokenWithMethod(t, jwt.SigningMethodRS512, rsaKey, "rsa", claims(nil)),
+			wantErr: app.ErrTokenAlgorithm},
+		{name: "should reject hmac signed by the public key",
+			token:   signTestTokenWithMethod(t, jwt.SigningMethodHS256, []byte("secret"), "rsa", claims(nil)),
+			wantErr: app.ErrTokenAlgorithm},
+		{name: "should reject algorithm which does not fit the key",
+			token:   signTestTokenWithMethod(t, jwt.SigningMethodES256, ecKey, "rsa", claims(nil)),
+			wantErr: app.ErrTokenAlgorithm},
+		{name: "should reject unknown key", token: rs256("other", claims(nil)), wantErr: app.ErrTokenUnknownKey},
+		{name: "should reject expired token",
+			token:   rs256("rsa", claims(func(c *Claims) { c.ExpiresAt = now.Add(-time.Minute).Unix() })),
+			wantErr: app.ErrTokenExpired},
+		{name: "should reject token without expiry",
+			token:   rs256("rsa", claims(func(c *Claims) { c.ExpiresAt = 0 })),
+			wantErr: app.ErrTokenExpired},
+		{name: "should reject token not valid yet",
+			token:   rs256("rsa", claims(func(c *Claims) { c.NotBefore = now.Add(time.Minute).Unix() })),
+			wantErr: app.ErrTokenNotYetValid},
+		{name: "should reject wrong issuer",
+			token:   rs256("rsa", claims(func(c *Claims) { c.Issuer = "other" })),
+			wantErr: app.ErrTokenIssuer},
+		{name: "should reject wrong audience",
+			token:   rs256("rsa", claims(func(c *Claims) { c.Audience = ClaimAudience{"other"} })),
+			wantErr: app.ErrTokenAudience},
+		{name: "should reject missing audience",
+			token:   rs256("rsa", claims(func(c *Claims) { c.Audience = nil })),
+			wantErr: app.ErrTokenAudience},
+		{name: "should reject revoked token id",
+			token:   rs256("rsa", claims(func(c *Claims) { c.Id = "revoked-jti" })),
+			wantErr: app.ErrTokenRevoked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ts.ParseToken(context.Background(), tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("TokenService.ParseToken() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && got.GetSubject() != "subject" {
+				t.Errorf("TokenService.ParseToken() subject = %v, want subject", got.GetSubject())
+			}
+		})
+	}
+
+	t.Run("should fail when the revocation list is not available", func(t *testing.T) {
+		mr.Close()
+
+		_, err := ts.ParseToken(context.Background(), rs256("rsa", claims(func(c *Claims) { c.Id = "jti" })))
+		var ae *app.Error
+		if !errors.As(err, &ae) || ae.Code() != http.StatusInternalServerError {
+			t.Errorf("TokenService.ParseToken() error = %v, want internal server error", err)
+		}
+	})
+
+	t.Run("should accept the token when the revocation check fails open", func(t *testing.T) {
+		t.Setenv("JWT_REVOCATION_FAIL_OPEN", "true")
+		ts := NewTokenService(config.New(), NewLoggerMock(), revoked)
+
+		if _, err := ts.ParseToken(context.Background(), rs256("rsa", claims(func(c *Claims) { c.Id = "jti" }))); err != nil {
+			t.Errorf("TokenService.ParseToken() error = %v", err)
+		}
+	})
+}
+
+func TestNewTokenService_Algorithms(t *testing.T) {
+	SetTokenServiceEnvForTesting(t)
+
+	for _, alg := range []string{"HS256", "none", "unknown"} {
+		t.Run(alg, func(t *testing.T) {
+			t.Setenv("JWT_ALGORITHMS", strings.Join([]string{"RS256", alg}, ","))
+
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewTokenService() should panic with %s", alg)
+				}
+			}()
+			NewTokenService(config.New(), NewLoggerMock(), nil)
+		})
+	}
+}
+
+func TestTokenService_ExtractAccessToken(t *testing.T) {
+	SetTokenServiceEnvForTesting(t)
+	t.Setenv("JWT_COOKIE", "session")
+
+	// the query parameter is disabled by default
+	r, _ := http.NewRequest(http.MethodGet, "/?access_token=abc", nil)
+	if _, err := NewTokenService(config.New(), NewLoggerMock(), nil).ExtractAccessToken(r); !errors.Is(err, app.ErrTokenMissing) {
+		t.Errorf("TokenService.ExtractAccessToken() error = %v, want %v", err, app.ErrTokenMissing)
+	}
+
+	t.Setenv("JWT_QUERY_PARAM", "access_token")
+	ts := NewTokenService(config.New(), NewLoggerMock(), nil)
+
+	request := func(method, target, header string, cookie *http.Cookie) *http.Request {
+		r, _ := http.NewRequest(method, target, nil)
+		if header != "" {
+			r.Header.Set("Authorization", header)
+		}
+		if cookie != nil {
+			r.AddCookie(cookie)
+		}
+		return r
+	}
+
+	tests := []struct {
+		name    string
+		r       *http.Request
+		want    string
+		wantErr error
+	}{
+		{name: "should read bearer token", r: request(http.MethodPost, "/", "Bearer abc", nil), want: "abc"},
+		{name: "should read case insensitive scheme", r: request(http.MethodPost, "/", "bearer abc", nil), want: "abc"},
+		{name: "should ignore extra spaces", r: request(http.MethodPost, "/", "  Bearer   abc ", nil), want: "abc"},
+		{name: "should reject missing header", r: request(http.MethodPost, "/", "", nil), wantErr: app.ErrTokenMissing},
+		{name: "should reject blank header", r: request(http.MethodPost, "/", "   ", nil), wantErr: app.ErrTokenMissing},
+		{name: "should reject short header", r: request(http.MethodPost, "/", "abc", nil), wantErr: app.ErrTokenScheme},
+		{name: "should reject other schemes", r: request(http.MethodPost, "/", "Basic abc", nil), wantErr: app.ErrTokenScheme},
+		{name: "should reject scheme without token", r: request(http.MethodPost, "/", "Bearer", nil), wantErr: app.ErrTokenMalformed},
+		{name: "should reject several tokens", r: request(http.MethodPost, "/", "Bearer a b", nil), wantErr: app.ErrTokenMalformed},
+		{name: "should read query parameter on GET", r: request(http.MethodGet, "/?access_token=abc", "", nil), want: "abc"},
+		{name: "should read cookie on GET", r: request(http.MethodGet, "/", "", &http.Cookie{Name: "session", Value: "abc"}), want: "abc"},
+		{name: "should prefer header", r: request(http.MethodGet, "/?access_token=abc", "Bearer def", nil), want: "def"},
+		{name: "should ignore query parameter on POST", r: request(http.MethodPost, "/?access_token=abc", "", nil), wantErr: app.ErrTokenMissing},
+		{name: "should ignore cookie on POST",
+			r:       request(http.MethodPost, "/", "", &http.Cookie{Name: "session", Value: "abc"}),
+			wantErr: app.ErrTokenMissing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ts.ExtractAccessToken(tt.r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("TokenService.ExtractAccessToken() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("TokenService.ExtractAccessToken() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
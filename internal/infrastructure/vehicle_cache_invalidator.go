@@ -0,0 +1,68 @@
+package infrastructure
+
+import (
+	"context"
+
+	"github.com/orkungursel/hey-taxi-location-api/internal/app"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger"
+)
+
+// VehicleCacheInvalidator keeps the vehicle cache in sync with the vehicle service
+// by evicting, or refreshing if enabled, the vehicles of the received events
+type VehicleCacheInvalidator struct {
+	source         app.VehicleEventSource
+	vehicleService app.VehicleService
+	logger         logger.ILogger
+	refresh        bool
+}
+
+func NewVehicleCacheInvalidator(source app.VehicleEventSource, vehicleService app.VehicleService,
+	logger logger.ILogger, refresh bool) *VehicleCacheInvalidator {
+
+	return &VehicleCacheInvalidator{
+		source:         source,
+		vehicleService: vehicleService,
+		logger:         logger,
+		refresh:        refresh,
+	}
+}
+
+// Start subscribes to the source and handles the events in the background
+// until the context is done
+func (i *VehicleCacheInvalidator) Start(ctx context.Context) error {
+	events, err := i.source.Subscribe(ctx)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for e := range events {
+			i.Handle(ctx, e)
+		}
+	}()
+
+	return nil
+}
+
+// Handle applies a single event to the cache
+func (i *VehicleCacheInvalidator) Handle(ctx context.Context, e app.VehicleEvent) {
+	if e.VehicleId == "" || !app.IsValidTenant(e.Tenant) {
+		i.logger.Warnf("invalid vehicle event: %+v", e)
+		return
+	}
+
+	ctx = app.WithTenant(ctx, e.Tenant)
+
+	if i.refresh && e.Type == app.VehicleEventUpdated {
+		_, err := i.vehicleService.RefreshVehicle(ctx, e.VehicleId)
+		if err == nil {
+			return
+		}
+
+		i.logger.Warnf("failed to refresh vehicle %s, purging: %v", e.VehicleId, err)
+	}
+
+	if err := i.vehicleService.PurgeVehicle(ctx, e.VehicleId); err != nil {
+		i.logger.Errorf("failed to purge vehicle %s: %v", e.VehicleId, err)
+	}
+}
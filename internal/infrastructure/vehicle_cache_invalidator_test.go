@@ -0,0 +1,173 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/orkungursel/hey-taxi-location-api/internal/app"
+	"github.com/orkungursel/hey-taxi-location-api/internal/app/mock"
+	logger "github.com/orkungursel/hey-taxi-location-api/pkg/logger/mock"
+)
+
+func TestVehicleCacheInvalidator_Handle(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tests := []struct {
+		name    string
+		refresh bool
+		event   app.VehicleEvent
+		setup   func(vs *mock.MockVehicleService)
+	}{
+		{
+			name:  "should purge updated vehicle",
+			event: app.VehicleEvent{Type: app.VehicleEventUpdated, VehicleId: "v1"},
+			setup: func(vs *mock.MockVehicleService) {
+				vs.EXPECT().PurgeVehicle(gomock.Any(), "v1").Return(nil).Times(1)
+			},
+		},
+		{
+			name:  "should purge deleted vehicle",
+			event: app.VehicleEvent{Type: app.VehicleEventDeleted, VehicleId: "v1"},
+			setup: func(vs *mock.MockVehicleService) {
+				vs.EXPECT().PurgeVehicle(gomock.Any(), "v1").Return(nil).Times(1)
+			},
+		},
+		{
+			name:    "should refresh updated vehicle when enabled",
+			refresh: true,
+			event:   app.VehicleEvent{Type: app.VehicleEventUpdated, VehicleId: "v1"},
+			setup: func(vs *mock.MockVehicleService) {
+				vs.EXPECT().RefreshVehicle(gomock.Any(), "v1").Return(vehicle1, nil).Times(1)
+				vs.EXPECT().PurgeVehicle(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:    "should purge when refresh fails",
+			refresh: true,
+			event:   app.VehicleEvent{Type: app.VehicleEventUpdated, VehicleId: "v1"},
+			setup: func(vs *mock.MockVehicleService) {
+				vs.EXPECT().RefreshVehicle(gomock.Any(), "v1").Return(nil, errors.New("error")).Times(1)
+				vs.EXPECT().PurgeVehicle(gomock.Any(), "v1").Return(nil).Times(1)
+			},
+		},
+		{
+			name:    "should not refresh deleted vehicle",
+			refresh: true,
+			event:   app.VehicleEvent{Type: app.VehicleEventDeleted, VehicleId: "v1"},
+			setup: func(vs *mock.MockVehicleService) {
+				vs.EXPECT().RefreshVehicle(gomock.Any(), gomock.Any()).Times(0)
+				vs.EXPECT().PurgeVehicle(gomock.Any(), "v1").Return(nil).Times(1)
+			},
+		},
+		{
+			name:  "should scope the purge to the tenant of the event",
+			event: app.VehicleEvent{Type: app.VehicleEventDeleted, VehicleId: "v1", Tenant: "acme"},
+			setup: func(vs *mock.MockVehicleService) {
+				vs.EXPECT().PurgeVehicle(gomock.Any(), "v1").DoAndReturn(
+					func(ctx context.Context, _ string) error {
+						if got := app.TenantFromContext(ctx); got != "acme" {
+							t.Errorf("PurgeVehicle() tenant = %v, want acme", got)
+						}
+						return nil
+					}).Times(1)
+			},
+		},
+		{
+			name:  "should ignore invalid events",
+			event: app.VehicleEvent{Type: app.VehicleEventDeleted, VehicleId: "v1", Tenant: "a:b"},
+			setup: func(vs *mock.MockVehicleService) {
+				vs.EXPECT().PurgeVehicle(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vs := mock.NewMockVehicleService(ctrl)
+			tt.setup(vs)
+
+			i := NewVehicleCacheInvalidator(NewMemoryVehicleEventSource(), vs, logger.NewLoggerMock(), tt.refresh)
+			i.Handle(context.Background(), tt.event)
+		})
+	}
+}
+
+func TestVehicleCacheInvalidator_Start(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	vs := mock.NewMockVehicleService(ctrl)
+	vs.EXPECT().PurgeVehicle(gomock.Any(), "v1").DoAndReturn(func(context.Context, string) error {
+		close(done)
+		return nil
+	}).Times(1)
+
+	source := NewMemoryVehicleEventSource()
+	i := NewVehicleCacheInvalidator(source, vs, logger.NewLoggerMock(), false)
+	if err := i.Start(ctx); err != nil {
+		t.Fatalf("VehicleCacheInvalidator.Start() error = %v", err)
+	}
+
+	source.Publish(app.VehicleEvent{Type: app.VehicleEventDeleted, VehicleId: "v1"})
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("VehicleCacheInvalidator did not handle the event")
+	}
+}
+
+func TestRedisVehicleEventSource_Subscribe(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	db := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	source := NewRedisVehicleEventSource(db, logger.NewLoggerMock(), "")
+	events, err := source.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("RedisVehicleEventSource.Subscribe() error = %v", err)
+	}
+
+	want := app.VehicleEvent{Type: app.VehicleEventUpdated, VehicleId: "v1", Tenant: "acme"}
+	payload, _ := json.Marshal(want)
+
+	mr.Publish(vehicleEventChannel, "not json")
+	mr.Publish(vehicleEventChannel, string(payload))
+
+	select {
+	case got := <-events:
+		if got != want {
+			t.Errorf("RedisVehicleEventSource.Subscribe() = %v, want %v", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("RedisVehicleEventSource.Subscribe() did not deliver the event")
+	}
+
+	cancel()
+
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("RedisVehicleEventSource.Subscribe() delivered unexpected event")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("RedisVehicleEventSource.Subscribe() channel is not closed after cancel")
+	}
+}
@@ -0,0 +1,127 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/orkungursel/hey-taxi-location-api/internal/app"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger"
+)
+
+const (
+	vehicleEventChannel    = "vehicle-events" // vehicleEventChannel is the default pub/sub channel of the vehicle events
+	vehicleEventBufferSize = 64               // vehicleEventBufferSize is the buffer of the subscriber channels
+)
+
+// RedisVehicleEventSource receives the vehicle events from a redis pub/sub channel
+type RedisVehicleEventSource struct {
+	db      redis.UniversalClient
+	logger  logger.ILogger
+	channel string
+}
+
+func NewRedisVehicleEventSource(db redis.UniversalClient, logger logger.ILogger,
+	channel string) *RedisVehicleEventSource {
+
+	if channel == "" {
+		channel = vehicleEventChannel
+	}
+
+	return &RedisVehicleEventSource{
+		db:      db,
+		logger:  logger,
+		channel: channel,
+	}
+}
+
+// Subscribe subscribes to the channel, the returned channel is closed when the context is done
+func (s *RedisVehicleEventSource) Subscribe(ctx context.Context) (<-chan app.VehicleEvent, error) {
+	ps := s.db.Subscribe(ctx, s.channel)
+
+	// wait for the subscription to be confirmed
+	if _, err := ps.Receive(ctx); err != nil {
+		_ = ps.Close()
+		return nil, err
+	}
+
+	out := make(chan app.VehicleEvent, vehicleEventBufferSize)
+
+	go func() {
+		defer close(out)
+		defer ps.Close()
+
+		ch := ps.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+
+				e := app.VehicleEvent{}
+				if err := json.Unmarshal([]byte(msg.Payload), &e); err != nil {
+					s.logger.Warnf("invalid vehicle event %q: %v", msg.Payload, err)
+					continue
+				}
+
+				select {
+				case out <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// MemoryVehicleEventSource is an in-process stand-in of the event bus,
+// the events given to Publish are delivered to every subscriber
+type MemoryVehicleEventSource struct {
+	mu   sync.Mutex
+	subs map[chan app.VehicleEvent]context.Context
+}
+
+func NewMemoryVehicleEventSource() *MemoryVehicleEventSource {
+	return &MemoryVehicleEventSource{
+		subs: make(map[chan app.VehicleEvent]context.Context),
+	}
+}
+
+// Subscribe returns a channel of the published events, it is closed when the context is done
+func (s *MemoryVehicleEventSource) Subscribe(ctx context.Context) (<-chan app.VehicleEvent, error) {
+	ch := make(chan app.VehicleEvent, vehicleEventBufferSize)
+
+	s.mu.Lock()
+	s.subs[ch] = ctx
+	s.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		s.mu.Lock()
+		delete(s.subs, ch)
+		close(ch)
+		s.mu.Unlock()
+	}()
+
+	return ch, nil
+}
+
+// Publish delivers the event to the subscribers, it blocks while a subscriber's buffer is full
+func (s *MemoryVehicleEventSource) Publish(e app.VehicleEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for ch, ctx := range s.subs {
+		select {
+		case ch <- e:
+		case <-ctx.Done():
+		}
+	}
+}
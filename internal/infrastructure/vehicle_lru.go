@@ -0,0 +1,164 @@
+package infrastructure
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-location-api/internal/domain/model"
+)
+
+// VehicleLRU is an in-process, size and TTL bounded cache of the vehicles which
+// sits in front of the vehicle repository. It also remembers, for a shorter TTL,
+// the vehicles unknown to the vehicle service. A nil *VehicleLRU is a disabled cache.
+type VehicleLRU struct {
+	mu         sync.Mutex
+	size       int
+	ttl        time.Duration
+	missingTtl time.Duration
+	ll         *list.List
+	items      map[string]*list.Element
+	now        func() time.Time
+}
+
+type vehicleLRUEntry struct {
+	key       string
+	vehicle   *model.Vehicle
+	expiresAt time.Time
+}
+
+// NewVehicleLRU returns a cache of at most size vehicles which are kept for ttl
+// and the missing ones for missingTtl. It returns nil, a disabled cache, when size
+// or ttl is not positive; a non-positive missingTtl disables only the negative caching.
+func NewVehicleLRU(size int, ttl, missingTtl time.Duration) *VehicleLRU {
+	if size <= 0 || ttl <= 0 {
+		return nil
+	}
+
+	return &VehicleLRU{
+		size:       size,
+		ttl:        ttl,
+		missingTtl: missingTtl,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element, size),
+		now:        time.Now,
+	}
+}
+
+// Get returns the vehicle of the key if it is cached and not expired,
+// the vehicle is nil if the key is cached as missing
+func (c *VehicleLRU) Get(key string) (*model.Vehicle, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	e := el.Value.(*vehicleLRUEntry)
+	if !c.now().Before(e.expiresAt) {
+		// kept to be served by GetStale until it is evicted or replaced
+		return nil, false
+	}
+
+	c.ll.MoveToFront(el)
+	return e.vehicle, true
+}
+
+// GetStale returns the vehicle of the key even if it is expired,
+// it is used when the vehicle service is not available
+func (c *VehicleLRU) GetStale(key string) (*model.Vehicle, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	e := el.Value.(*vehicleLRUEntry)
+	if e.vehicle == nil {
+		return nil, false
+	}
+
+	return e.vehicle, true
+}
+
+// Add caches the vehicle, evicting the least recently used one when the cache is full
+func (c *VehicleLRU) Add(key string, vehicle *model.Vehicle) {
+	if c == nil || vehicle == nil {
+		return
+	}
+
+	c.add(key, vehicle, c.ttl)
+}
+
+// AddMissing caches the key as missing, so the vehicle service is not asked again until it expires
+func (c *VehicleLRU) AddMissing(key string) {
+	if c == nil || c.missingTtl <= 0 {
+		return
+	}
+
+	c.add(key, nil, c.missingTtl)
+}
+
+func (c *VehicleLRU) add(key string, vehicle *model.Vehicle, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := c.now().Add(ttl)
+
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*vehicleLRUEntry)
+		e.vehicle = vehicle
+		e.expiresAt = expiresAt
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&vehicleLRUEntry{key: key, vehicle: vehicle, expiresAt: expiresAt})
+
+	for c.ll.Len() > c.size {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+// Remove evicts the vehicle of the key
+func (c *VehicleLRU) Remove(key string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
+}
+
+// Len returns the number of the cached vehicles, including the expired ones not evicted yet
+func (c *VehicleLRU) Len() int {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.ll.Len()
+}
+
+func (c *VehicleLRU) removeElement(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*vehicleLRUEntry).key)
+}
@@ -0,0 +1,94 @@
+package infrastructure
+
+import (
+	"testing"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-location-api/internal/domain/model"
+)
+
+func TestNewVehicleLRU(t *testing.T) {
+	tests := []struct {
+		name     string
+		size     int
+		ttl      time.Duration
+		disabled bool
+	}{
+		{name: "should be enabled", size: 1, ttl: time.Second},
+		{name: "should be disabled with zero size", size: 0, ttl: time.Second, disabled: true},
+		{name: "should be disabled with zero ttl", size: 1, ttl: 0, disabled: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewVehicleLRU(tt.size, tt.ttl, 0)
+			if (c == nil) != tt.disabled {
+				t.Errorf("NewVehicleLRU() = %v, disabled %v", c, tt.disabled)
+			}
+
+			// a disabled cache is a no-op
+			c.Add("key", vehicle1)
+			if _, ok := c.Get("key"); ok == tt.disabled {
+				t.Errorf("VehicleLRU.Get() ok = %v, disabled %v", ok, tt.disabled)
+			}
+			c.Remove("key")
+		})
+	}
+}
+
+func TestVehicleLRU(t *testing.T) {
+	now := time.Now()
+
+	c := NewVehicleLRU(2, time.Minute, 10*time.Second)
+	c.now = func() time.Time { return now }
+
+	v1, v2, v3 := &model.Vehicle{Id: "v1"}, &model.Vehicle{Id: "v2"}, &model.Vehicle{Id: "v3"}
+
+	c.Add("v1", v1)
+	c.Add("v2", v2)
+
+	// v1 becomes the most recently used, so v2 is evicted
+	if got, ok := c.Get("v1"); !ok || got != v1 {
+		t.Errorf("VehicleLRU.Get() = %v, want %v", got, v1)
+	}
+	c.Add("v3", v3)
+
+	if _, ok := c.Get("v2"); ok {
+		t.Error("VehicleLRU.Get() should not return the least recently used vehicle")
+	}
+	if c.Len() != 2 {
+		t.Errorf("VehicleLRU.Len() = %v, want 2", c.Len())
+	}
+
+	c.Remove("v3")
+	if _, ok := c.Get("v3"); ok {
+		t.Error("VehicleLRU.Get() should not return removed vehicle")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := c.Get("v1"); ok {
+		t.Error("VehicleLRU.Get() should not return expired vehicle")
+	}
+	if got, ok := c.GetStale("v1"); !ok || got != v1 {
+		t.Errorf("VehicleLRU.GetStale() = %v, want %v", got, v1)
+	}
+
+	c.AddMissing("unknown")
+	if got, ok := c.Get("unknown"); !ok || got != nil {
+		t.Errorf("VehicleLRU.Get() = %v, %v, want missing", got, ok)
+	}
+
+	if _, ok := c.GetStale("unknown"); ok {
+		t.Error("VehicleLRU.GetStale() should not return missing vehicle")
+	}
+
+	now = now.Add(10 * time.Second)
+	if _, ok := c.Get("unknown"); ok {
+		t.Error("VehicleLRU.Get() should not return expired missing vehicle")
+	}
+
+	c = NewVehicleLRU(2, time.Minute, 0)
+	c.AddMissing("unknown")
+	if _, ok := c.Get("unknown"); ok {
+		t.Error("VehicleLRU.AddMissing() should be a no-op when negative caching is disabled")
+	}
+}
@@ -2,7 +2,9 @@ package infrastructure
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -13,16 +15,26 @@ import (
 const (
 	vehicleDbKey        = "vehicle"                     // dbKey is the key to store drivers in redis
 	vehicleDbExpiration = time.Duration(24) * time.Hour // expire is the expiration time for the vehicle in redis
+	vehicleDbVersion    = 2                             // vehicleDbVersion is the version of the cached vehicle format
 )
 
+// cachedVehicle is the versioned format of the vehicles in redis. The first
+// version was the bare vehicle json under "vehicle:<id>", the older releases
+// still write and read it there, so the versioned one is kept under
+// "vehicle:v2:<id>" and the first one is only read as a fallback.
+type cachedVehicle struct {
+	Version int            `json:"v"`
+	Vehicle *model.Vehicle `json:"vehicle"`
+}
+
 type VehicleRepository struct {
-	db     *redis.Client
+	db     redis.UniversalClient
 	logger logger.ILogger
 	dbKey  string
 	expire time.Duration
 }
 
-func NewVehicleRepository(db *redis.Client, logger logger.ILogger) *VehicleRepository {
+func NewVehicleRepository(db redis.UniversalClient, logger logger.ILogger) *VehicleRepository {
 	return &VehicleRepository{
 		db:     db,
 		logger: logger,
@@ -32,42 +44,91 @@ func NewVehicleRepository(db *redis.Client, logger logger.ILogger) *VehicleRepos
 }
 
 // generateDbKey generates the key to store the vehicle in redis
-func (r *VehicleRepository) generateDbKey(vehicleId string) (string, error) {
+func (r *VehicleRepository) generateDbKey(ctx context.Context, vehicleId string) (string, error) {
 	if vehicleId == "" {
 		return "", errors.New("vehicleId is empty")
 	}
 
-	return r.dbKey + ":" + vehicleId, nil
+	return tenantKey(ctx, fmt.Sprintf("%s:v%d:%s", r.dbKey, vehicleDbVersion, vehicleId)), nil
 }
 
-// Get returns the vehicle from redis database
+// generateLegacyDbKey generates the key of the bare vehicle json of version 1
+func (r *VehicleRepository) generateLegacyDbKey(ctx context.Context, vehicleId string) string {
+	return tenantKey(ctx, r.dbKey+":"+vehicleId)
+}
+
+// Get returns the vehicle from redis database, or the one of version 1 if the
+// versioned one is missing
 func (r *VehicleRepository) Get(ctx context.Context, vehicleId string) (*model.Vehicle, error) {
-	key, err := r.generateDbKey(vehicleId)
+	key, err := r.generateDbKey(ctx, vehicleId)
 	if err != nil {
 		return nil, err
 	}
 
 	s, err := r.db.Get(ctx, key).Result()
+	if err == redis.Nil {
+		s, err = r.db.Get(ctx, r.generateLegacyDbKey(ctx, vehicleId)).Result()
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	vehicle := &model.Vehicle{}
-	if err := vehicle.UnmarshalJson([]byte(s)); err != nil {
+	return decodeVehicle([]byte(s))
+}
+
+// GetMany returns the vehicles of the ids from redis database with a single round
+// trip. The GETs are pipelined rather than sent as a MGET, the keys of a MGET
+// must be in the same redis cluster slot.
+func (r *VehicleRepository) GetMany(ctx context.Context, vehicleIds []string) (map[string]*model.Vehicle, error) {
+	cmds := make(map[string]*redis.StringCmd, len(vehicleIds))
+	legacyCmds := make(map[string]*redis.StringCmd, len(vehicleIds))
+	_, err := r.db.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, id := range vehicleIds {
+			key, err := r.generateDbKey(ctx, id)
+			if err != nil {
+				return err
+			}
+			cmds[id] = pipe.Get(ctx, key)
+			legacyCmds[id] = pipe.Get(ctx, r.generateLegacyDbKey(ctx, id))
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
 		return nil, err
 	}
 
-	return vehicle, nil
+	res := make(map[string]*model.Vehicle, len(cmds))
+	for id, cmd := range cmds {
+		b, err := cmd.Bytes()
+		if err == redis.Nil {
+			b, err = legacyCmds[id].Bytes()
+		}
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		vehicle, err := decodeVehicle(b)
+		if err != nil {
+			r.logger.Warnf("skipped undecodable vehicle %s: %v", id, err)
+			continue
+		}
+		res[id] = vehicle
+	}
+
+	return res, nil
 }
 
 // Save saves the vehicle to redis database
 func (r *VehicleRepository) Save(ctx context.Context, vehicle *model.Vehicle) error {
-	key, err := r.generateDbKey(vehicle.Id)
+	key, err := r.generateDbKey(ctx, vehicle.Id)
 	if err != nil {
 		return err
 	}
 
-	s, err := vehicle.MarshalJson()
+	s, err := encodeVehicle(vehicle)
 	if err != nil {
 		return err
 	}
@@ -75,12 +136,44 @@ func (r *VehicleRepository) Save(ctx context.Context, vehicle *model.Vehicle) er
 	return r.db.SetEX(ctx, key, s, r.expire).Err()
 }
 
-// Delete deletes the vehicle from redis database
+// Delete deletes the vehicle from redis database, in both formats. The keys
+// are deleted one by one, they may be in different redis cluster slots.
 func (r *VehicleRepository) Delete(ctx context.Context, vehicleId string) error {
-	key, err := r.generateDbKey(vehicleId)
+	key, err := r.generateDbKey(ctx, vehicleId)
 	if err != nil {
 		return err
 	}
 
-	return r.db.Del(ctx, key).Err()
+	_, err = r.db.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.Del(ctx, r.generateLegacyDbKey(ctx, vehicleId))
+		return nil
+	})
+
+	return err
+}
+
+func encodeVehicle(vehicle *model.Vehicle) ([]byte, error) {
+	return json.Marshal(cachedVehicle{Version: vehicleDbVersion, Vehicle: vehicle})
+}
+
+func decodeVehicle(b []byte) (*model.Vehicle, error) {
+	c := cachedVehicle{}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case c.Version == 0:
+		// version 1, the bare vehicle without the driver profile and color
+		vehicle := &model.Vehicle{}
+		if err := vehicle.UnmarshalJson(b); err != nil {
+			return nil, err
+		}
+		return vehicle, nil
+	case c.Version > vehicleDbVersion || c.Vehicle == nil:
+		return nil, fmt.Errorf("unsupported cached vehicle version: %d", c.Version)
+	}
+
+	return c.Vehicle, nil
 }
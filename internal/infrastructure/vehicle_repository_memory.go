@@ -0,0 +1,125 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-location-api/internal/domain/model"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger"
+)
+
+var (
+	ErrVehicleNotInRepository = errors.New("vehicle not found in repository")
+)
+
+type memoryVehicleItem struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+// MemoryVehicleRepository is an in-process VehicleRepository with the same
+// expiration semantics as the redis implementation.
+type MemoryVehicleRepository struct {
+	mu     sync.RWMutex
+	logger logger.ILogger
+	items  map[string]memoryVehicleItem
+	expire time.Duration
+	now    func() time.Time
+}
+
+func NewMemoryVehicleRepository(logger logger.ILogger) *MemoryVehicleRepository {
+	return &MemoryVehicleRepository{
+		logger: logger,
+		items:  make(map[string]memoryVehicleItem),
+		expire: vehicleDbExpiration,
+		now:    time.Now,
+	}
+}
+
+// Get returns the vehicle from memory
+func (r *MemoryVehicleRepository) Get(ctx context.Context, vehicleId string) (*model.Vehicle, error) {
+	if vehicleId == "" {
+		return nil, errors.New("vehicleId is empty")
+	}
+
+	key := tenantKey(ctx, vehicleId)
+
+	r.mu.RLock()
+	item, ok := r.items[key]
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, ErrVehicleNotInRepository
+	}
+
+	if !r.now().Before(item.expiresAt) {
+		r.mu.Lock()
+		if cur, ok := r.items[key]; ok && !r.now().Before(cur.expiresAt) {
+			delete(r.items, key)
+		}
+		r.mu.Unlock()
+		return nil, ErrVehicleNotInRepository
+	}
+
+	vehicle := &model.Vehicle{}
+	if err := vehicle.UnmarshalJson(item.data); err != nil {
+		return nil, err
+	}
+
+	return vehicle, nil
+}
+
+// GetMany returns the vehicles of the ids from memory, the missing ones are left out
+func (r *MemoryVehicleRepository) GetMany(ctx context.Context, vehicleIds []string) (map[string]*model.Vehicle, error) {
+	res := make(map[string]*model.Vehicle, len(vehicleIds))
+	for _, id := range vehicleIds {
+		vehicle, err := r.Get(ctx, id)
+		if errors.Is(err, ErrVehicleNotInRepository) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		res[id] = vehicle
+	}
+
+	return res, nil
+}
+
+// Save saves the vehicle to memory
+func (r *MemoryVehicleRepository) Save(ctx context.Context, vehicle *model.Vehicle) error {
+	if vehicle.Id == "" {
+		return errors.New("vehicleId is empty")
+	}
+
+	s, err := vehicle.MarshalJson()
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.items[tenantKey(ctx, vehicle.Id)] = memoryVehicleItem{
+		data:      s,
+		expiresAt: r.now().Add(r.expire),
+	}
+
+	return nil
+}
+
+// Delete deletes the vehicle from memory
+func (r *MemoryVehicleRepository) Delete(ctx context.Context, vehicleId string) error {
+	if vehicleId == "" {
+		return errors.New("vehicleId is empty")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.items, tenantKey(ctx, vehicleId))
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package infrastructure
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-location-api/internal/domain/model"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger/mock"
+)
+
+func TestMemoryVehicleRepository(t *testing.T) {
+	repo := NewMemoryVehicleRepository(mock.NewLoggerMock())
+	ctx := context.Background()
+
+	now := time.Now()
+	repo.now = func() time.Time { return now }
+
+	v := &model.Vehicle{
+		Id:    "vehicle_id",
+		Name:  "name",
+		Plate: "plate",
+		Seats: 1,
+		Driver: model.Driver{
+			Id:   "driver_id",
+			Name: "driver_name",
+		},
+	}
+
+	if err := repo.Save(ctx, &model.Vehicle{}); err == nil {
+		t.Errorf("MemoryVehicleRepository.Save() should fail with empty id")
+	}
+
+	if err := repo.Save(ctx, v); err != nil {
+		t.Fatalf("MemoryVehicleRepository.Save() error = %v", err)
+	}
+
+	got, err := repo.Get(ctx, v.Id)
+	if err != nil {
+		t.Fatalf("MemoryVehicleRepository.Get() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("MemoryVehicleRepository.Get() = %v, want %v", got, v)
+	}
+
+	// changing the returned value should not change the stored one
+	got.Name = "changed"
+	if got, _ := repo.Get(ctx, v.Id); got.Name != v.Name {
+		t.Errorf("MemoryVehicleRepository.Get() returned a shared instance")
+	}
+
+	if _, err := repo.Get(ctx, "invalid_id"); err == nil {
+		t.Errorf("MemoryVehicleRepository.Get() should fail with unknown id")
+	}
+
+	many, err := repo.GetMany(ctx, []string{v.Id, "invalid_id"})
+	if err != nil || len(many) != 1 || !reflect.DeepEqual(many[v.Id], v) {
+		t.Errorf("MemoryVehicleRepository.GetMany() = %v, %v, want %v", many, err, v.Id)
+	}
+
+	if err := repo.Delete(ctx, v.Id); err != nil {
+		t.Errorf("MemoryVehicleRepository.Delete() error = %v", err)
+	}
+	if _, err := repo.Get(ctx, v.Id); err == nil {
+		t.Errorf("MemoryVehicleRepository.Delete() item not deleted")
+	}
+
+	_ = repo.Save(ctx, v)
+	now = now.Add(vehicleDbExpiration)
+	if _, err := repo.Get(ctx, v.Id); err == nil {
+		t.Errorf("MemoryVehicleRepository.Get() should not return expired items")
+	}
+	if len(repo.items) != 0 {
+		t.Errorf("MemoryVehicleRepository.Get() expired item is not removed")
+	}
+}
@@ -43,14 +43,14 @@ func TestVehicleRepository_generateDbKey(t *testing.T) {
 			args: args{
 				vehicleId: "vehicle_id",
 			},
-			want: vehicleDbKey + ":vehicle_id",
+			want: vehicleDbKey + ":v2:vehicle_id",
 		},
 		{
 			name: "generate key with custom db key",
 			args: args{
 				vehicleId: "vehicle_id",
 			},
-			want:  "custom_key:vehicle_id",
+			want:  "custom_key:v2:vehicle_id",
 			dbKey: "custom_key",
 		},
 		{
@@ -67,7 +67,7 @@ func TestVehicleRepository_generateDbKey(t *testing.T) {
 				repo.dbKey = tt.dbKey
 			}
 
-			if got, err := repo.generateDbKey(tt.args.vehicleId); got != tt.want {
+			if got, err := repo.generateDbKey(context.Background(), tt.args.vehicleId); got != tt.want {
 				if (err != nil) != tt.wantErr {
 					t.Errorf("VehicleRepository.generateDbKey() error = %v, wantErr %v", err, tt.wantErr)
 					return
@@ -105,11 +105,15 @@ func TestVehicleRepository_Get(t *testing.T) {
 		Type:  "type",
 		Class: "class",
 		Seats: 1,
+		Color: "yellow",
 		Driver: model.Driver{
-			Id:      "driver_id",
-			Name:    "driver_name",
-			Email:   "driver_email",
-			Picture: "driver_picture",
+			Id:        "driver_id",
+			Name:      "driver_name",
+			Nickname:  "driver_nickname",
+			Email:     "driver_email",
+			Picture:   "driver_picture",
+			Rating:    4.8,
+			PhoneMask: "+90*******67",
 		},
 	}
 
@@ -135,6 +139,15 @@ func TestVehicleRepository_Get(t *testing.T) {
 			want:    v1,
 			wantErr: false,
 		},
+		{
+			name: "get vehicle with driver profile",
+			args: args{
+				ctx:       ctx,
+				vehicleId: v2.Id,
+			},
+			want:    v2,
+			wantErr: false,
+		},
 		{
 			name: "get vehicle with invalid id",
 			args: args{
@@ -243,11 +256,15 @@ func TestVehicleRepository_Delete(t *testing.T) {
 		Type:  "type",
 		Class: "class",
 		Seats: 1,
+		Color: "yellow",
 		Driver: model.Driver{
-			Id:      "driver_id",
-			Name:    "driver_name",
-			Email:   "driver_email",
-			Picture: "driver_picture",
+			Id:        "driver_id",
+			Name:      "driver_name",
+			Nickname:  "driver_nickname",
+			Email:     "driver_email",
+			Picture:   "driver_picture",
+			Rating:    4.8,
+			PhoneMask: "+90*******67",
 		},
 	}
 
@@ -305,3 +322,124 @@ func TestVehicleRepository_Delete(t *testing.T) {
 		})
 	}
 }
+
+func TestVehicleRepository_Get_Versions(t *testing.T) {
+	repo, db := SetupVehicleRepositoryMocks()
+	ctx := context.Background()
+
+	legacy := `{"vehicle_id":"v1","name":"name","seats":4,"driver":{"user_id":"driver_id","name":"driver_name"}}`
+	tests := []struct {
+		name    string
+		cached  map[string]string
+		want    *model.Vehicle
+		wantErr bool
+	}{
+		{
+			name:   "should decode the bare vehicle of version 1",
+			cached: map[string]string{vehicleDbKey + ":v1": legacy},
+			want:   &model.Vehicle{Id: "v1", Name: "name", Seats: 4, Driver: model.Driver{Id: "driver_id", Name: "driver_name"}},
+		},
+		{
+			name: "should decode version 2 before version 1",
+			cached: map[string]string{
+				vehicleDbKey + ":v1":    legacy,
+				vehicleDbKey + ":v2:v1": `{"v":2,"vehicle":{"vehicle_id":"v1","color":"yellow","driver":{"user_id":"driver_id","rating":4.5,"phone_mask":"+90*******67"}}}`,
+			},
+			want: &model.Vehicle{Id: "v1", Color: "yellow",
+				Driver: model.Driver{Id: "driver_id", Rating: 4.5, PhoneMask: "+90*******67"}},
+		},
+		{
+			name:    "should fail with unknown version",
+			cached:  map[string]string{vehicleDbKey + ":v2:v1": `{"v":99,"vehicle":{"vehicle_id":"v1"}}`},
+			wantErr: true,
+		},
+		{
+			name:    "should fail with invalid json",
+			cached:  map[string]string{vehicleDbKey + ":v2:v1": `{`},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := db.FlushAll(ctx).Err(); err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tt.cached {
+				if err := db.Set(ctx, k, v, 0).Err(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := repo.Get(ctx, "v1")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VehicleRepository.Get() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("VehicleRepository.Get() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVehicleRepository_GetMany(t *testing.T) {
+	repo, db := SetupVehicleRepositoryMocks()
+	ctx := context.Background()
+
+	v1 := &model.Vehicle{Id: "v1", Name: "name"}
+	if err := repo.Save(ctx, v1); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set(ctx, vehicleDbKey+":v2:broken", "{", 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the missing and the undecodable vehicles are left out
+	got, err := repo.GetMany(ctx, []string{"v1", "missing", "broken"})
+	if err != nil {
+		t.Fatalf("VehicleRepository.GetMany() error = %v", err)
+	}
+	if want := map[string]*model.Vehicle{"v1": v1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("VehicleRepository.GetMany() = %v, want %v", got, want)
+	}
+
+	if _, err := repo.GetMany(ctx, []string{""}); err == nil {
+		t.Errorf("VehicleRepository.GetMany() should fail with empty id")
+	}
+}
+
+func TestVehicleRepository_LegacyFormat(t *testing.T) {
+	repo, db := SetupVehicleRepositoryMocks()
+	ctx := context.Background()
+
+	// written by a release which knows only the bare vehicle json
+	legacy := `{"vehicle_id":"v2","driver":{"user_id":"driver_id"}}`
+	if err := db.Set(ctx, vehicleDbKey+":v2", legacy, 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	v1 := &model.Vehicle{Id: "v1", Color: "yellow", Driver: model.Driver{Id: "driver_id"}}
+	if err := repo.Save(ctx, v1); err != nil {
+		t.Fatal(err)
+	}
+
+	// the older releases must not find the versioned format under their key
+	if n, err := db.Exists(ctx, vehicleDbKey+":v1").Result(); err != nil || n != 0 {
+		t.Errorf("Save() wrote the key of version 1, exists = %v, %v", n, err)
+	}
+
+	got, err := repo.GetMany(ctx, []string{"v1", "v2"})
+	if err != nil {
+		t.Fatalf("VehicleRepository.GetMany() error = %v", err)
+	}
+	want := map[string]*model.Vehicle{"v1": v1, "v2": {Id: "v2", Driver: model.Driver{Id: "driver_id"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("VehicleRepository.GetMany() = %v, want %v", got, want)
+	}
+
+	if err := repo.Delete(ctx, "v2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Get(ctx, "v2"); err != redis.Nil {
+		t.Errorf("VehicleRepository.Get() error = %v, want %v", err, redis.Nil)
+	}
+}
@@ -3,35 +3,249 @@ package infrastructure
 import (
 	"context"
 	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/orkungursel/hey-taxi-location-api/internal/app"
 	"github.com/orkungursel/hey-taxi-location-api/internal/domain/model"
 	"github.com/orkungursel/hey-taxi-location-api/pkg/logger"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/metrics"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/tracing"
 	"github.com/orkungursel/hey-taxi-location-api/proto"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
+const (
+	tenantMetadataKey = "x-tenant-id" // tenantMetadataKey forwards the tenant to the vehicle service
+)
+
+// VehicleService resolves the vehicles through an in-process LRU, the vehicle
+// repository and finally the vehicle gRPC service. Concurrent misses of the
+// same vehicle are de-duplicated, so only one of them reaches redis and gRPC.
 type VehicleService struct {
-	repo   app.VehicleRepository
-	client proto.VehicleServiceClient
-	logger logger.ILogger
+	repo    app.VehicleRepository
+	client  proto.VehicleServiceClient
+	logger  logger.ILogger
+	cache   *VehicleLRU
+	timeout time.Duration
+	metrics *metrics.Metrics
+
+	mu       sync.Mutex
+	inflight map[string]*vehicleLookup // by tenant key
+
+	batchUnimplemented int32 // set once the vehicle service answers GetVehicles with Unimplemented
+}
+
+// vehicleLookup is the lookup of a vehicle in flight, the concurrent misses
+// of the vehicle wait for it instead of starting their own
+type vehicleLookup struct {
+	done    chan struct{}
+	vehicle *model.Vehicle
+	err     error
 }
 
-func NewVehicleService(logger logger.ILogger,
-	client proto.VehicleServiceClient, repo app.VehicleRepository) *VehicleService {
+// vehicleFetcher fetches the vehicles of the ids, the unknown ones are left out
+type vehicleFetcher func(ctx context.Context, vehicleIds []string) (map[string]*model.Vehicle, error)
+
+// NewVehicleService returns the vehicle service, the cache lookups are counted
+// by m if it is not nil. timeout bounds the lookups shared by the concurrent
+// misses as they do not end with the request of any of them, 0 disables it.
+func NewVehicleService(logger logger.ILogger, client proto.VehicleServiceClient,
+	repo app.VehicleRepository, cache *VehicleLRU, timeout time.Duration, m *metrics.Metrics) *VehicleService {
 	return &VehicleService{
-		repo:   repo,
-		client: client,
-		logger: logger,
+		repo:     repo,
+		client:   client,
+		logger:   logger,
+		cache:    cache,
+		timeout:  timeout,
+		metrics:  m,
+		inflight: make(map[string]*vehicleLookup),
 	}
 }
 
 func (vs *VehicleService) GetVehicleById(ctx context.Context,
-	vehicleId string) (*model.Vehicle, error) {
+	vehicleId string) (_ *model.Vehicle, err error) {
 
 	if vehicleId == "" {
 		return nil, errors.New("empty vehicle id")
 	}
 
+	ctx, span := tracing.Tracer().Start(ctx, "VehicleService.GetVehicleById",
+		trace.WithAttributes(attribute.String("vehicle.id", vehicleId)))
+	defer endVehicleSpan(span, &err)
+
+	key := tenantKey(ctx, vehicleId)
+	vehicle, ok := vs.cacheGet(key)
+	span.SetAttributes(attribute.Bool("vehicle.cache_hit", ok))
+	if ok {
+		if vehicle == nil {
+			return nil, app.ErrVehicleNotFound
+		}
+		return vehicle, nil
+	}
+
+	lookups, err := vs.lookup(ctx, []string{vehicleId}, vs.fetchVehicle)
+	if err != nil {
+		return nil, err
+	}
+
+	l := lookups[vehicleId]
+	if l.vehicle == nil {
+		return nil, l.err
+	}
+
+	return l.vehicle, nil
+}
+
+// lookup returns the finished lookups of the ids. The ids without a lookup in
+// flight are fetched together by fetch, the others join the lookups in flight.
+// The callers wait until their own context is done, the fetch is not canceled
+// with the context of the caller which started it.
+func (vs *VehicleService) lookup(ctx context.Context, vehicleIds []string,
+	fetch vehicleFetcher) (map[string]*vehicleLookup, error) {
+
+	lookups := make(map[string]*vehicleLookup, len(vehicleIds))
+	started := make([]string, 0, len(vehicleIds))
+
+	vs.mu.Lock()
+	for _, id := range vehicleIds {
+		key := tenantKey(ctx, id)
+		l, ok := vs.inflight[key]
+		if !ok {
+			l = &vehicleLookup{done: make(chan struct{})}
+			vs.inflight[key] = l
+			started = append(started, id)
+		}
+		lookups[id] = l
+	}
+	vs.mu.Unlock()
+
+	if len(started) > 0 {
+		go vs.runLookups(ctx, started, lookups, fetch)
+	}
+
+	for _, l := range lookups {
+		select {
+		case <-l.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return lookups, nil
+}
+
+// runLookups fetches the started lookups on a shared context and finishes them,
+// an id left out by fetch is not found unless fetch failed. The lookups are
+// finished even if fetch does not return, so their callers are not left waiting.
+func (vs *VehicleService) runLookups(ctx context.Context, vehicleIds []string,
+	lookups map[string]*vehicleLookup, fetch vehicleFetcher) {
+
+	var vehicles map[string]*model.Vehicle
+	err := errors.New("vehicle lookup is aborted")
+	defer func() {
+		vs.finishLookups(ctx, vehicleIds, lookups, vehicles, err)
+	}()
+
+	shared, cancel := vs.sharedContext(ctx)
+	defer cancel()
+
+	vehicles, err = fetch(shared, vehicleIds)
+	if err == nil {
+		err = app.ErrVehicleNotFound
+	}
+}
+
+func (vs *VehicleService) finishLookups(ctx context.Context, vehicleIds []string,
+	lookups map[string]*vehicleLookup, vehicles map[string]*model.Vehicle, err error) {
+
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	for _, id := range vehicleIds {
+		l := lookups[id]
+		if l.vehicle = vehicles[id]; l.vehicle == nil {
+			l.err = err
+		}
+
+		delete(vs.inflight, tenantKey(ctx, id))
+		close(l.done)
+	}
+}
+
+// fetchVehicle fetches a single vehicle through the repository and GetVehicle, a
+// stale vehicle is served while the vehicle service is not available
+func (vs *VehicleService) fetchVehicle(ctx context.Context, vehicleIds []string) (map[string]*model.Vehicle, error) {
+	vehicleId := vehicleIds[0]
+	key := tenantKey(ctx, vehicleId)
+
+	vehicle, err := vs.getVehicleById(ctx, vehicleId)
+	if errors.Is(err, app.ErrVehicleNotFound) {
+		vs.cache.AddMissing(key)
+		return nil, nil
+	}
+	if errors.Is(err, ErrCircuitOpen) {
+		if stale, ok := vs.cache.GetStale(key); ok {
+			vs.metrics.VehicleCacheLookup(metrics.ResultStale)
+			vs.logger.Warnf("vehicle service is not available, serving stale vehicle %s", vehicleId)
+			return map[string]*model.Vehicle{vehicleId: stale}, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	vs.cache.Add(key, vehicle)
+	return map[string]*model.Vehicle{vehicleId: vehicle}, nil
+}
+
+// sharedContext returns the context of a lookup shared by the concurrent callers,
+// it is detached from the cancellation and the deadline of ctx. The tenant, the
+// access token and the trace of ctx are kept, the result is cached for the whole
+// tenant anyway.
+func (vs *VehicleService) sharedContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	shared := trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(ctx))
+	shared = app.WithTenant(shared, app.TenantFromContext(ctx))
+	if token := app.AccessTokenFromContext(ctx); token != "" {
+		shared = app.WithAccessToken(shared, token)
+	}
+
+	if vs.timeout <= 0 {
+		return context.WithCancel(shared)
+	}
+
+	return context.WithTimeout(shared, vs.timeout)
+}
+
+// endVehicleSpan ends the span, failed if the lookup failed for another reason
+// than an unknown vehicle
+func endVehicleSpan(span trace.Span, err *error) {
+	if !errors.Is(*err, app.ErrVehicleNotFound) {
+		endSpan(span, *err)
+	}
+	span.End()
+}
+
+// cacheGet looks the vehicle up in the in-process cache and counts the result
+func (vs *VehicleService) cacheGet(key string) (*model.Vehicle, bool) {
+	vehicle, ok := vs.cache.Get(key)
+	if ok {
+		vs.metrics.VehicleCacheLookup(metrics.ResultHit)
+	} else {
+		vs.metrics.VehicleCacheLookup(metrics.ResultMiss)
+	}
+
+	return vehicle, ok
+}
+
+func (vs *VehicleService) getVehicleById(ctx context.Context,
+	vehicleId string) (*model.Vehicle, error) {
+
 	if vehicle, err := vs.repo.Get(ctx, vehicleId); err == nil && vehicle != nil {
 		return vehicle, nil
 	}
@@ -48,26 +262,241 @@ func (vs *VehicleService) GetVehicleById(ctx context.Context,
 	return vehicle, nil
 }
 
+// RefreshVehicle fetches the vehicle from the vehicle service and replaces the cached one
+func (vs *VehicleService) RefreshVehicle(ctx context.Context,
+	vehicleId string) (*model.Vehicle, error) {
+
+	if vehicleId == "" {
+		return nil, errors.New("empty vehicle id")
+	}
+
+	vehicle, err := vs.getVehicleByIdFromGrpcService(ctx, vehicleId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := vs.repo.Save(ctx, vehicle); err != nil {
+		return nil, err
+	}
+
+	vs.cache.Add(tenantKey(ctx, vehicleId), vehicle)
+
+	return vehicle, nil
+}
+
+// PurgeVehicle removes the vehicle from the cache, so the next lookup goes to the vehicle service
+func (vs *VehicleService) PurgeVehicle(ctx context.Context, vehicleId string) error {
+	if vehicleId == "" {
+		return errors.New("empty vehicle id")
+	}
+
+	vs.cache.Remove(tenantKey(ctx, vehicleId))
+
+	return vs.repo.Delete(ctx, vehicleId)
+}
+
+// GetVehiclesByIds returns the found vehicles of the ids by their ids, the unknown
+// ones are left out. The cache misses are read from the repository at once, and
+// the rest with a single GetVehicles call, or one by one if the vehicle service
+// does not implement it. The misses in flight by other callers are waited for.
+func (vs *VehicleService) GetVehiclesByIds(ctx context.Context,
+	vehicleIds []string) (_ map[string]*model.Vehicle, err error) {
+
+	ctx, span := tracing.Tracer().Start(ctx, "VehicleService.GetVehiclesByIds",
+		trace.WithAttributes(attribute.Int("vehicle.count", len(vehicleIds))))
+	defer endVehicleSpan(span, &err)
+
+	res := make(map[string]*model.Vehicle, len(vehicleIds))
+	seen := make(map[string]bool, len(vehicleIds))
+	misses := make([]string, 0)
+	cacheHits := 0
+
+	for _, id := range vehicleIds {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		if vehicle, ok := vs.cacheGet(tenantKey(ctx, id)); ok {
+			if vehicle != nil {
+				res[id] = vehicle
+			}
+			cacheHits++
+			continue
+		}
+
+		misses = append(misses, id)
+	}
+
+	span.SetAttributes(
+		attribute.Int("vehicle.cache_hits", cacheHits),
+		attribute.Int("vehicle.misses", len(misses)),
+	)
+
+	if len(misses) == 0 {
+		return res, nil
+	}
+
+	lookups, err := vs.lookup(ctx, misses, vs.fetchVehicles)
+	if err != nil {
+		return nil, err
+	}
+
+	for id, l := range lookups {
+		switch {
+		case l.vehicle != nil:
+			res[id] = l.vehicle
+		case errors.Is(l.err, app.ErrVehicleNotFound), errors.Is(l.err, ErrCircuitOpen):
+			// unknown, or not available and not in the cache anymore
+		default:
+			return nil, l.err
+		}
+	}
+
+	return res, nil
+}
+
+// fetchVehicles fetches the vehicles through the repository and the vehicle
+// service, the stale vehicles are served while the vehicle service is not
+// available with ErrCircuitOpen
+func (vs *VehicleService) fetchVehicles(ctx context.Context,
+	vehicleIds []string) (map[string]*model.Vehicle, error) {
+
+	stored, err := vs.repo.GetMany(ctx, vehicleIds)
+	if err != nil {
+		vs.logger.Infof("failed to get vehicles from repository: %v", err)
+	}
+
+	res := make(map[string]*model.Vehicle, len(vehicleIds))
+	misses := make([]string, 0, len(vehicleIds))
+	for _, id := range vehicleIds {
+		if vehicle := stored[id]; vehicle != nil {
+			vs.cache.Add(tenantKey(ctx, id), vehicle)
+			res[id] = vehicle
+			continue
+		}
+		misses = append(misses, id)
+	}
+
+	if len(misses) == 0 {
+		return res, nil
+	}
+
+	vehicles, err := vs.getVehiclesFromGrpcService(ctx, misses)
+	if errors.Is(err, ErrCircuitOpen) {
+		for id, vehicle := range vs.getStaleVehicles(ctx, misses) {
+			res[id] = vehicle
+		}
+		return res, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range misses {
+		vehicle, ok := vehicles[id]
+		if !ok {
+			vs.cache.AddMissing(tenantKey(ctx, id))
+			continue
+		}
+
+		if err := vs.repo.Save(ctx, vehicle); err != nil {
+			vs.logger.Infof("failed to save vehicle to repository: %v", err)
+		}
+		vs.cache.Add(tenantKey(ctx, id), vehicle)
+		res[id] = vehicle
+	}
+
+	return res, nil
+}
+
+// getVehiclesFromGrpcService fetches the vehicles with a single GetVehicles call,
+// or one by one once the vehicle service answered it with Unimplemented
+func (vs *VehicleService) getVehiclesFromGrpcService(ctx context.Context,
+	vehicleIds []string) (map[string]*model.Vehicle, error) {
+
+	if atomic.LoadInt32(&vs.batchUnimplemented) == 0 {
+		vehicles, err := vs.getVehiclesByIdsFromGrpcService(ctx, vehicleIds)
+		if status.Code(err) != codes.Unimplemented {
+			return vehicles, err
+		}
+
+		atomic.StoreInt32(&vs.batchUnimplemented, 1)
+		vs.logger.Infof("GetVehicles is not implemented by the vehicle service, falling back to GetVehicle")
+	}
+
+	return vs.getVehiclesOneByOne(ctx, vehicleIds)
+}
+
+func (vs *VehicleService) getVehiclesOneByOne(ctx context.Context,
+	vehicleIds []string) (map[string]*model.Vehicle, error) {
+
+	res := make(map[string]*model.Vehicle, len(vehicleIds))
+	for _, id := range vehicleIds {
+		vehicle, err := vs.getVehicleByIdFromGrpcService(ctx, id)
+		if errors.Is(err, app.ErrVehicleNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		res[id] = vehicle
+	}
+
+	return res, nil
+}
+
+// getStaleVehicles returns the expired vehicles still in the in-process cache,
+// it is used while the vehicle service is not available
+func (vs *VehicleService) getStaleVehicles(ctx context.Context, vehicleIds []string) map[string]*model.Vehicle {
+	vs.logger.Warnf("vehicle service is not available, serving stale vehicles")
+
+	res := make(map[string]*model.Vehicle, len(vehicleIds))
+	for _, id := range vehicleIds {
+		if vehicle, ok := vs.cache.GetStale(tenantKey(ctx, id)); ok {
+			vs.metrics.VehicleCacheLookup(metrics.ResultStale)
+			res[id] = vehicle
+		}
+	}
+
+	return res
+}
+
+func (vs *VehicleService) getVehiclesByIdsFromGrpcService(ctx context.Context,
+	vehicleIds []string) (map[string]*model.Vehicle, error) {
+
+	out, err := vs.client.GetVehicles(vs.outgoingContext(ctx), &proto.GetVehiclesRequest{Ids: vehicleIds})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]*model.Vehicle, len(out.Vehicles))
+	for _, v := range out.Vehicles {
+		res[v.Id] = MapGrpcVehicleToDomain(v)
+	}
+
+	return res, nil
+}
+
 func (vs *VehicleService) getVehicleByIdFromGrpcService(ctx context.Context,
 	vehicleId string) (*model.Vehicle, error) {
 
-	vehicle, err := vs.client.GetVehicle(ctx, &proto.GetVehicleRequest{Id: vehicleId})
+	vehicle, err := vs.client.GetVehicle(vs.outgoingContext(ctx), &proto.GetVehicleRequest{Id: vehicleId})
+	if status.Code(err) == codes.NotFound {
+		return nil, app.ErrVehicleNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	return &model.Vehicle{
-		Id:    vehicle.Id,
-		Name:  vehicle.Name,
-		Plate: vehicle.Plate,
-		Type:  vehicle.Type,
-		Class: vehicle.Class,
-		Seats: int(vehicle.Seats),
-		Driver: model.Driver{
-			Id:      vehicle.Driver.Id,
-			Name:    vehicle.Driver.Name,
-			Email:   vehicle.Driver.Email,
-			Picture: vehicle.Driver.Avatar,
-		},
-	}, nil
+	return MapGrpcVehicleToDomain(vehicle), nil
+}
+
+// outgoingContext forwards the tenant of the context to the vehicle service
+func (vs *VehicleService) outgoingContext(ctx context.Context) context.Context {
+	if tenant := app.TenantFromContext(ctx); tenant != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, tenantMetadataKey, tenant)
+	}
+
+	return ctx
 }
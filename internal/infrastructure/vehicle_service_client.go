@@ -0,0 +1,244 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/metrics"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	maxVehicleServiceBackoff = 2 * time.Second
+)
+
+// VehicleServiceClientOptions configures the resilience of the vehicle service calls
+type VehicleServiceClientOptions struct {
+	Timeout          time.Duration // deadline of every attempt, 0 disables it
+	MaxRetries       int           // retries after the first attempt on transient errors
+	Backoff          time.Duration // base of the exponential backoff between the attempts
+	BreakerThreshold int           // consecutive failures which open the breaker, 0 disables it
+	BreakerTimeout   time.Duration // how long the breaker stays open before a probe call
+}
+
+// MaxDuration returns how long a call takes at most with all of its attempts,
+// 0 if the attempts have no deadline
+func (o VehicleServiceClientOptions) MaxDuration() time.Duration {
+	if o.Timeout <= 0 {
+		return 0
+	}
+
+	return time.Duration(o.MaxRetries+1)*o.Timeout + time.Duration(o.MaxRetries)*maxVehicleServiceBackoff
+}
+
+// NewVehicleServiceInterceptor returns a unary client interceptor which applies
+// the per attempt deadline, retries the transient errors with a jittered exponential
+// backoff and fails fast with ErrCircuitOpen while the circuit breaker is open
+func NewVehicleServiceInterceptor(opts VehicleServiceClientOptions,
+	logger logger.ILogger) grpc.UnaryClientInterceptor {
+
+	breaker := NewCircuitBreaker(opts.BreakerThreshold, opts.BreakerTimeout)
+	breaker.OnStateChange(func(from, to string) {
+		logger.Warnf("vehicle service circuit breaker changed from %s to %s", from, to)
+	})
+
+	return func(ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+
+		var err error
+		for attempt := 0; attempt <= opts.MaxRetries; attempt++ {
+			if attempt > 0 {
+				if err := sleepContext(ctx, backoffDuration(opts.Backoff, attempt)); err != nil {
+					return err
+				}
+			}
+
+			generation, berr := breaker.Allow()
+			if berr != nil {
+				return berr
+			}
+
+			err = invokeWithTimeout(ctx, opts.Timeout, method, req, reply, cc, invoker, callOpts...)
+			breaker.Done(generation, !isVehicleServiceFailure(err))
+
+			if err == nil || !isTransientVehicleServiceError(err) || ctx.Err() != nil {
+				return err
+			}
+
+			logger.Debugf("vehicle service call %s failed, attempt %d: %v", method, attempt+1, err)
+		}
+
+		return err
+	}
+}
+
+// NewVehicleServiceMetricsInterceptor returns a unary client interceptor which
+// observes the outcome of the calls, it is chained before the retries so a call
+// is observed once
+func NewVehicleServiceMetricsInterceptor(m *metrics.Metrics) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, callOpts...)
+
+		code := status.Code(err).String()
+		if errors.Is(err, ErrCircuitOpen) {
+			code = "CircuitOpen"
+		}
+		m.ObserveVehicleServiceCall(method, code, time.Since(start))
+
+		return err
+	}
+}
+
+// NewVehicleServiceTracingInterceptor returns a unary client interceptor which
+// starts a client span for every call and propagates its trace context in the
+// outgoing metadata, it is chained first so the retries are inside the span
+func NewVehicleServiceTracingInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+
+		service, rpc := splitGrpcMethod(method)
+		ctx, span := tracing.Tracer().Start(ctx, strings.TrimPrefix(method, "/"),
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.RPCSystemKey.String("grpc"),
+				semconv.RPCServiceKey.String(service),
+				semconv.RPCMethodKey.String(rpc),
+			),
+		)
+		defer span.End()
+
+		md, ok := metadata.FromOutgoingContext(ctx)
+		if ok {
+			md = md.Copy()
+		} else {
+			md = metadata.MD{}
+		}
+		otel.GetTextMapPropagator().Inject(ctx, grpcMetadataCarrier(md))
+		ctx = metadata.NewOutgoingContext(ctx, md)
+
+		err := invoker(ctx, method, req, reply, cc, callOpts...)
+
+		code := status.Code(err)
+		span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int64(int64(code)))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(otelcodes.Error, err.Error())
+		}
+
+		return err
+	}
+}
+
+// grpcMetadataCarrier lets the propagator read and write the grpc metadata
+type grpcMetadataCarrier metadata.MD
+
+func (c grpcMetadataCarrier) Get(key string) string {
+	if v := metadata.MD(c).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (c grpcMetadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c grpcMetadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// splitGrpcMethod splits "/package.Service/Method" into its service and method
+func splitGrpcMethod(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "", fullMethod
+}
+
+func invokeWithTimeout(ctx context.Context, timeout time.Duration, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// isTransientVehicleServiceError reports whether the call may succeed when retried
+func isTransientVehicleServiceError(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+		return true
+	}
+
+	return false
+}
+
+// isVehicleServiceFailure reports whether the error counts against the circuit breaker,
+// errors caused by the request itself, e.g. NotFound, do not
+func isVehicleServiceFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted,
+		codes.Internal, codes.Unknown, codes.DataLoss:
+		return true
+	}
+
+	return false
+}
+
+// backoffDuration returns the full jitter exponential backoff of the attempt
+func backoffDuration(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+
+	d := base << (attempt - 1)
+	if d <= 0 || d > maxVehicleServiceBackoff {
+		d = maxVehicleServiceBackoff
+	}
+
+	return time.Duration(rand.Int63n(int64(d)) + 1)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return status.FromContextError(ctx.Err()).Err()
+	case <-t.C:
+		return nil
+	}
+}
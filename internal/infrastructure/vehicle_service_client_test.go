@@ -0,0 +1,152 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	logger "github.com/orkungursel/hey-taxi-location-api/pkg/logger/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNewVehicleServiceInterceptor(t *testing.T) {
+	opts := VehicleServiceClientOptions{
+		Timeout:          50 * time.Millisecond,
+		MaxRetries:       2,
+		Backoff:          time.Millisecond,
+		BreakerThreshold: 100,
+		BreakerTimeout:   time.Minute,
+	}
+
+	tests := []struct {
+		name      string
+		opts      VehicleServiceClientOptions
+		results   []error
+		wantCalls int
+		wantCode  codes.Code
+	}{
+		{
+			name:      "should not retry on success",
+			opts:      opts,
+			results:   []error{nil},
+			wantCalls: 1,
+			wantCode:  codes.OK,
+		},
+		{
+			name:      "should retry transient errors",
+			opts:      opts,
+			results:   []error{status.Error(codes.Unavailable, ""), status.Error(codes.Unavailable, ""), nil},
+			wantCalls: 3,
+			wantCode:  codes.OK,
+		},
+		{
+			name:      "should give up after max retries",
+			opts:      opts,
+			results:   []error{status.Error(codes.Unavailable, ""), status.Error(codes.Unavailable, ""), status.Error(codes.Unavailable, "")},
+			wantCalls: 3,
+			wantCode:  codes.Unavailable,
+		},
+		{
+			name:      "should not retry not found",
+			opts:      opts,
+			results:   []error{status.Error(codes.NotFound, "")},
+			wantCalls: 1,
+			wantCode:  codes.NotFound,
+		},
+		{
+			name:      "should apply the deadline to every attempt",
+			opts:      opts,
+			results:   []error{errSlowCall, nil},
+			wantCalls: 2,
+			wantCode:  codes.OK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			invoker := func(ctx context.Context, _ string, _, _ interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+				err := tt.results[calls]
+				calls++
+				if err == errSlowCall {
+					<-ctx.Done()
+					return status.FromContextError(ctx.Err()).Err()
+				}
+				return err
+			}
+
+			interceptor := NewVehicleServiceInterceptor(tt.opts, logger.NewLoggerMock())
+			err := interceptor(context.Background(), "/vehicle.VehicleService/GetVehicle", nil, nil, nil, invoker)
+
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("interceptor() code = %v, want %v", got, tt.wantCode)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("interceptor() calls = %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+var errSlowCall = errors.New("slow call")
+
+func TestNewVehicleServiceInterceptor_CircuitBreaker(t *testing.T) {
+	interceptor := NewVehicleServiceInterceptor(VehicleServiceClientOptions{
+		BreakerThreshold: 2,
+		BreakerTimeout:   time.Minute,
+	}, logger.NewLoggerMock())
+
+	calls := 0
+	invoker := func(context.Context, string, interface{}, interface{}, *grpc.ClientConn, ...grpc.CallOption) error {
+		calls++
+		return status.Error(codes.Unavailable, "")
+	}
+
+	for i := 0; i < 2; i++ {
+		_ = interceptor(context.Background(), "/vehicle.VehicleService/GetVehicle", nil, nil, nil, invoker)
+	}
+
+	// fails fast without calling the service
+	err := interceptor(context.Background(), "/vehicle.VehicleService/GetVehicle", nil, nil, nil, invoker)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("interceptor() error = %v, want %v", err, ErrCircuitOpen)
+	}
+	if calls != 2 {
+		t.Errorf("interceptor() calls = %v, want 2", calls)
+	}
+}
+
+func TestBackoffDuration(t *testing.T) {
+	for attempt := 1; attempt < 40; attempt++ {
+		d := backoffDuration(100*time.Millisecond, attempt)
+		if d <= 0 || d > maxVehicleServiceBackoff {
+			t.Errorf("backoffDuration(%d) = %v", attempt, d)
+		}
+	}
+
+	if d := backoffDuration(0, 1); d != 0 {
+		t.Errorf("backoffDuration() = %v, want 0", d)
+	}
+}
+
+func TestVehicleServiceClientOptions_MaxDuration(t *testing.T) {
+	tests := []struct {
+		name string
+		opts VehicleServiceClientOptions
+		want time.Duration
+	}{
+		{"should cover every attempt and backoff", VehicleServiceClientOptions{Timeout: time.Second, MaxRetries: 2},
+			3*time.Second + 2*maxVehicleServiceBackoff},
+		{"should be a single attempt without retries", VehicleServiceClientOptions{Timeout: time.Second}, time.Second},
+		{"should be unbounded without timeout", VehicleServiceClientOptions{MaxRetries: 2}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.MaxDuration(); got != tt.want {
+				t.Errorf("VehicleServiceClientOptions.MaxDuration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/orkungursel/hey-taxi-location-api/internal/app"
+	"google.golang.org/grpc/credentials"
+)
+
+const (
+	authorizationMetadataKey = "authorization" // authorizationMetadataKey carries the bearer token to the vehicle service
+)
+
+// VehicleServiceTLSOptions configures the TLS of the vehicle service connection,
+// the client certificate is optional and enables mTLS
+type VehicleServiceTLSOptions struct {
+	CaFile     string // CA bundle to verify the server, the system pool is used if empty
+	ServerName string // overrides the name verified in the server certificate
+	CertFile   string
+	KeyFile    string
+}
+
+// NewVehicleServiceTransportCredentials returns the TLS credentials of the options
+func NewVehicleServiceTransportCredentials(opts VehicleServiceTLSOptions) (credentials.TransportCredentials, error) {
+	cfg := &tls.Config{
+		ServerName: opts.ServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if opts.CaFile != "" {
+		b, err := ioutil.ReadFile(opts.CaFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read vehicle service ca: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, errors.New("no certificate found in vehicle service ca")
+		}
+		cfg.RootCAs = pool
+	}
+
+	if (opts.CertFile == "") != (opts.KeyFile == "") {
+		return nil, errors.New("both vehicle service client cert and key are required")
+	}
+
+	if opts.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load vehicle service client cert: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return credentials.NewTLS(cfg), nil
+}
+
+// VehicleServiceRPCCredentials attaches a bearer token to every call, either
+// the access token of the request being served or a static service token
+type VehicleServiceRPCCredentials struct {
+	token      string
+	forward    bool
+	requireTLS bool
+}
+
+// NewVehicleServiceRPCCredentials returns the per-RPC credentials, the forwarded token
+// takes precedence over the static one. requireTLS refuses to send them in plaintext.
+func NewVehicleServiceRPCCredentials(token string, forward, requireTLS bool) *VehicleServiceRPCCredentials {
+	return &VehicleServiceRPCCredentials{
+		token:      token,
+		forward:    forward,
+		requireTLS: requireTLS,
+	}
+}
+
+func (c *VehicleServiceRPCCredentials) GetRequestMetadata(ctx context.Context,
+	uri ...string) (map[string]string, error) {
+
+	token := c.token
+	if c.forward {
+		if t := app.AccessTokenFromContext(ctx); t != "" {
+			token = t
+		}
+	}
+
+	if token == "" {
+		return nil, nil
+	}
+
+	return map[string]string{authorizationMetadataKey: "Bearer " + token}, nil
+}
+
+func (c *VehicleServiceRPCCredentials) RequireTransportSecurity() bool {
+	return c.requireTLS
+}
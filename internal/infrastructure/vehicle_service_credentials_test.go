@@ -0,0 +1,284 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-location-api/internal/app"
+	"github.com/orkungursel/hey-taxi-location-api/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const testVehicleServiceName = "vehicle-service.internal"
+
+// testCertificate is a certificate and its key written as PEM files
+type testCertificate struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+func newTestCertificate(t *testing.T, name string, parent *testCertificate, isCA bool,
+	usage x509.ExtKeyUsage) *testCertificate {
+
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	if !isCA {
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{usage}
+	}
+
+	signer, signerKey := tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	c := &testCertificate{
+		cert:     cert,
+		key:      key,
+		certFile: filepath.Join(dir, "cert.pem"),
+		keyFile:  filepath.Join(dir, "key.pem"),
+	}
+	_ = ioutil.WriteFile(c.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	_ = ioutil.WriteFile(c.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+
+	return c
+}
+
+// stubVehicleServiceServer answers with the client certificate name and the authorization metadata
+type stubVehicleServiceServer struct {
+	proto.UnimplementedVehicleServiceServer
+}
+
+func (stubVehicleServiceServer) GetVehicle(ctx context.Context, in *proto.GetVehicleRequest) (*proto.GetVehicleResponse, error) {
+	res := &proto.GetVehicleResponse{Id: in.Id, Driver: &proto.DriverDetailsResponse{}}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.PeerCertificates) > 0 {
+			res.Name = info.State.PeerCertificates[0].Subject.CommonName
+		}
+	}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get(authorizationMetadataKey)) > 0 {
+		res.Plate = md.Get(authorizationMetadataKey)[0]
+	}
+
+	return res, nil
+}
+
+func startStubVehicleService(t *testing.T, ca *testCertificate, server *testCertificate, mtls bool) string {
+	t.Helper()
+
+	cert, err := tls.LoadX509KeyPair(server.certFile, server.keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &tls.Config{Certificates: []tls.Certificate{cert}}
+	if mtls {
+		pool := x509.NewCertPool()
+		pool.AddCert(ca.cert)
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := grpc.NewServer(grpc.Creds(credentials.NewTLS(cfg)))
+	proto.RegisterVehicleServiceServer(s, stubVehicleServiceServer{})
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+
+	return lis.Addr().String()
+}
+
+func TestVehicleServiceCredentials(t *testing.T) {
+	ca := newTestCertificate(t, "test-ca", nil, true, 0)
+	otherCa := newTestCertificate(t, "other-ca", nil, true, 0)
+	server := newTestCertificate(t, testVehicleServiceName, ca, false, x509.ExtKeyUsageServerAuth)
+	client := newTestCertificate(t, "location-api", ca, false, x509.ExtKeyUsageClientAuth)
+
+	tlsAddr := startStubVehicleService(t, ca, server, false)
+	mtlsAddr := startStubVehicleService(t, ca, server, true)
+
+	tests := []struct {
+		name      string
+		addr      string
+		opts      VehicleServiceTLSOptions
+		rpc       *VehicleServiceRPCCredentials
+		ctx       context.Context
+		wantName  string
+		wantPlate string
+		wantErr   bool
+	}{
+		{
+			name: "should connect with tls",
+			addr: tlsAddr,
+			opts: VehicleServiceTLSOptions{CaFile: ca.certFile, ServerName: testVehicleServiceName},
+		},
+		{
+			name:    "should fail when server name does not match",
+			addr:    tlsAddr,
+			opts:    VehicleServiceTLSOptions{CaFile: ca.certFile, ServerName: "other.internal"},
+			wantErr: true,
+		},
+		{
+			name:    "should fail when server is not signed by the ca",
+			addr:    tlsAddr,
+			opts:    VehicleServiceTLSOptions{CaFile: otherCa.certFile, ServerName: testVehicleServiceName},
+			wantErr: true,
+		},
+		{
+			name: "should connect with mtls",
+			addr: mtlsAddr,
+			opts: VehicleServiceTLSOptions{
+				CaFile:     ca.certFile,
+				ServerName: testVehicleServiceName,
+				CertFile:   client.certFile,
+				KeyFile:    client.keyFile,
+			},
+			wantName: "location-api",
+		},
+		{
+			name:    "should fail with mtls without client certificate",
+			addr:    mtlsAddr,
+			opts:    VehicleServiceTLSOptions{CaFile: ca.certFile, ServerName: testVehicleServiceName},
+			wantErr: true,
+		},
+		{
+			name:      "should send the static token",
+			addr:      tlsAddr,
+			opts:      VehicleServiceTLSOptions{CaFile: ca.certFile, ServerName: testVehicleServiceName},
+			rpc:       NewVehicleServiceRPCCredentials("service-token", true, true),
+			wantPlate: "Bearer service-token",
+		},
+		{
+			name:      "should forward the access token of the request",
+			addr:      tlsAddr,
+			opts:      VehicleServiceTLSOptions{CaFile: ca.certFile, ServerName: testVehicleServiceName},
+			rpc:       NewVehicleServiceRPCCredentials("service-token", true, true),
+			ctx:       app.WithAccessToken(context.Background(), "user-token"),
+			wantPlate: "Bearer user-token",
+		},
+		{
+			name:      "should not forward the access token when disabled",
+			addr:      tlsAddr,
+			opts:      VehicleServiceTLSOptions{CaFile: ca.certFile, ServerName: testVehicleServiceName},
+			rpc:       NewVehicleServiceRPCCredentials("", false, true),
+			ctx:       app.WithAccessToken(context.Background(), "user-token"),
+			wantPlate: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			creds, err := NewVehicleServiceTransportCredentials(tt.opts)
+			if err != nil {
+				t.Fatalf("NewVehicleServiceTransportCredentials() error = %v", err)
+			}
+
+			dialOpts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+			if tt.rpc != nil {
+				dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(tt.rpc))
+			}
+
+			conn, err := grpc.Dial(tt.addr, dialOpts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			defer cancel()
+
+			res, err := proto.NewVehicleServiceClient(conn).GetVehicle(ctx, &proto.GetVehicleRequest{Id: "v1"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetVehicle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if status.Code(err) != codes.Unavailable {
+					t.Errorf("GetVehicle() code = %v, want %v", status.Code(err), codes.Unavailable)
+				}
+				return
+			}
+
+			if res.Name != tt.wantName {
+				t.Errorf("GetVehicle() client certificate = %q, want %q", res.Name, tt.wantName)
+			}
+			if res.Plate != tt.wantPlate {
+				t.Errorf("GetVehicle() authorization = %q, want %q", res.Plate, tt.wantPlate)
+			}
+		})
+	}
+}
+
+func TestNewVehicleServiceTransportCredentials_Invalid(t *testing.T) {
+	ca := newTestCertificate(t, "test-ca", nil, true, 0)
+
+	tests := []struct {
+		name string
+		opts VehicleServiceTLSOptions
+	}{
+		{name: "should fail when ca file does not exist", opts: VehicleServiceTLSOptions{CaFile: "/not/exist"}},
+		{name: "should fail when ca file has no certificate", opts: VehicleServiceTLSOptions{CaFile: ca.keyFile}},
+		{name: "should fail when key is missing", opts: VehicleServiceTLSOptions{CertFile: ca.certFile}},
+		{name: "should fail when key does not match", opts: VehicleServiceTLSOptions{CertFile: ca.certFile, KeyFile: ca.certFile}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewVehicleServiceTransportCredentials(tt.opts); err == nil {
+				t.Error("NewVehicleServiceTransportCredentials() should fail")
+			}
+		})
+	}
+}
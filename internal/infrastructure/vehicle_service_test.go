@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/orkungursel/hey-taxi-location-api/internal/app"
@@ -13,6 +15,9 @@ import (
 	logger "github.com/orkungursel/hey-taxi-location-api/pkg/logger/mock"
 	"github.com/orkungursel/hey-taxi-location-api/proto"
 	protoMock "github.com/orkungursel/hey-taxi-location-api/proto/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var vehicle1 = &model.Vehicle{
@@ -141,11 +146,7 @@ func TestVehicleService_GetVehicleById(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			vs := &VehicleService{
-				repo:   tt.fields.repo(),
-				client: tt.fields.client(),
-				logger: logger.NewLoggerMock(),
-			}
+			vs := NewVehicleService(logger.NewLoggerMock(), tt.fields.client(), tt.fields.repo(), nil, time.Second, nil)
 			got, err := vs.GetVehicleById(tt.args.ctx, tt.args.vehicleId)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("VehicleService.GetVehicleById() error = %v, wantErr %v", err, tt.wantErr)
@@ -157,3 +158,362 @@ func TestVehicleService_GetVehicleById(t *testing.T) {
 		})
 	}
 }
+
+func TestVehicleService_PurgeVehicle(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mock.NewMockVehicleRepository(ctrl)
+	repo.EXPECT().Delete(gomock.Any(), vehicle1.Id).Return(nil).Times(1)
+
+	vs := NewVehicleService(logger.NewLoggerMock(), protoMock.NewMockVehicleServiceClient(ctrl), repo,
+		nil, time.Second, nil)
+
+	if err := vs.PurgeVehicle(context.Background(), vehicle1.Id); err != nil {
+		t.Errorf("VehicleService.PurgeVehicle() error = %v", err)
+	}
+	if err := vs.PurgeVehicle(context.Background(), ""); err == nil {
+		t.Error("VehicleService.PurgeVehicle() should fail with empty vehicle id")
+	}
+}
+
+func TestVehicleService_RefreshVehicle(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mock.NewMockVehicleRepository(ctrl)
+	repo.EXPECT().Get(gomock.Any(), gomock.Any()).Times(0)
+	repo.EXPECT().Save(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+	client := protoMock.NewMockVehicleServiceClient(ctrl)
+	client.EXPECT().GetVehicle(gomock.Any(), gomock.Any()).Return(&proto.GetVehicleResponse{
+		Id:     vehicle1.Id,
+		Plate:  "new plate",
+		Driver: &proto.DriverDetailsResponse{Id: vehicle1.Driver.Id},
+	}, nil).Times(1)
+
+	vs := NewVehicleService(logger.NewLoggerMock(), client, repo, nil, time.Second, nil)
+
+	got, err := vs.RefreshVehicle(context.Background(), vehicle1.Id)
+	if err != nil {
+		t.Fatalf("VehicleService.RefreshVehicle() error = %v", err)
+	}
+	if got.Plate != "new plate" {
+		t.Errorf("VehicleService.RefreshVehicle() = %v, want plate %v", got, "new plate")
+	}
+}
+
+func TestVehicleService_GetVehicleById_Cache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	release := make(chan struct{})
+
+	repo := mock.NewMockVehicleRepository(ctrl)
+	repo.EXPECT().Get(gomock.Any(), vehicle1.Id).DoAndReturn(
+		func(context.Context, string) (*model.Vehicle, error) {
+			<-release
+			return vehicle1, nil
+		}).Times(1)
+
+	vs := NewVehicleService(logger.NewLoggerMock(), protoMock.NewMockVehicleServiceClient(ctrl), repo,
+		NewVehicleLRU(10, time.Minute, 0), time.Second, nil)
+
+	// concurrent misses share a single repository lookup
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got, err := vs.GetVehicleById(context.Background(), vehicle1.Id); err != nil || got != vehicle1 {
+				t.Errorf("VehicleService.GetVehicleById() = %v, %v, want %v", got, err, vehicle1)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	// served from the in-process cache
+	if got, err := vs.GetVehicleById(context.Background(), vehicle1.Id); err != nil || got != vehicle1 {
+		t.Errorf("VehicleService.GetVehicleById() = %v, %v, want %v", got, err, vehicle1)
+	}
+
+	// other tenants do not share the entry
+	acme := app.WithTenant(context.Background(), "acme")
+	repo.EXPECT().Get(gomock.Any(), vehicle1.Id).Return(vehicle1, nil).Times(2)
+	if _, err := vs.GetVehicleById(acme, vehicle1.Id); err != nil {
+		t.Fatalf("VehicleService.GetVehicleById() error = %v", err)
+	}
+
+	// purge evicts the in-process entry too
+	repo.EXPECT().Delete(gomock.Any(), vehicle1.Id).Return(nil).Times(1)
+	if err := vs.PurgeVehicle(acme, vehicle1.Id); err != nil {
+		t.Fatalf("VehicleService.PurgeVehicle() error = %v", err)
+	}
+	if _, err := vs.GetVehicleById(acme, vehicle1.Id); err != nil {
+		t.Fatalf("VehicleService.GetVehicleById() error = %v", err)
+	}
+}
+
+func TestVehicleService_GetVehicleById_Canceled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	repo := mock.NewMockVehicleRepository(ctrl)
+	repo.EXPECT().Get(gomock.Any(), vehicle1.Id).Return(nil, nil).Times(1)
+	repo.EXPECT().Save(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+	client := protoMock.NewMockVehicleServiceClient(ctrl)
+	client.EXPECT().GetVehicle(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, _ *proto.GetVehicleRequest, _ ...grpc.CallOption) (*proto.GetVehicleResponse, error) {
+			close(started)
+			<-release
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return &proto.GetVehicleResponse{Id: vehicle1.Id, Driver: &proto.DriverDetailsResponse{}}, nil
+		}).Times(1)
+
+	vs := NewVehicleService(logger.NewLoggerMock(), client, repo, NewVehicleLRU(10, time.Minute, 0), time.Second, nil)
+
+	first, cancel := context.WithCancel(app.WithTenant(context.Background(), "acme"))
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := vs.GetVehicleById(first, vehicle1.Id)
+		firstErr <- err
+	}()
+	<-started
+
+	second := make(chan *model.Vehicle, 1)
+	go func() {
+		got, err := vs.GetVehicleById(app.WithTenant(context.Background(), "acme"), vehicle1.Id)
+		if err != nil {
+			t.Errorf("VehicleService.GetVehicleById() error = %v", err)
+		}
+		second <- got
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// the first caller gives up, the shared lookup goes on for the second one
+	cancel()
+	select {
+	case err := <-firstErr:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("VehicleService.GetVehicleById() error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("VehicleService.GetVehicleById() did not return after the cancel")
+	}
+
+	close(release)
+	if got := <-second; got == nil || got.Id != vehicle1.Id {
+		t.Errorf("VehicleService.GetVehicleById() = %v, want %v", got, vehicle1.Id)
+	}
+}
+
+func TestVehicleService_GetVehicleById_NotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mock.NewMockVehicleRepository(ctrl)
+	repo.EXPECT().Get(gomock.Any(), "unknown").Return(nil, nil).Times(1)
+	repo.EXPECT().Save(gomock.Any(), gomock.Any()).Times(0)
+
+	client := protoMock.NewMockVehicleServiceClient(ctrl)
+	client.EXPECT().GetVehicle(gomock.Any(), gomock.Any()).
+		Return(nil, status.Error(codes.NotFound, "not found")).Times(1)
+
+	vs := NewVehicleService(logger.NewLoggerMock(), client, repo,
+		NewVehicleLRU(10, time.Minute, time.Minute), time.Second, nil)
+
+	// the absence is cached, so the second call does not reach redis or grpc
+	for i := 0; i < 2; i++ {
+		if _, err := vs.GetVehicleById(context.Background(), "unknown"); !errors.Is(err, app.ErrVehicleNotFound) {
+			t.Errorf("VehicleService.GetVehicleById() error = %v, want %v", err, app.ErrVehicleNotFound)
+		}
+	}
+}
+
+func TestVehicleService_GetVehicleById_Stale(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mock.NewMockVehicleRepository(ctrl)
+	repo.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+
+	client := protoMock.NewMockVehicleServiceClient(ctrl)
+	client.EXPECT().GetVehicle(gomock.Any(), gomock.Any()).Return(nil, ErrCircuitOpen).Times(2)
+
+	cache := NewVehicleLRU(10, time.Minute, 0)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	cache.Add(vehicle1.Id, vehicle1)
+	now = now.Add(time.Hour)
+
+	vs := NewVehicleService(logger.NewLoggerMock(), client, repo, cache, time.Second, nil)
+
+	got, err := vs.GetVehicleById(context.Background(), vehicle1.Id)
+	if err != nil || got != vehicle1 {
+		t.Errorf("VehicleService.GetVehicleById() = %v, %v, want stale %v", got, err, vehicle1)
+	}
+
+	if _, err := vs.GetVehicleById(context.Background(), "unknown"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("VehicleService.GetVehicleById() error = %v, want %v", err, ErrCircuitOpen)
+	}
+}
+
+func TestVehicleService_GetVehiclesByIds(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cached := &model.Vehicle{Id: "cached"}
+	redis := &model.Vehicle{Id: "redis"}
+	upstream := &proto.GetVehicleResponse{Id: "upstream", Driver: &proto.DriverDetailsResponse{Id: "driver"}}
+	want := map[string]*model.Vehicle{
+		"cached":   cached,
+		"redis":    redis,
+		"upstream": MapGrpcVehicleToDomain(upstream),
+	}
+	ids := []string{"cached", "redis", "upstream", "unknown", "cached", "unknown"}
+
+	tests := []struct {
+		name   string
+		client func() proto.VehicleServiceClient
+		want   map[string]*model.Vehicle
+	}{
+		{
+			name: "should fetch the misses with a single call",
+			client: func() proto.VehicleServiceClient {
+				client := protoMock.NewMockVehicleServiceClient(ctrl)
+				client.EXPECT().GetVehicles(gomock.Any(), &proto.GetVehiclesRequest{Ids: []string{"upstream", "unknown"}}).
+					Return(&proto.GetVehiclesResponse{Vehicles: []*proto.GetVehicleResponse{upstream}}, nil).Times(1)
+				client.EXPECT().GetVehicle(gomock.Any(), gomock.Any()).Times(0)
+				return client
+			},
+			want: want,
+		},
+		{
+			name: "should fall back to single calls when bulk call is unimplemented",
+			client: func() proto.VehicleServiceClient {
+				client := protoMock.NewMockVehicleServiceClient(ctrl)
+				client.EXPECT().GetVehicles(gomock.Any(), gomock.Any()).
+					Return(nil, status.Error(codes.Unimplemented, "")).Times(1)
+				client.EXPECT().GetVehicle(gomock.Any(), &proto.GetVehicleRequest{Id: "upstream"}).Return(upstream, nil).Times(1)
+				client.EXPECT().GetVehicle(gomock.Any(), &proto.GetVehicleRequest{Id: "unknown"}).
+					Return(nil, status.Error(codes.NotFound, "")).Times(1)
+				return client
+			},
+			want: want,
+		},
+		{
+			name: "should serve only the cached vehicles when the circuit is open",
+			client: func() proto.VehicleServiceClient {
+				client := protoMock.NewMockVehicleServiceClient(ctrl)
+				client.EXPECT().GetVehicles(gomock.Any(), gomock.Any()).Return(nil, ErrCircuitOpen).Times(1)
+				return client
+			},
+			want: map[string]*model.Vehicle{"cached": cached, "redis": redis},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the misses are read from the repository at once, without the duplicates
+			repo := mock.NewMockVehicleRepository(ctrl)
+			repo.EXPECT().GetMany(gomock.Any(), []string{"redis", "upstream", "unknown"}).
+				Return(map[string]*model.Vehicle{"redis": redis}, nil).Times(1)
+			repo.EXPECT().Get(gomock.Any(), gomock.Any()).Times(0)
+			repo.EXPECT().Save(gomock.Any(), gomock.Any()).Return(nil).MaxTimes(1)
+
+			cache := NewVehicleLRU(10, time.Minute, time.Minute)
+			cache.Add("cached", cached)
+
+			vs := NewVehicleService(logger.NewLoggerMock(), tt.client(), repo, cache, time.Second, nil)
+
+			got, err := vs.GetVehiclesByIds(context.Background(), ids)
+			if err != nil {
+				t.Fatalf("VehicleService.GetVehiclesByIds() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("VehicleService.GetVehiclesByIds() = %v, want %v", got, tt.want)
+			}
+
+			// the unknown vehicle is remembered unless the vehicle service was not asked
+			if _, ok := cache.Get("unknown"); ok != (len(tt.want) == len(want)) {
+				t.Errorf("VehicleLRU.Get() ok = %v for unknown vehicle", ok)
+			}
+		})
+	}
+}
+
+func TestVehicleService_GetVehiclesByIds_Unimplemented(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mock.NewMockVehicleRepository(ctrl)
+	repo.EXPECT().GetMany(gomock.Any(), gomock.Any()).Return(nil, nil).Times(2)
+	repo.EXPECT().Save(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+
+	client := protoMock.NewMockVehicleServiceClient(ctrl)
+	client.EXPECT().GetVehicles(gomock.Any(), gomock.Any()).
+		Return(nil, status.Error(codes.Unimplemented, "")).Times(1)
+	client.EXPECT().GetVehicle(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, in *proto.GetVehicleRequest, _ ...grpc.CallOption) (*proto.GetVehicleResponse, error) {
+			return &proto.GetVehicleResponse{Id: in.Id, Driver: &proto.DriverDetailsResponse{}}, nil
+		}).Times(2)
+
+	vs := NewVehicleService(logger.NewLoggerMock(), client, repo, NewVehicleLRU(10, time.Minute, 0), time.Second, nil)
+
+	// GetVehicles is not asked again once it is known to be unimplemented
+	for _, id := range []string{"v1", "v2"} {
+		got, err := vs.GetVehiclesByIds(context.Background(), []string{id})
+		if err != nil || got[id] == nil {
+			t.Errorf("VehicleService.GetVehiclesByIds() = %v, %v, want %v", got, err, id)
+		}
+	}
+}
+
+func TestVehicleService_GetVehiclesByIds_Inflight(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	repo := mock.NewMockVehicleRepository(ctrl)
+	repo.EXPECT().Get(gomock.Any(), vehicle1.Id).DoAndReturn(
+		func(context.Context, string) (*model.Vehicle, error) {
+			close(started)
+			<-release
+			return vehicle1, nil
+		}).Times(1)
+	repo.EXPECT().GetMany(gomock.Any(), []string{"other"}).
+		Return(map[string]*model.Vehicle{"other": {Id: "other"}}, nil).Times(1)
+
+	vs := NewVehicleService(logger.NewLoggerMock(), protoMock.NewMockVehicleServiceClient(ctrl), repo,
+		NewVehicleLRU(10, time.Minute, 0), time.Second, nil)
+
+	go func() {
+		_, _ = vs.GetVehicleById(context.Background(), vehicle1.Id)
+	}()
+	<-started
+
+	// the batch waits for the single lookup in flight instead of fetching it again
+	res := make(chan map[string]*model.Vehicle, 1)
+	go func() {
+		got, err := vs.GetVehiclesByIds(context.Background(), []string{vehicle1.Id, "other"})
+		if err != nil {
+			t.Errorf("VehicleService.GetVehiclesByIds() error = %v", err)
+		}
+		res <- got
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	if got := <-res; got[vehicle1.Id] != vehicle1 || got["other"] == nil {
+		t.Errorf("VehicleService.GetVehiclesByIds() = %v, want %v and other", got, vehicle1.Id)
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	emw "github.com/labstack/echo/v4/middleware"
 	"github.com/orkungursel/hey-taxi-location-api/internal/server/middleware"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/tracing"
 )
 
 // configure the echo server
@@ -12,9 +13,21 @@ func (s *Server) configure() {
 	s.echo.HidePort = true
 	s.echo.HideBanner = true
 
+	// the access token passed by the query is not logged or traced
+	var redacted []string
+	if name := s.config.Jwt.QueryParam; name != "" {
+		redacted = append(redacted, name)
+	}
+
 	// add pre middlewares
 	s.echo.Pre(middleware.AddTrailingSlash())
-	s.echo.Pre(middleware.Logger(s.logger))
+	s.echo.Pre(middleware.Logger(s.logger, redacted...))
+	if tracing.Enabled(s.config) {
+		s.echo.Pre(middleware.Tracing(redacted...))
+	}
+	if s.metrics != nil {
+		s.echo.Pre(middleware.Metrics(s.metrics))
+	}
 
 	// add middlewares
 	s.echo.Use(emw.Recover())
@@ -6,6 +6,8 @@ func (s *Server) mapHandlers() {
 	s.echo.GET("/", func(c echo.Context) error {
 		return c.JSON(200, map[string]string{"service": s.config.App.Name})
 	})
+	s.echo.GET("/healthz/", s.liveness())
+	s.echo.GET("/readyz/", s.readiness())
 
 	root := s.echo.Group("/api/v1")
 
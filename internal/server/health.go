@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HealthStatusOk          = "ok"
+	HealthStatusError       = "error"
+	HealthStatusUnavailable = "unavailable"
+	HealthStatusDraining    = "draining" // the server is shutting down, no new requests please
+)
+
+// HealthCheck checks a dependency of the server, nil means it is available
+type HealthCheck func(ctx context.Context) error
+
+type healthCheckItem struct {
+	name  string
+	check HealthCheck
+}
+
+// HealthStatus is the response of the health endpoints
+type HealthStatus struct {
+	Status string                       `json:"status"`
+	Checks map[string]HealthCheckStatus `json:"checks,omitempty"`
+}
+
+// HealthCheckStatus is the status of a single dependency, the error itself is
+// only logged as it may hold addresses of the internal services
+type HealthCheckStatus struct {
+	Status string `json:"status"`
+}
+
+// RegisterHealthCheck adds a dependency check to the readiness endpoint, the
+// plugins register them before the http server is started
+func (s *Server) RegisterHealthCheck(name string, check HealthCheck) {
+	s.healthChecks = append(s.healthChecks, healthCheckItem{name: name, check: check})
+}
+
+// Draining returns true once the server is shutting down
+func (s *Server) Draining() bool {
+	return atomic.LoadInt32(&s.draining) == 1
+}
+
+func (s *Server) drain() {
+	atomic.StoreInt32(&s.draining, 1)
+}
+
+// liveness answers as long as the process serves requests
+func (s *Server) liveness() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, HealthStatus{Status: HealthStatusOk})
+	}
+}
+
+// readiness runs the dependency checks concurrently, the server is ready if
+// all of them pass and it is not draining
+func (s *Server) readiness() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if s.Draining() {
+			return c.JSON(http.StatusServiceUnavailable, HealthStatus{Status: HealthStatusDraining})
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request().Context(),
+			time.Duration(s.config.Server.Http.ReadinessTimeout)*time.Second)
+		defer cancel()
+
+		res := s.checkHealth(ctx)
+
+		code := http.StatusOK
+		if res.Status != HealthStatusOk {
+			code = http.StatusServiceUnavailable
+		}
+
+		return c.JSON(code, res)
+	}
+}
+
+func (s *Server) checkHealth(ctx context.Context) HealthStatus {
+	res := HealthStatus{
+		Status: HealthStatusOk,
+		Checks: make(map[string]HealthCheckStatus, len(s.healthChecks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, item := range s.healthChecks {
+		wg.Add(1)
+		go func(item healthCheckItem) {
+			defer wg.Done()
+
+			status := HealthCheckStatus{Status: HealthStatusOk}
+			if err := item.check(ctx); err != nil {
+				s.logger.Warnf("health check %s failed: %v", item.name, err)
+				status = HealthCheckStatus{Status: HealthStatusError}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			res.Checks[item.name] = status
+			if status.Status != HealthStatusOk {
+				res.Status = HealthStatusUnavailable
+			}
+		}(item)
+	}
+	wg.Wait()
+
+	return res
+}
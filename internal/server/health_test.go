@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/orkungursel/hey-taxi-location-api/config"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger/mock"
+)
+
+func TestServer_Health(t *testing.T) {
+	ok := func(ctx context.Context) error { return nil }
+	failed := func(ctx context.Context) error { return errors.New("connection refused") }
+
+	tests := []struct {
+		name     string
+		path     string
+		checks   map[string]HealthCheck
+		draining bool
+		wantCode int
+		want     HealthStatus
+	}{
+		{
+			name:     "should be alive",
+			path:     "/healthz",
+			checks:   map[string]HealthCheck{"redis": failed},
+			wantCode: http.StatusOK,
+			want:     HealthStatus{Status: HealthStatusOk},
+		},
+		{
+			name:     "should be ready if all checks pass",
+			path:     "/readyz",
+			checks:   map[string]HealthCheck{"redis": ok, "token_keys": ok},
+			wantCode: http.StatusOK,
+			want: HealthStatus{Status: HealthStatusOk, Checks: map[string]HealthCheckStatus{
+				"redis":      {Status: HealthStatusOk},
+				"token_keys": {Status: HealthStatusOk},
+			}},
+		},
+		{
+			name:     "should not be ready if a check fails",
+			path:     "/readyz",
+			checks:   map[string]HealthCheck{"redis": failed, "token_keys": ok},
+			wantCode: http.StatusServiceUnavailable,
+			want: HealthStatus{Status: HealthStatusUnavailable, Checks: map[string]HealthCheckStatus{
+				"redis":      {Status: HealthStatusError},
+				"token_keys": {Status: HealthStatusOk},
+			}},
+		},
+		{
+			name:     "should not be ready while draining",
+			path:     "/readyz",
+			checks:   map[string]HealthCheck{"redis": ok},
+			draining: true,
+			wantCode: http.StatusServiceUnavailable,
+			want:     HealthStatus{Status: HealthStatusDraining},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(context.Background(), config.New(), mock.NewLoggerMock())
+			for name, check := range tt.checks {
+				s.RegisterHealthCheck(name, check)
+			}
+			if tt.draining {
+				s.drain()
+			}
+
+			s.configure()
+			s.mapHandlers()
+
+			rec := httptest.NewRecorder()
+			s.echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %v, want %v", rec.Code, tt.wantCode)
+			}
+
+			// the errors are logged, not returned to the unauthenticated callers
+			if strings.Contains(rec.Body.String(), "connection refused") {
+				t.Errorf("body = %s, want no error details", rec.Body.String())
+			}
+
+			var got HealthStatus
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("body = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
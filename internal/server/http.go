@@ -27,9 +27,20 @@ func (s *Server) startHttpServer(ctx context.Context, cancel context.CancelFunc)
 }
 
 // shutdownHttpServer stops the server
-func (s *Server) shutdownHttpServer(ctx context.Context) error {
+func (s *Server) shutdownHttpServer() error {
+	// the readiness fails during the drain delay, so the load balancer stops
+	// sending new requests before the listener is closed
+	s.drain()
+	if delay := time.Duration(s.config.Server.Http.DrainDelay) * time.Second; delay > 0 {
+		s.logger.Infof("draining for %s...", delay)
+		time.Sleep(delay)
+	}
+
 	s.logger.Info("stopping server...")
-	ctx, cancel := context.WithTimeout(ctx, time.Duration(s.config.Server.Http.ShutdownTimeout)*time.Second)
+
+	// the signal context is already done, the in-flight requests get their own timeout
+	ctx, cancel := context.WithTimeout(context.Background(),
+		time.Duration(s.config.Server.Http.ShutdownTimeout)*time.Second)
 	defer cancel()
 
 	if err := s.echo.Shutdown(ctx); err != nil {
@@ -37,7 +48,6 @@ func (s *Server) shutdownHttpServer(ctx context.Context) error {
 		return err
 	}
 
-	<-ctx.Done()
 	s.logger.Info("stopped server...")
 
 	return nil
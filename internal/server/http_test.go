@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/orkungursel/hey-taxi-location-api/config"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/logger/mock"
+)
+
+type slowHandler struct {
+	started chan struct{}
+}
+
+func (h *slowHandler) RegisterRoutes(e *echo.Group) {
+	e.GET("/", func(c echo.Context) error {
+		close(h.started)
+		time.Sleep(100 * time.Millisecond)
+		return c.NoContent(http.StatusNoContent)
+	})
+}
+
+func TestServer_ShutdownHttpServer(t *testing.T) {
+	c := config.New()
+	c.Server.Http.Host = "127.0.0.1"
+	c.Server.Http.Port = "0"
+	c.Server.Http.DrainDelay = 0
+
+	s := New(context.Background(), c, mock.NewLoggerMock())
+	h := &slowHandler{started: make(chan struct{})}
+	if err := s.RegisterHttpApiAsRoot("/slow", h); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.startHttpServer(ctx, cancel)
+
+	var addr string
+	for i := 0; i < 100 && addr == ""; i++ {
+		time.Sleep(10 * time.Millisecond)
+		if a := s.echo.ListenerAddr(); a != nil {
+			addr = a.String()
+		}
+	}
+	if addr == "" {
+		t.Fatal("server did not start")
+	}
+
+	res := make(chan int, 1)
+	go func() {
+		r, err := http.Get("http://" + addr + "/slow/")
+		if err != nil {
+			res <- 0
+			return
+		}
+		r.Body.Close()
+		res <- r.StatusCode
+	}()
+	<-h.started
+
+	// the signal context is done by now, the in-flight request still completes
+	cancel()
+	if err := s.shutdownHttpServer(); err != nil {
+		t.Fatalf("shutdownHttpServer() error = %v", err)
+	}
+
+	if code := <-res; code != http.StatusNoContent {
+		t.Errorf("in-flight request status = %v, want %v", code, http.StatusNoContent)
+	}
+	if !s.Draining() {
+		t.Error("server is not draining after the shutdown")
+	}
+}
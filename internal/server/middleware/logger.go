@@ -7,7 +7,9 @@ import (
 	"github.com/orkungursel/hey-taxi-location-api/pkg/logger"
 )
 
-func Logger(log logger.ILogger) echo.MiddlewareFunc {
+// Logger logs the requests, the query parameters of the redacted names are
+// removed from the logged uri
+func Logger(log logger.ILogger, redacted ...string) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			start := time.Now()
@@ -17,6 +19,14 @@ func Logger(log logger.ILogger) echo.MiddlewareFunc {
 				c.Error(err)
 			}
 
+			req := c.Request()
+			if uri := redactQuery(req.RequestURI, redacted); uri != req.RequestURI {
+				r := req.WithContext(req.Context())
+				r.RequestURI = uri
+				c.SetRequest(r)
+				defer c.SetRequest(req)
+			}
+
 			log.EchoCtx(c, start)
 
 			return nil
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/metrics"
+)
+
+// Metrics observes the latency of the requests by their route. It runs inside
+// the logger, which renders the errors, so the status code is taken from them.
+func Metrics(m *metrics.Metrics) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+
+			err := next(c)
+
+			code := c.Response().Status
+			if err != nil {
+				code = http.StatusInternalServerError
+
+				var he *echo.HTTPError
+				if errors.As(err, &he) {
+					code = he.Code
+				}
+			}
+
+			// the path template keeps the cardinality low, the paths of no route share one
+			route := c.Path()
+			if route == "" || errors.Is(err, echo.ErrNotFound) || errors.Is(err, echo.ErrMethodNotAllowed) {
+				route = "unmatched"
+			}
+			m.ObserveHttpRequest(c.Request().Method, route, code, time.Since(start))
+
+			return err
+		}
+	}
+}
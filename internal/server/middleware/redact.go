@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/url"
+	"strings"
+)
+
+// redactQuery removes the query parameters of the names from the request uri,
+// e.g. the access token, so they are not logged or traced
+func redactQuery(uri string, names []string) string {
+	i := strings.IndexByte(uri, '?')
+	if i < 0 || len(names) == 0 {
+		return uri
+	}
+
+	params := strings.Split(uri[i+1:], "&")
+	kept := params[:0]
+	for _, p := range params {
+		name := p
+		if j := strings.IndexByte(p, '='); j >= 0 {
+			name = p[:j]
+		}
+		if n, err := url.QueryUnescape(name); err == nil {
+			name = n
+		}
+
+		if !containsString(names, name) {
+			kept = append(kept, p)
+		}
+	}
+
+	if len(kept) == 0 {
+		return uri[:i]
+	}
+
+	return uri[:i+1] + strings.Join(kept, "&")
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import "testing"
+
+func TestRedactQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		uri   string
+		names []string
+		want  string
+	}{
+		{"should keep the uri without query", "/api/v1/location/", []string{"access_token"}, "/api/v1/location/"},
+		{"should keep the uri without names", "/a/?access_token=x", nil, "/a/?access_token=x"},
+		{"should remove the only parameter", "/a/?access_token=x", []string{"access_token"}, "/a/"},
+		{"should keep the other parameters", "/a/?lat=1&access_token=x&lng=2", []string{"access_token"}, "/a/?lat=1&lng=2"},
+		{"should remove the repeated parameter", "/a/?access_token=x&access_token=y", []string{"access_token"}, "/a/"},
+		{"should remove the escaped parameter", "/a/?access%5Ftoken=x&lat=1", []string{"access_token"}, "/a/?lat=1"},
+		{"should keep the parameter with a similar name", "/a/?access_token_hint=x", []string{"access_token"}, "/a/?access_token_hint=x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactQuery(tt.uri, tt.names); got != tt.want {
+				t.Errorf("redactQuery() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
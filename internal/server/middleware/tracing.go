@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing starts a server span for every request, continuing the trace of the
+// caller if it sent a trace context. The span is named after the route once the
+// request is routed. The query parameters of the redacted names are removed
+// from the traced target.
+func Tracing(redacted ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+
+			attrs := semconv.HTTPServerAttributesFromHTTPRequest("", "", req)
+			for i, attr := range attrs {
+				if attr.Key == semconv.HTTPTargetKey {
+					attrs[i] = semconv.HTTPTargetKey.String(redactQuery(req.RequestURI, redacted))
+				}
+			}
+
+			ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+			ctx, span := tracing.Tracer().Start(ctx, "HTTP "+req.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(attrs...),
+				trace.WithAttributes(semconv.NetAttributesFromHTTPRequest("tcp", req)...),
+			)
+			defer span.End()
+
+			c.SetRequest(req.WithContext(ctx))
+
+			err := next(c)
+
+			code := c.Response().Status
+			if err != nil {
+				code = http.StatusInternalServerError
+
+				var he *echo.HTTPError
+				if errors.As(err, &he) {
+					code = he.Code
+				}
+				span.RecordError(err)
+			}
+
+			if route := c.Path(); route != "" && !errors.Is(err, echo.ErrNotFound) {
+				span.SetName(req.Method + " " + route)
+				span.SetAttributes(semconv.HTTPRouteKey.String(route))
+			}
+			span.SetAttributes(semconv.HTTPStatusCodeKey.Int(code))
+			span.SetStatus(semconv.SpanStatusFromHTTPStatusCodeAndSpanKind(code, trace.SpanKindServer))
+
+			return err
+		}
+	}
+}
@@ -23,7 +23,9 @@ func runPlugs(s *Server) error {
 				ch <- err
 			}
 
+			s.plugs.Add(1)
 			go func() {
+				defer s.plugs.Done()
 				p(s, next)
 			}()
 
@@ -4,30 +4,46 @@ import (
 	"context"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/orkungursel/hey-taxi-location-api/config"
 	"github.com/orkungursel/hey-taxi-location-api/pkg/logger"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/metrics"
+	"github.com/orkungursel/hey-taxi-location-api/pkg/tracing"
 )
 
 type Server struct {
 	echo         *echo.Echo
 	config       *config.Config
 	logger       logger.ILogger
+	metrics      *metrics.Metrics
 	ctx          context.Context
 	httpHandlers []HttpApiHandlerItem
 	done         chan struct{}
+	stopped      chan struct{}  // closed once the http server is shut down
+	plugs        sync.WaitGroup // the plugins running until the shutdown
+	healthChecks []healthCheckItem
+	draining     int32 // set once the shutdown begins, see Draining
 }
 
 func New(ctx context.Context, config *config.Config, logger logger.ILogger) *Server {
-	return &Server{
-		ctx:    ctx,
-		echo:   echo.New(),
-		config: config,
-		logger: logger,
-		done:   make(chan struct{}),
+	s := &Server{
+		ctx:     ctx,
+		echo:    echo.New(),
+		config:  config,
+		logger:  logger,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
 	}
+
+	if config.Metrics.Enabled {
+		s.metrics = metrics.New()
+	}
+
+	return s
 }
 
 // Run starts the server
@@ -35,6 +51,12 @@ func (s *Server) Run() error {
 	ctx, cancel := signal.NotifyContext(s.ctx, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
 	defer cancel()
 
+	shutdownTracing, err := tracing.New(ctx, s.config)
+	if err != nil {
+		return err
+	}
+	defer s.shutdownTracing(shutdownTracing)
+
 	if err := runPlugs(s); err != nil {
 		return err
 	}
@@ -46,11 +68,14 @@ func (s *Server) Run() error {
 
 	s.logger.Info("shutting down...")
 
-	if err := s.shutdownHttpServer(ctx); err != nil {
-		return err
-	}
+	err = s.shutdownHttpServer()
+
+	// the plugins release their clients once no handler uses them anymore, the
+	// buffered work is written out even if the shutdown timed out
+	close(s.stopped)
+	s.waitForPlugs()
 
-	return nil
+	return err
 }
 
 func (s *Server) Config() *config.Config {
@@ -61,6 +86,11 @@ func (s *Server) Logger() logger.ILogger {
 	return s.logger
 }
 
+// Metrics returns the metrics of the server, nil if they are disabled
+func (s *Server) Metrics() *metrics.Metrics {
+	return s.metrics
+}
+
 func (s *Server) Context() context.Context {
 	return s.ctx
 }
@@ -69,6 +99,39 @@ func (s *Server) Wait() chan struct{} {
 	return s.done
 }
 
+// Stopped is closed once the http server is shut down and its handlers are
+// done, the plugins close their clients after it
+func (s *Server) Stopped() <-chan struct{} {
+	return s.stopped
+}
+
+// shutdownTracing flushes the pending spans within the shutdown timeout
+func (s *Server) shutdownTracing(shutdown func(context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.Background(),
+		time.Duration(s.config.Server.Http.ShutdownTimeout)*time.Second)
+	defer cancel()
+
+	if err := shutdown(ctx); err != nil {
+		s.logger.Errorf("error while flushing spans: %s", err)
+	}
+}
+
+// waitForPlugs gives the plugins the shutdown timeout to release their resources,
+// e.g. to write the buffered work out
+func (s *Server) waitForPlugs() {
+	done := make(chan struct{})
+	go func() {
+		s.plugs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Duration(s.config.Server.Http.ShutdownTimeout) * time.Second):
+		s.logger.Warn("plugins did not stop in time")
+	}
+}
+
 // waitForSignal waits for the cancellation token
 func (s *Server) waitForSignal(ctx context.Context) {
 	defer close(s.done)
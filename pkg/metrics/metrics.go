@@ -0,0 +1,186 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace = "hey_taxi_location"
+
+	ResultOk        = "ok"
+	ResultError     = "error"
+	ResultHit       = "hit"
+	ResultMiss      = "miss"
+	ResultStale     = "stale"     // a stale vehicle is served while the vehicle service is down
+	ResultStored    = "stored"    // the location is saved
+	ResultCoalesced = "coalesced" // the location is skipped, the vehicle barely moved
+)
+
+// Metrics holds the collectors of the service on its own registry. The
+// methods of a nil Metrics do nothing, so the metrics can be disabled.
+type Metrics struct {
+	registry            *prometheus.Registry
+	httpRequests        *prometheus.HistogramVec
+	redisCommands       *prometheus.HistogramVec
+	vehicleServiceCalls *prometheus.HistogramVec
+	vehicleCache        *prometheus.CounterVec
+	locationSaves       *prometheus.CounterVec
+	locationSearches    *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of the http requests by route.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "code"}),
+		redisCommands: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "redis_command_duration_seconds",
+			Help:      "Latency of the redis commands, a pipeline is observed as a whole.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"command", "result"}),
+		vehicleServiceCalls: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "vehicle_service_call_duration_seconds",
+			Help:      "Latency of the vehicle service calls by their gRPC status code, retries included.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "code"}),
+		vehicleCache: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "vehicle_cache_lookups_total",
+			Help:      "Lookups of the in-process vehicle cache by result.",
+		}, []string{"result"}),
+		locationSaves: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "location_saves_total",
+			Help:      "Location saves by result.",
+		}, []string{"result"}),
+		locationSearches: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "location_searches_total",
+			Help:      "Location searches by result.",
+		}, []string{"result"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.redisCommands,
+		m.vehicleServiceCalls,
+		m.vehicleCache,
+		m.locationSaves,
+		m.locationSearches,
+	)
+
+	return m
+}
+
+// Handler serves the metrics in the prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	if m == nil {
+		return http.NotFoundHandler()
+	}
+
+	// the responses are compressed by the server
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{DisableCompression: true})
+}
+
+// GaugeFunc registers a gauge whose value is read by f on every scrape
+func (m *Metrics) GaugeFunc(name, help string, f func() float64) error {
+	if m == nil {
+		return nil
+	}
+
+	return m.registry.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, f))
+}
+
+// CounterFunc registers a counter whose value is read by f on every scrape
+func (m *Metrics) CounterFunc(name, help string, f func() float64) error {
+	if m == nil {
+		return nil
+	}
+
+	return m.registry.Register(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, f))
+}
+
+// ObserveHttpRequest records a request of the route, i.e. the path template
+func (m *Metrics) ObserveHttpRequest(method, route string, code int, d time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.httpRequests.WithLabelValues(method, route, strconv.Itoa(code)).Observe(d.Seconds())
+}
+
+// ObserveRedisCommand records a redis command, failed if err is not nil
+func (m *Metrics) ObserveRedisCommand(command string, err error, d time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.redisCommands.WithLabelValues(command, result(err)).Observe(d.Seconds())
+}
+
+// ObserveVehicleServiceCall records a vehicle service call by its gRPC status code
+func (m *Metrics) ObserveVehicleServiceCall(method, code string, d time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.vehicleServiceCalls.WithLabelValues(method, code).Observe(d.Seconds())
+}
+
+// VehicleCacheLookup counts a lookup of the vehicle cache, see ResultHit,
+// ResultMiss and ResultStale
+func (m *Metrics) VehicleCacheLookup(result string) {
+	if m == nil {
+		return
+	}
+
+	m.vehicleCache.WithLabelValues(result).Inc()
+}
+
+// LocationSave counts a location save, see ResultStored, ResultCoalesced and ResultError
+func (m *Metrics) LocationSave(result string) {
+	if m == nil {
+		return
+	}
+
+	m.locationSaves.WithLabelValues(result).Inc()
+}
+
+// LocationSearch counts a location search, failed if err is not nil
+func (m *Metrics) LocationSearch(err error) {
+	if m == nil {
+		return
+	}
+
+	m.locationSearches.WithLabelValues(result(err)).Inc()
+}
+
+func result(err error) string {
+	if err != nil {
+		return ResultError
+	}
+
+	return ResultOk
+}
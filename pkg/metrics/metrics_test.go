@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Metrics.Handler() status = %v, want %v", rec.Code, http.StatusOK)
+	}
+
+	return rec.Body.String()
+}
+
+func TestMetrics(t *testing.T) {
+	m := New()
+
+	m.ObserveHttpRequest(http.MethodPost, "/api/v1/location/save/", http.StatusOK, time.Millisecond)
+	m.ObserveRedisCommand("geoadd", nil, time.Millisecond)
+	m.ObserveVehicleServiceCall("/VehicleService/GetVehicle", "Unavailable", time.Millisecond)
+	m.VehicleCacheLookup(ResultHit)
+	m.VehicleCacheLookup(ResultMiss)
+	m.LocationSave(ResultCoalesced)
+	m.LocationSearch(errors.New("failed"))
+	if err := m.GaugeFunc("active_drivers", "Drivers.", func() float64 { return 42 }); err != nil {
+		t.Fatal(err)
+	}
+
+	body := scrape(t, m)
+
+	want := []string{
+		`hey_taxi_location_http_request_duration_seconds_count{code="200",method="POST",route="/api/v1/location/save/"} 1`,
+		`hey_taxi_location_redis_command_duration_seconds_count{command="geoadd",result="ok"} 1`,
+		`hey_taxi_location_vehicle_service_call_duration_seconds_count{code="Unavailable",method="/VehicleService/GetVehicle"} 1`,
+		`hey_taxi_location_vehicle_cache_lookups_total{result="hit"} 1`,
+		`hey_taxi_location_vehicle_cache_lookups_total{result="miss"} 1`,
+		`hey_taxi_location_location_saves_total{result="coalesced"} 1`,
+		`hey_taxi_location_location_searches_total{result="error"} 1`,
+		`hey_taxi_location_active_drivers 42`,
+		`go_goroutines`,
+	}
+	for _, w := range want {
+		if !strings.Contains(body, w) {
+			t.Errorf("metrics do not contain %q", w)
+		}
+	}
+
+	if err := m.GaugeFunc("active_drivers", "Drivers.", func() float64 { return 0 }); err == nil {
+		t.Error("Metrics.GaugeFunc() registered the same gauge twice")
+	}
+}
+
+func TestMetrics_Nil(t *testing.T) {
+	var m *Metrics
+
+	m.ObserveHttpRequest(http.MethodGet, "/", http.StatusOK, time.Millisecond)
+	m.LocationSave(ResultStored)
+	if err := m.GaugeFunc("active_drivers", "Drivers.", func() float64 { return 0 }); err != nil {
+		t.Errorf("Metrics.GaugeFunc() error = %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Metrics.Handler() status = %v, want %v", rec.Code, http.StatusNotFound)
+	}
+}
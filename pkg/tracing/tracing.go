@@ -0,0 +1,87 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/orkungursel/hey-taxi-location-api/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout" // writes the spans to stdout, for the local development
+	ExporterOtlp   = "otlp"   // sends the spans to an otlp/http collector
+
+	serviceName = "hey-taxi-location-api"
+	tracerName  = "github.com/orkungursel/hey-taxi-location-api"
+)
+
+// Enabled returns true if the spans are exported
+func Enabled(c *config.Config) bool {
+	return c.Tracing.Exporter != "" && c.Tracing.Exporter != ExporterNone
+}
+
+// New installs the global tracer provider of the configured exporter and the
+// W3C trace context propagator. The returned func flushes the pending spans and
+// stops the provider. The global no-op provider is left in place if the tracing
+// is disabled.
+func New(ctx context.Context, c *config.Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+
+	if !Enabled(c) {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := newExporter(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceNameKey.String(serviceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(c.Tracing.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, c *config.Config) (sdktrace.SpanExporter, error) {
+	switch c.Tracing.Exporter {
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOtlp:
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(c.Tracing.Endpoint)}
+		if c.Tracing.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, opts...)
+	}
+
+	return nil, fmt.Errorf("unknown tracing exporter: %s", c.Tracing.Exporter)
+}
+
+// Tracer returns the tracer of the service from the global provider
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
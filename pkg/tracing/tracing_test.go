@@ -0,0 +1,39 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/orkungursel/hey-taxi-location-api/config"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		exporter string
+		wantErr  bool
+	}{
+		{name: "should keep the no-op provider if disabled", exporter: ExporterNone},
+		{name: "should export to stdout", exporter: ExporterStdout},
+		{name: "should export to an otlp collector", exporter: ExporterOtlp},
+		{name: "should fail on unknown exporter", exporter: "jaeger", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := config.New()
+			c.Tracing.Exporter = tt.exporter
+
+			shutdown, err := New(context.Background(), c)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if err := shutdown(context.Background()); err != nil {
+				t.Errorf("shutdown() error = %v", err)
+			}
+		})
+	}
+}
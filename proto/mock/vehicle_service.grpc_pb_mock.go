@@ -11,6 +11,7 @@ import (
 	gomock "github.com/golang/mock/gomock"
 	proto "github.com/orkungursel/hey-taxi-location-api/proto"
 	grpc "google.golang.org/grpc"
+	metadata "google.golang.org/grpc/metadata"
 )
 
 // MockVehicleServiceClient is a mock of VehicleServiceClient interface.
@@ -56,6 +57,169 @@ func (mr *MockVehicleServiceClientMockRecorder) GetVehicle(ctx, in interface{},
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVehicle", reflect.TypeOf((*MockVehicleServiceClient)(nil).GetVehicle), varargs...)
 }
 
+// GetVehicles mocks base method.
+func (m *MockVehicleServiceClient) GetVehicles(ctx context.Context, in *proto.GetVehiclesRequest, opts ...grpc.CallOption) (*proto.GetVehiclesResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetVehicles", varargs...)
+	ret0, _ := ret[0].(*proto.GetVehiclesResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVehicles indicates an expected call of GetVehicles.
+func (mr *MockVehicleServiceClientMockRecorder) GetVehicles(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVehicles", reflect.TypeOf((*MockVehicleServiceClient)(nil).GetVehicles), varargs...)
+}
+
+// StreamVehicles mocks base method.
+func (m *MockVehicleServiceClient) StreamVehicles(ctx context.Context, in *proto.GetVehiclesRequest, opts ...grpc.CallOption) (proto.VehicleService_StreamVehiclesClient, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "StreamVehicles", varargs...)
+	ret0, _ := ret[0].(proto.VehicleService_StreamVehiclesClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StreamVehicles indicates an expected call of StreamVehicles.
+func (mr *MockVehicleServiceClientMockRecorder) StreamVehicles(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamVehicles", reflect.TypeOf((*MockVehicleServiceClient)(nil).StreamVehicles), varargs...)
+}
+
+// MockVehicleService_StreamVehiclesClient is a mock of VehicleService_StreamVehiclesClient interface.
+type MockVehicleService_StreamVehiclesClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockVehicleService_StreamVehiclesClientMockRecorder
+}
+
+// MockVehicleService_StreamVehiclesClientMockRecorder is the mock recorder for MockVehicleService_StreamVehiclesClient.
+type MockVehicleService_StreamVehiclesClientMockRecorder struct {
+	mock *MockVehicleService_StreamVehiclesClient
+}
+
+// NewMockVehicleService_StreamVehiclesClient creates a new mock instance.
+func NewMockVehicleService_StreamVehiclesClient(ctrl *gomock.Controller) *MockVehicleService_StreamVehiclesClient {
+	mock := &MockVehicleService_StreamVehiclesClient{ctrl: ctrl}
+	mock.recorder = &MockVehicleService_StreamVehiclesClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockVehicleService_StreamVehiclesClient) EXPECT() *MockVehicleService_StreamVehiclesClientMockRecorder {
+	return m.recorder
+}
+
+// CloseSend mocks base method.
+func (m *MockVehicleService_StreamVehiclesClient) CloseSend() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseSend")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseSend indicates an expected call of CloseSend.
+func (mr *MockVehicleService_StreamVehiclesClientMockRecorder) CloseSend() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseSend", reflect.TypeOf((*MockVehicleService_StreamVehiclesClient)(nil).CloseSend))
+}
+
+// Context mocks base method.
+func (m *MockVehicleService_StreamVehiclesClient) Context() context.Context {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Context")
+	ret0, _ := ret[0].(context.Context)
+	return ret0
+}
+
+// Context indicates an expected call of Context.
+func (mr *MockVehicleService_StreamVehiclesClientMockRecorder) Context() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockVehicleService_StreamVehiclesClient)(nil).Context))
+}
+
+// Header mocks base method.
+func (m *MockVehicleService_StreamVehiclesClient) Header() (metadata.MD, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Header")
+	ret0, _ := ret[0].(metadata.MD)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Header indicates an expected call of Header.
+func (mr *MockVehicleService_StreamVehiclesClientMockRecorder) Header() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Header", reflect.TypeOf((*MockVehicleService_StreamVehiclesClient)(nil).Header))
+}
+
+// Recv mocks base method.
+func (m *MockVehicleService_StreamVehiclesClient) Recv() (*proto.GetVehicleResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Recv")
+	ret0, _ := ret[0].(*proto.GetVehicleResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Recv indicates an expected call of Recv.
+func (mr *MockVehicleService_StreamVehiclesClientMockRecorder) Recv() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Recv", reflect.TypeOf((*MockVehicleService_StreamVehiclesClient)(nil).Recv))
+}
+
+// RecvMsg mocks base method.
+func (m_2 *MockVehicleService_StreamVehiclesClient) RecvMsg(m interface{}) error {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "RecvMsg", m)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecvMsg indicates an expected call of RecvMsg.
+func (mr *MockVehicleService_StreamVehiclesClientMockRecorder) RecvMsg(m interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecvMsg", reflect.TypeOf((*MockVehicleService_StreamVehiclesClient)(nil).RecvMsg), m)
+}
+
+// SendMsg mocks base method.
+func (m_2 *MockVehicleService_StreamVehiclesClient) SendMsg(m interface{}) error {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "SendMsg", m)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendMsg indicates an expected call of SendMsg.
+func (mr *MockVehicleService_StreamVehiclesClientMockRecorder) SendMsg(m interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMsg", reflect.TypeOf((*MockVehicleService_StreamVehiclesClient)(nil).SendMsg), m)
+}
+
+// Trailer mocks base method.
+func (m *MockVehicleService_StreamVehiclesClient) Trailer() metadata.MD {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Trailer")
+	ret0, _ := ret[0].(metadata.MD)
+	return ret0
+}
+
+// Trailer indicates an expected call of Trailer.
+func (mr *MockVehicleService_StreamVehiclesClientMockRecorder) Trailer() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Trailer", reflect.TypeOf((*MockVehicleService_StreamVehiclesClient)(nil).Trailer))
+}
+
 // MockVehicleServiceServer is a mock of VehicleServiceServer interface.
 type MockVehicleServiceServer struct {
 	ctrl     *gomock.Controller
@@ -94,6 +258,35 @@ func (mr *MockVehicleServiceServerMockRecorder) GetVehicle(arg0, arg1 interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVehicle", reflect.TypeOf((*MockVehicleServiceServer)(nil).GetVehicle), arg0, arg1)
 }
 
+// GetVehicles mocks base method.
+func (m *MockVehicleServiceServer) GetVehicles(arg0 context.Context, arg1 *proto.GetVehiclesRequest) (*proto.GetVehiclesResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVehicles", arg0, arg1)
+	ret0, _ := ret[0].(*proto.GetVehiclesResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVehicles indicates an expected call of GetVehicles.
+func (mr *MockVehicleServiceServerMockRecorder) GetVehicles(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVehicles", reflect.TypeOf((*MockVehicleServiceServer)(nil).GetVehicles), arg0, arg1)
+}
+
+// StreamVehicles mocks base method.
+func (m *MockVehicleServiceServer) StreamVehicles(arg0 *proto.GetVehiclesRequest, arg1 proto.VehicleService_StreamVehiclesServer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamVehicles", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StreamVehicles indicates an expected call of StreamVehicles.
+func (mr *MockVehicleServiceServerMockRecorder) StreamVehicles(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamVehicles", reflect.TypeOf((*MockVehicleServiceServer)(nil).StreamVehicles), arg0, arg1)
+}
+
 // mustEmbedUnimplementedVehicleServiceServer mocks base method.
 func (m *MockVehicleServiceServer) mustEmbedUnimplementedVehicleServiceServer() {
 	m.ctrl.T.Helper()
@@ -140,3 +333,122 @@ func (mr *MockUnsafeVehicleServiceServerMockRecorder) mustEmbedUnimplementedVehi
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "mustEmbedUnimplementedVehicleServiceServer", reflect.TypeOf((*MockUnsafeVehicleServiceServer)(nil).mustEmbedUnimplementedVehicleServiceServer))
 }
+
+// MockVehicleService_StreamVehiclesServer is a mock of VehicleService_StreamVehiclesServer interface.
+type MockVehicleService_StreamVehiclesServer struct {
+	ctrl     *gomock.Controller
+	recorder *MockVehicleService_StreamVehiclesServerMockRecorder
+}
+
+// MockVehicleService_StreamVehiclesServerMockRecorder is the mock recorder for MockVehicleService_StreamVehiclesServer.
+type MockVehicleService_StreamVehiclesServerMockRecorder struct {
+	mock *MockVehicleService_StreamVehiclesServer
+}
+
+// NewMockVehicleService_StreamVehiclesServer creates a new mock instance.
+func NewMockVehicleService_StreamVehiclesServer(ctrl *gomock.Controller) *MockVehicleService_StreamVehiclesServer {
+	mock := &MockVehicleService_StreamVehiclesServer{ctrl: ctrl}
+	mock.recorder = &MockVehicleService_StreamVehiclesServerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockVehicleService_StreamVehiclesServer) EXPECT() *MockVehicleService_StreamVehiclesServerMockRecorder {
+	return m.recorder
+}
+
+// Context mocks base method.
+func (m *MockVehicleService_StreamVehiclesServer) Context() context.Context {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Context")
+	ret0, _ := ret[0].(context.Context)
+	return ret0
+}
+
+// Context indicates an expected call of Context.
+func (mr *MockVehicleService_StreamVehiclesServerMockRecorder) Context() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockVehicleService_StreamVehiclesServer)(nil).Context))
+}
+
+// RecvMsg mocks base method.
+func (m_2 *MockVehicleService_StreamVehiclesServer) RecvMsg(m interface{}) error {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "RecvMsg", m)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecvMsg indicates an expected call of RecvMsg.
+func (mr *MockVehicleService_StreamVehiclesServerMockRecorder) RecvMsg(m interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecvMsg", reflect.TypeOf((*MockVehicleService_StreamVehiclesServer)(nil).RecvMsg), m)
+}
+
+// Send mocks base method.
+func (m *MockVehicleService_StreamVehiclesServer) Send(arg0 *proto.GetVehicleResponse) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Send", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Send indicates an expected call of Send.
+func (mr *MockVehicleService_StreamVehiclesServerMockRecorder) Send(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockVehicleService_StreamVehiclesServer)(nil).Send), arg0)
+}
+
+// SendHeader mocks base method.
+func (m *MockVehicleService_StreamVehiclesServer) SendHeader(arg0 metadata.MD) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendHeader", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendHeader indicates an expected call of SendHeader.
+func (mr *MockVehicleService_StreamVehiclesServerMockRecorder) SendHeader(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendHeader", reflect.TypeOf((*MockVehicleService_StreamVehiclesServer)(nil).SendHeader), arg0)
+}
+
+// SendMsg mocks base method.
+func (m_2 *MockVehicleService_StreamVehiclesServer) SendMsg(m interface{}) error {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "SendMsg", m)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendMsg indicates an expected call of SendMsg.
+func (mr *MockVehicleService_StreamVehiclesServerMockRecorder) SendMsg(m interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMsg", reflect.TypeOf((*MockVehicleService_StreamVehiclesServer)(nil).SendMsg), m)
+}
+
+// SetHeader mocks base method.
+func (m *MockVehicleService_StreamVehiclesServer) SetHeader(arg0 metadata.MD) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetHeader", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetHeader indicates an expected call of SetHeader.
+func (mr *MockVehicleService_StreamVehiclesServerMockRecorder) SetHeader(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetHeader", reflect.TypeOf((*MockVehicleService_StreamVehiclesServer)(nil).SetHeader), arg0)
+}
+
+// SetTrailer mocks base method.
+func (m *MockVehicleService_StreamVehiclesServer) SetTrailer(arg0 metadata.MD) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetTrailer", arg0)
+}
+
+// SetTrailer indicates an expected call of SetTrailer.
+func (mr *MockVehicleService_StreamVehiclesServerMockRecorder) SetTrailer(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTrailer", reflect.TypeOf((*MockVehicleService_StreamVehiclesServer)(nil).SetTrailer), arg0)
+}
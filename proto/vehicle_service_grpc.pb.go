@@ -23,6 +23,10 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type VehicleServiceClient interface {
 	GetVehicle(ctx context.Context, in *GetVehicleRequest, opts ...grpc.CallOption) (*GetVehicleResponse, error)
+	// Returns the vehicles of the IDs, the unknown IDs are left out.
+	GetVehicles(ctx context.Context, in *GetVehiclesRequest, opts ...grpc.CallOption) (*GetVehiclesResponse, error)
+	// Streams the vehicles of the IDs, the unknown IDs are left out.
+	StreamVehicles(ctx context.Context, in *GetVehiclesRequest, opts ...grpc.CallOption) (VehicleService_StreamVehiclesClient, error)
 }
 
 type vehicleServiceClient struct {
@@ -42,11 +46,56 @@ func (c *vehicleServiceClient) GetVehicle(ctx context.Context, in *GetVehicleReq
 	return out, nil
 }
 
+func (c *vehicleServiceClient) GetVehicles(ctx context.Context, in *GetVehiclesRequest, opts ...grpc.CallOption) (*GetVehiclesResponse, error) {
+	out := new(GetVehiclesResponse)
+	err := c.cc.Invoke(ctx, "/vehicle.VehicleService/GetVehicles", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vehicleServiceClient) StreamVehicles(ctx context.Context, in *GetVehiclesRequest, opts ...grpc.CallOption) (VehicleService_StreamVehiclesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &VehicleService_ServiceDesc.Streams[0], "/vehicle.VehicleService/StreamVehicles", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &vehicleServiceStreamVehiclesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type VehicleService_StreamVehiclesClient interface {
+	Recv() (*GetVehicleResponse, error)
+	grpc.ClientStream
+}
+
+type vehicleServiceStreamVehiclesClient struct {
+	grpc.ClientStream
+}
+
+func (x *vehicleServiceStreamVehiclesClient) Recv() (*GetVehicleResponse, error) {
+	m := new(GetVehicleResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // VehicleServiceServer is the server API for VehicleService service.
 // All implementations must embed UnimplementedVehicleServiceServer
 // for forward compatibility
 type VehicleServiceServer interface {
 	GetVehicle(context.Context, *GetVehicleRequest) (*GetVehicleResponse, error)
+	// Returns the vehicles of the IDs, the unknown IDs are left out.
+	GetVehicles(context.Context, *GetVehiclesRequest) (*GetVehiclesResponse, error)
+	// Streams the vehicles of the IDs, the unknown IDs are left out.
+	StreamVehicles(*GetVehiclesRequest, VehicleService_StreamVehiclesServer) error
 	mustEmbedUnimplementedVehicleServiceServer()
 }
 
@@ -57,6 +106,12 @@ type UnimplementedVehicleServiceServer struct {
 func (UnimplementedVehicleServiceServer) GetVehicle(context.Context, *GetVehicleRequest) (*GetVehicleResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetVehicle not implemented")
 }
+func (UnimplementedVehicleServiceServer) GetVehicles(context.Context, *GetVehiclesRequest) (*GetVehiclesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetVehicles not implemented")
+}
+func (UnimplementedVehicleServiceServer) StreamVehicles(*GetVehiclesRequest, VehicleService_StreamVehiclesServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamVehicles not implemented")
+}
 func (UnimplementedVehicleServiceServer) mustEmbedUnimplementedVehicleServiceServer() {}
 
 // UnsafeVehicleServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -88,6 +143,45 @@ func _VehicleService_GetVehicle_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _VehicleService_GetVehicles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetVehiclesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VehicleServiceServer).GetVehicles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/vehicle.VehicleService/GetVehicles",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VehicleServiceServer).GetVehicles(ctx, req.(*GetVehiclesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VehicleService_StreamVehicles_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetVehiclesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(VehicleServiceServer).StreamVehicles(m, &vehicleServiceStreamVehiclesServer{stream})
+}
+
+type VehicleService_StreamVehiclesServer interface {
+	Send(*GetVehicleResponse) error
+	grpc.ServerStream
+}
+
+type vehicleServiceStreamVehiclesServer struct {
+	grpc.ServerStream
+}
+
+func (x *vehicleServiceStreamVehiclesServer) Send(m *GetVehicleResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // VehicleService_ServiceDesc is the grpc.ServiceDesc for VehicleService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -99,7 +193,17 @@ var VehicleService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetVehicle",
 			Handler:    _VehicleService_GetVehicle_Handler,
 		},
+		{
+			MethodName: "GetVehicles",
+			Handler:    _VehicleService_GetVehicles_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamVehicles",
+			Handler:       _VehicleService_StreamVehicles_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "vehicle_service.proto",
 }